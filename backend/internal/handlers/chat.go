@@ -1,6 +1,15 @@
 package handlers
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"meu-pdi-estrategico/backend/internal/models"
 	"meu-pdi-estrategico/backend/internal/services"
 
@@ -10,64 +19,226 @@ import (
 
 type ChatHandler struct {
 	chatService   *services.ChatService
-	openaiService *services.OpenAIService
 	pdiService    *services.PDIService
+	openaiService *services.OpenAIService
+	worker        *services.ChatWorkerPool
+	quota         *services.QuotaService
+	attachments   *services.AttachmentExtractor
 }
 
-func NewChatHandler(chatService *services.ChatService, openaiService *services.OpenAIService, pdiService *services.PDIService) *ChatHandler {
+func NewChatHandler(chatService *services.ChatService, pdiService *services.PDIService, openaiService *services.OpenAIService, worker *services.ChatWorkerPool, quota *services.QuotaService, attachments *services.AttachmentExtractor) *ChatHandler {
 	return &ChatHandler{
 		chatService:   chatService,
-		openaiService: openaiService,
 		pdiService:    pdiService,
+		openaiService: openaiService,
+		worker:        worker,
+		quota:         quota,
+		attachments:   attachments,
 	}
 }
 
+// RequestChat é o corpo das mensagens, em JSON ou multipart/form-data. No
+// multipart, os arquivos vão no campo attachments.
 type RequestChat struct {
-	Content string `json:"content"`
-	Role    string `json:"role"`
+	Content string `json:"content" form:"content"`
+	Role    string `json:"role" form:"role"`
 }
 
 type ResponseChat struct {
-	ID        uuid.UUID `json:"id"`
-	Content   string    `json:"content"`
-	Role      string    `json:"role"`
-	Status    string    `json:"status"`
-	CreatedAt string    `json:"created_at"`
+	ID          uuid.UUID            `json:"id"`
+	Content     string               `json:"content"`
+	Role        string               `json:"role"`
+	Status      string               `json:"status"`
+	Error       string               `json:"error,omitempty"`
+	ParentID    *uuid.UUID           `json:"parent_id,omitempty"`
+	BranchID    uuid.UUID            `json:"branch_id"`
+	RunID       string               `json:"run_id,omitempty"`
+	Attempts    int                  `json:"attempts"`
+	PersonaID   *uuid.UUID           `json:"persona_id,omitempty"`
+	Attachments []ResponseAttachment `json:"attachments,omitempty"`
+	CreatedAt   string               `json:"created_at"`
+}
+
+type ResponseAttachment struct {
+	ID          uuid.UUID `json:"id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Truncated   bool      `json:"truncated"`
+}
+
+type RequestEditMessage struct {
+	Content string `json:"content"`
 }
 
+type ResponseBranchList struct {
+	ActiveBranchID uuid.UUID         `json:"active_branch_id"`
+	Branches       []services.Branch `json:"branches"`
+}
+
+// ResponseChatList é uma página da conversa. Total conta todas as mensagens
+// do ramo; as páginas vizinhas são obtidas com before (ID da primeira
+// mensagem) e after (ID da última).
 type ResponseChatList struct {
-	Messages []ResponseChat `json:"messages"`
+	Messages  []ResponseChat `json:"messages"`
+	Total     int64          `json:"total"`
+	HasBefore bool           `json:"has_before"`
+	HasAfter  bool           `json:"has_after"`
+}
+
+type ResponseChatStatus struct {
+	Message ResponseChat  `json:"message"`
+	Reply   *ResponseChat `json:"reply"`
+}
+
+// RequestResetConversation é o corpo opcional do reinício da conversa. Sem
+// keep_content, o conteúdo do PDI é mantido.
+type RequestResetConversation struct {
+	KeepContent *bool `json:"keep_content"`
+}
+
+type ResponseArchive struct {
+	Archive  models.ConversationArchive `json:"archive"`
+	Messages []ResponseChat             `json:"messages"`
+}
+
+type ResponseRestoreArchive struct {
+	Restored uuid.UUID                   `json:"restored"`
+	Archived *models.ConversationArchive `json:"archived"`
 }
 
+// CreateMessage salva a mensagem do usuário e a coloca na fila de
+// processamento. A resposta do assistente é obtida consultando o status da
+// mensagem ou se inscrevendo nos seus eventos.
 func (h *ChatHandler) CreateMessage(c *fiber.Ctx) error {
-	pdiID := c.Params("id")
-	if pdiID == "" {
+	pdi, userID, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	if exceeded, err := h.quotaExceeded(c, userID); exceeded {
+		return err
+	}
+
+	var request RequestChat
+	if err := c.BodyParser(&request); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "ID do PDI é obrigatório",
+			"error": "Erro ao processar a mensagem",
 		})
 	}
 
-	userID := c.Locals("user_id").(string)
-	if userID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Usuário não autenticado",
+	if blocked, err := h.contentBlocked(c, request.Content); blocked {
+		return err
+	}
+
+	attachments, ok, err := h.readAttachments(c)
+	if !ok {
+		return err
+	}
+
+	createdMessage, err := h.createMessage(pdi, request, attachments)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao criar mensagem",
 		})
 	}
 
-	// Validar se o PDI existe e está ativo
-	pdi, err := h.pdiService.GetPDIByID(userID, pdiID)
+	if err := h.enqueue(createdMessage, userID); err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(newResponseChat(createdMessage))
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(newResponseChat(createdMessage))
+}
+
+// GetMessages devolve uma página da conversa do ramo ativo. Sem cursor, traz as
+// mensagens mais recentes; limit vai até 200 (padrão 50).
+func (h *ChatHandler) GetMessages(c *fiber.Ctx) error {
+	pdi, _, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	request := services.MessagePageRequest{
+		Before: c.Query("before"),
+		After:  c.Query("after"),
+	}
+	if limit := c.Query("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": services.ErrInvalidPageSize.Error(),
+			})
+		}
+		request.Limit = parsed
+	}
+
+	page, err := h.chatService.GetBranchMessagesPage(pdi.ID, pdi.BranchID(), request)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "PDI não encontrado",
+		if errors.Is(err, services.ErrInvalidCursor) || errors.Is(err, services.ErrInvalidPageSize) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao buscar mensagens",
 		})
 	}
 
-	if !pdi.Activated {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "PDI não está ativo",
+	response := ResponseChatList{
+		Messages:  make([]ResponseChat, len(page.Messages)),
+		Total:     page.Total,
+		HasBefore: page.HasBefore,
+		HasAfter:  page.HasAfter,
+	}
+
+	for i, msg := range page.Messages {
+		response.Messages[i] = newResponseChat(msg)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// GetMessageStatus devolve a mensagem com seu status atual e, quando houver,
+// a resposta do assistente.
+func (h *ChatHandler) GetMessageStatus(c *fiber.Ctx) error {
+	pdi, _, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	message, err := h.chatService.GetMessageByID(pdi.ID, c.Params("messageId"))
+	if err != nil {
+		return messageError(c, err)
+	}
+
+	response, err := h.messageStatus(message)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao buscar resposta",
 		})
 	}
 
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// StreamMessage cria a mensagem do usuário e transmite a resposta do
+// assistente via Server-Sent Events. Eventos enviados:
+//   - message: mensagem persistida (primeiro a do usuário, por último a do assistente)
+//   - token: trecho da resposta do assistente; com o provedor assistants, que
+//     consulta o run em vez de transmiti-lo, a resposta inteira em um só evento
+//   - tool_call: chamada de ferramenta feita pelo assistente (por exemplo save_pdi)
+//   - status: novo status da mensagem do usuário (completed ou failed)
+//   - error: falha ao processar a mensagem
+func (h *ChatHandler) StreamMessage(c *fiber.Ctx) error {
+	pdi, userID, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	if exceeded, err := h.quotaExceeded(c, userID); exceeded {
+		return err
+	}
+
 	var request RequestChat
 	if err := c.BodyParser(&request); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -75,61 +246,630 @@ func (h *ChatHandler) CreateMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	// Criar mensagem do usuário
-	userMessage := &models.Message{
-		PDIID:   pdiID,
-		Content: request.Content,
-		Role:    request.Role,
+	if blocked, err := h.contentBlocked(c, request.Content); blocked {
+		return err
+	}
+
+	attachments, ok, err := h.readAttachments(c)
+	if !ok {
+		return err
 	}
 
-	// Salvar mensagem do usuário
-	createdMessage, err := h.chatService.CreateMessage(userMessage)
+	createdMessage, err := h.createMessage(pdi, request, attachments)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Erro ao criar mensagem",
 		})
 	}
 
-	// Processar mensagem com OpenAI
-	assistantMessage, err := h.openaiService.ProcessMessage(c.Context(), userMessage, userID)
+	events, unsubscribe := h.worker.Subscribe(createdMessage.ID)
+	if err := h.enqueue(createdMessage, userID); err != nil {
+		unsubscribe()
+		return c.Status(fiber.StatusServiceUnavailable).JSON(newResponseChat(createdMessage))
+	}
+
+	// O processamento é cancelado se o cliente desconectar antes do fim
+	h.streamEvents(c, events, unsubscribe, newResponseChat(createdMessage), func() {
+		h.worker.Cancel(createdMessage.ID)
+	})
+	return nil
+}
+
+// SubscribeMessage transmite via Server-Sent Events o processamento de uma
+// mensagem já criada. Se o processamento já terminou, envia o estado final.
+func (h *ChatHandler) SubscribeMessage(c *fiber.Ctx) error {
+	pdi, _, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	message, err := h.chatService.GetMessageByID(pdi.ID, c.Params("messageId"))
+	if err != nil {
+		return messageError(c, err)
+	}
+
+	events, unsubscribe := h.worker.Subscribe(message.ID)
+
+	// Relê a mensagem após a inscrição para não perder um término concorrente
+	message, err = h.chatService.GetMessageByID(pdi.ID, message.ID.String())
+	if err != nil {
+		unsubscribe()
+		return messageError(c, err)
+	}
+
+	if message.Status != models.MessageStatusPending {
+		unsubscribe()
+		status, err := h.messageStatus(message)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Erro ao buscar resposta",
+			})
+		}
+
+		setSSEHeaders(c)
+		conn := c.Context().Conn()
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			stream := newSSEStream(w, conn, func() {})
+			stream.Send(services.StreamEventMessage, status.Message)
+			if status.Reply != nil {
+				stream.Send(services.StreamEventMessage, status.Reply)
+			}
+			sendStatus(stream, message.ID, message.Status, message.Error)
+		})
+		return nil
+	}
+
+	h.streamEvents(c, events, unsubscribe, newResponseChat(message), nil)
+	return nil
+}
+
+// RetryMessage reprocessa a última mensagem do usuário quando o processamento
+// falhou. A mensagem já enviada ao provedor é reaproveitada no mesmo thread.
+func (h *ChatHandler) RetryMessage(c *fiber.Ctx) error {
+	pdi, userID, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	if exceeded, err := h.quotaExceeded(c, userID); exceeded {
+		return err
+	}
+
+	message, err := h.chatService.GetMessageByID(pdi.ID, c.Params("messageId"))
+	if err != nil {
+		return messageError(c, err)
+	}
+
+	if message.Role != "user" || message.Status != models.MessageStatusFailed {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Apenas mensagens do usuário com falha podem ser reenviadas",
+		})
+	}
+
+	if !h.isLastMessage(pdi, message) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Apenas a última mensagem do usuário pode ser reenviada",
+		})
+	}
+
+	return h.reprocess(c, message, userID)
+}
+
+// RegenerateMessage gera uma nova resposta para a última mensagem do
+// assistente. A resposta anterior é substituída quando a nova for salva.
+func (h *ChatHandler) RegenerateMessage(c *fiber.Ctx) error {
+	pdi, userID, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	if exceeded, err := h.quotaExceeded(c, userID); exceeded {
+		return err
+	}
+
+	reply, err := h.chatService.GetMessageByID(pdi.ID, c.Params("messageId"))
+	if err != nil {
+		return messageError(c, err)
+	}
+
+	if reply.Role != "assistant" || reply.ParentID == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Apenas respostas do assistente podem ser regeneradas",
+		})
+	}
+
+	if !h.isLastMessage(pdi, reply) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Apenas a última resposta do assistente pode ser regenerada",
+		})
+	}
+
+	message, err := h.chatService.GetMessageByID(pdi.ID, reply.ParentID.String())
+	if err != nil {
+		return messageError(c, err)
+	}
+
+	if message.Status == models.MessageStatusPending {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A mensagem ainda está sendo processada",
+		})
+	}
+
+	return h.reprocess(c, message, userID)
+}
+
+// EditMessage substitui uma mensagem do usuário criando um ramo novo da
+// conversa, que passa a ser o ativo. A mensagem editada é processada como uma
+// mensagem nova e o contexto do provedor é refeito a partir do ramo.
+func (h *ChatHandler) EditMessage(c *fiber.Ctx) error {
+	pdi, userID, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	if exceeded, err := h.quotaExceeded(c, userID); exceeded {
+		return err
+	}
+
+	var request RequestEditMessage
+	if err := c.BodyParser(&request); err != nil || request.Content == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Conteúdo da mensagem é obrigatório",
+		})
+	}
+
+	if blocked, err := h.contentBlocked(c, request.Content); blocked {
+		return err
+	}
+
+	edited, err := h.chatService.EditMessage(pdi, c.Params("messageId"), request.Content)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrMessageNotEditable):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Apenas mensagens do usuário no ramo ativo podem ser editadas",
+			})
+		case errors.Is(err, services.ErrConversationBusy):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "A mensagem ainda está sendo processada",
+			})
+		default:
+			return messageError(c, err)
+		}
+	}
+
+	if err := h.enqueue(edited, userID); err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(newResponseChat(edited))
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(newResponseChat(edited))
+}
+
+// ExportMessages devolve a conversa do ramo ativo como transcrição em
+// Markdown (format=md, padrão) ou HTML (format=html), para download.
+func (h *ChatHandler) ExportMessages(c *fiber.Ctx) error {
+	pdi, _, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	format := services.ExportFormat(c.Query("format", string(services.ExportMarkdown)))
+	if format != services.ExportMarkdown && format != services.ExportHTML {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": services.ErrInvalidExportFormat.Error(),
+		})
+	}
+
+	messages, err := h.chatService.GetBranchMessages(pdi.ID, pdi.BranchID())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao buscar mensagens",
+		})
+	}
+
+	var out bytes.Buffer
+	if err := services.ExportTranscript(&out, format, services.NewTranscript(pdi, messages, time.Now())); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao exportar conversa",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, format.ContentType())
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="conversa-%s.%s"`, pdi.ID, format))
+	return c.Send(out.Bytes())
+}
+
+// ListBranches lista os ramos da conversa do PDI.
+func (h *ChatHandler) ListBranches(c *fiber.Ctx) error {
+	pdi, _, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	branches, err := h.chatService.ListBranches(pdi)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao buscar ramos da conversa",
+		})
+	}
+
+	return c.JSON(ResponseBranchList{ActiveBranchID: pdi.BranchID(), Branches: branches})
+}
+
+// ResetConversation reinicia a conversa do PDI. A conversa atual vai para o
+// arquivo do PDI e pode ser restaurada depois.
+func (h *ChatHandler) ResetConversation(c *fiber.Ctx) error {
+	pdi, _, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	var request RequestResetConversation
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Dados inválidos",
+			})
+		}
+	}
+	keepContent := request.KeepContent == nil || *request.KeepContent
+
+	archive, err := h.chatService.ResetConversation(pdi, keepContent)
+	if err != nil {
+		return archiveError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(archive)
+}
+
+// ListArchives lista as conversas arquivadas do PDI.
+func (h *ChatHandler) ListArchives(c *fiber.Ctx) error {
+	pdi, _, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	archives, err := h.chatService.ListArchives(pdi.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao buscar conversas arquivadas",
+		})
+	}
+
+	return c.JSON(fiber.Map{"archives": archives})
+}
+
+// GetArchive devolve uma conversa arquivada com as suas mensagens.
+func (h *ChatHandler) GetArchive(c *fiber.Ctx) error {
+	pdi, _, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	archive, messages, err := h.chatService.GetArchive(pdi.ID, c.Params("archiveId"))
 	if err != nil {
+		return archiveError(c, err)
+	}
+
+	response := ResponseArchive{Archive: *archive, Messages: make([]ResponseChat, len(messages))}
+	for i, msg := range messages {
+		response.Messages[i] = newResponseChat(msg)
+	}
+	return c.JSON(response)
+}
+
+// RestoreArchive restaura uma conversa arquivada. A conversa atual, se houver,
+// é arquivada no lugar dela.
+func (h *ChatHandler) RestoreArchive(c *fiber.Ctx) error {
+	pdi, _, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	archiveID := c.Params("archiveId")
+	archived, err := h.chatService.RestoreArchive(pdi, archiveID)
+	if err != nil {
+		return archiveError(c, err)
+	}
+
+	restored, _ := uuid.Parse(archiveID)
+	return c.JSON(ResponseRestoreArchive{Restored: restored, Archived: archived})
+}
+
+func archiveError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrArchiveNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Conversa arquivada não encontrada",
+		})
+	case errors.Is(err, services.ErrConversationEmpty):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "A conversa do PDI não tem mensagens",
+		})
+	case errors.Is(err, services.ErrConversationBusy):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Aguarde o processamento das mensagens para reiniciar ou restaurar a conversa",
+		})
+	default:
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao arquivar conversa",
+		})
+	}
+}
+
+// SwitchBranch torna ativo outro ramo da conversa e devolve suas mensagens.
+func (h *ChatHandler) SwitchBranch(c *fiber.Ctx) error {
+	pdi, _, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	if err := h.chatService.SwitchBranch(pdi, c.Params("branchId")); err != nil {
+		switch {
+		case errors.Is(err, services.ErrBranchNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Ramo da conversa não encontrado",
+			})
+		case errors.Is(err, services.ErrConversationBusy):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Aguarde o processamento das mensagens para trocar de ramo",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Erro ao trocar ramo da conversa",
+			})
+		}
+	}
+
+	return h.GetMessages(c)
+}
+
+// RehydrateThread coloca na fila a reconstrução da thread do PDI no provedor
+// a partir das mensagens salvas, para recuperar uma thread perdida ou trocar
+// de assistente. Responde 202; o andamento fica em GetRehydrateStatus.
+func (h *ChatHandler) RehydrateThread(c *fiber.Ctx) error {
+	pdi, userID, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	err = h.openaiService.CheckRehydrate(pdi.ID)
+	if err == nil {
+		err = h.worker.EnqueueRehydrate(userID, pdi.ID)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrThreadsNotSupported):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "O provedor configurado não usa threads",
+			})
+		case errors.Is(err, services.ErrConversationBusy):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Aguarde o processamento das mensagens para recriar a thread",
+			})
+		case errors.Is(err, services.ErrChatQueueFull), errors.Is(err, services.ErrChatStopped):
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Não foi possível enfileirar a reconstrução da thread",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Erro ao recriar a thread do PDI",
+			})
+		}
+	}
+
+	status, _ := h.worker.RehydrateStatus(pdi.ID)
+	return c.Status(fiber.StatusAccepted).JSON(status)
+}
+
+// GetRehydrateStatus devolve o andamento da última reconstrução da thread do
+// PDI e, quando concluída, a thread criada.
+func (h *ChatHandler) GetRehydrateStatus(c *fiber.Ctx) error {
+	pdi, _, err := h.activePDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	status, ok := h.worker.RehydrateStatus(pdi.ID)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Nenhuma reconstrução de thread pedida para o PDI",
+		})
+	}
+	return c.JSON(status)
+}
+
+// reprocess devolve a mensagem do usuário para pending e a coloca novamente
+// na fila de processamento.
+func (h *ChatHandler) reprocess(c *fiber.Ctx, message *models.Message, userID string) error {
+	if err := h.chatService.ResetMessage(message); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao atualizar mensagem",
+		})
+	}
+
+	if err := h.enqueue(message, userID); err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(newResponseChat(message))
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(newResponseChat(message))
+}
+
+// isLastMessage indica se a mensagem é a mais recente do seu papel no ramo
+// ativo do PDI.
+func (h *ChatHandler) isLastMessage(pdi *models.PDI, message *models.Message) bool {
+	if message.BranchID != pdi.BranchID() {
+		return false
+	}
+	last, err := h.chatService.GetLastMessage(pdi.ID, message.BranchID, message.Role)
+	return err == nil && last.ID == message.ID
+}
+
+// streamEvents repassa ao cliente os eventos de processamento até o fim do
+// processamento ou a desconexão do cliente. onDisconnect, se informado, é
+// chamado quando o cliente desconecta antes do fim.
+func (h *ChatHandler) streamEvents(c *fiber.Ctx, events <-chan services.StreamEvent, unsubscribe func(), first ResponseChat, onDisconnect func()) {
+	setSSEHeaders(c)
+	conn := c.Context().Conn()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		stream := newSSEStream(w, conn, cancel)
+		stream.Send(services.StreamEventMessage, first)
+
+		stopKeepAlive := stream.KeepAlive(sseKeepAliveInterval)
+		defer stopKeepAlive()
+
+		for {
+			select {
+			case <-ctx.Done():
+				if onDisconnect != nil {
+					onDisconnect()
+				}
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				switch event.Type {
+				case services.StreamEventMessage:
+					stream.Send(event.Type, newResponseChat(event.Message))
+				case services.StreamEventStatus:
+					sendStatus(stream, first.ID, event.Status, event.Error)
+				default:
+					stream.Send(event.Type, event)
+				}
+			}
+		}
+	})
+}
+
+func sendStatus(stream *sseStream, messageID uuid.UUID, status models.MessageStatus, reason string) {
+	stream.Send(services.StreamEventStatus, fiber.Map{
+		"id":     messageID,
+		"status": status,
+		"error":  reason,
+	})
+	if status == models.MessageStatusFailed {
+		stream.Send("error", fiber.Map{
 			"error": "Erro ao processar mensagem com OpenAI",
 		})
 	}
+}
+
+func (h *ChatHandler) createMessage(pdi *models.PDI, request RequestChat, attachments []models.Attachment) (*models.Message, error) {
+	return h.chatService.CreateMessage(&models.Message{
+		PDIID:       pdi.ID,
+		Content:     request.Content,
+		Role:        request.Role,
+		Status:      models.MessageStatusPending,
+		Attachments: attachments,
+	})
+}
+
+// readAttachments extrai o texto dos arquivos de uma requisição multipart.
+// Quando algum arquivo é recusado, a resposta de erro já foi escrita e
+// devolve false.
+func (h *ChatHandler) readAttachments(c *fiber.Ctx) ([]models.Attachment, bool, error) {
+	if !strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+		return nil, true, nil
+	}
 
-	// Retornar ambas as mensagens
-	response := []ResponseChat{
-		{
-			ID:        createdMessage.ID,
-			Content:   createdMessage.Content,
-			Role:      createdMessage.Role,
-			Status:    string(createdMessage.Status),
-			CreatedAt: createdMessage.CreatedAt.Format("2006-01-02 15:04:05"),
-		},
-		{
-			ID:        assistantMessage.ID,
-			Content:   assistantMessage.Content,
-			Role:      assistantMessage.Role,
-			Status:    string(assistantMessage.Status),
-			CreatedAt: assistantMessage.CreatedAt.Format("2006-01-02 15:04:05"),
-		},
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Erro ao processar os anexos",
+		})
+	}
+	files := form.File["attachments"]
+	if err := h.attachments.CheckCount(len(files)); err != nil {
+		return nil, false, attachmentError(c, err)
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(response)
+	attachments := make([]models.Attachment, 0, len(files))
+	for _, header := range files {
+		file, err := header.Open()
+		if err != nil {
+			return nil, false, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Erro ao ler o anexo " + header.Filename,
+			})
+		}
+		attachment, err := h.attachments.Extract(header.Filename, header.Header.Get(fiber.HeaderContentType), file)
+		file.Close()
+		if err != nil {
+			return nil, false, attachmentError(c, err)
+		}
+		// O texto extraído passa pela mesma moderação da mensagem
+		if blocked, err := h.contentBlocked(c, attachment.Text); blocked {
+			return nil, false, err
+		}
+		attachments = append(attachments, *attachment)
+	}
+	return attachments, true, nil
 }
 
-func (h *ChatHandler) GetMessages(c *fiber.Ctx) error {
+func attachmentError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrTooManyAttachments):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrUnsupportedAttachment):
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrAttachmentUnreadable):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao processar os anexos",
+		})
+	}
+}
+
+// enqueue coloca a mensagem na fila; se a fila estiver cheia, a mensagem é
+// marcada como falha para poder ser reenviada depois. O worker recebe uma
+// cópia da mensagem, já que a original ainda é usada na resposta.
+func (h *ChatHandler) enqueue(message *models.Message, userID string) error {
+	job := *message
+	err := h.worker.Enqueue(services.ChatJob{Message: &job, UserID: userID})
+	if err != nil {
+		message.Status = models.MessageStatusFailed
+		message.Error = err.Error()
+		h.chatService.MarkMessageFailed(message.ID, err.Error())
+	}
+	return err
+}
+
+func (h *ChatHandler) messageStatus(message *models.Message) (*ResponseChatStatus, error) {
+	response := &ResponseChatStatus{Message: newResponseChat(message)}
+
+	reply, err := h.chatService.GetReply(message.ID)
+	if err != nil && !errors.Is(err, services.ErrMessageNotFound) {
+		return nil, err
+	}
+	if reply != nil {
+		replyResponse := newResponseChat(reply)
+		response.Reply = &replyResponse
+	}
+
+	return response, nil
+}
+
+// activePDI valida o usuário autenticado e o PDI da rota. Quando a validação
+// falha, a resposta de erro já foi escrita e o PDI devolvido é nil.
+func (h *ChatHandler) activePDI(c *fiber.Ctx) (*models.PDI, string, error) {
 	pdiID := c.Params("id")
 	if pdiID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		return nil, "", c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "ID do PDI é obrigatório",
 		})
 	}
 
 	userID := c.Locals("user_id").(string)
 	if userID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		return nil, "", c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Usuário não autenticado",
 		})
 	}
@@ -137,37 +877,99 @@ func (h *ChatHandler) GetMessages(c *fiber.Ctx) error {
 	// Validar se o PDI existe e está ativo
 	pdi, err := h.pdiService.GetPDIByID(userID, pdiID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		return nil, "", c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "PDI não encontrado",
 		})
 	}
 
 	if !pdi.Activated {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		return nil, "", c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "PDI não está ativo",
 		})
 	}
 
-	messages, err := h.chatService.GetMessagesByPDIID(pdiID)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Erro ao buscar mensagens",
+	return pdi, userID, nil
+}
+
+// quotaExceeded verifica os limites do plano do usuário. Quando a mensagem não
+// pode ser enviada, a resposta de erro já foi escrita e devolve true.
+func (h *ChatHandler) quotaExceeded(c *fiber.Ctx, userID string) (bool, error) {
+	err := h.quota.Check(userID)
+	if err == nil {
+		return false, nil
+	}
+
+	var exceeded *services.QuotaExceededError
+	if !errors.As(err, &exceeded) {
+		return true, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao verificar limites de uso",
 		})
 	}
 
-	response := ResponseChatList{
-		Messages: make([]ResponseChat, len(messages)),
+	retryAfter := int(time.Until(exceeded.ResetAt).Seconds()) + 1
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	return true, c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":    "Limite de uso atingido: " + exceeded.Error(),
+		"limit":    exceeded.Limit,
+		"used":     exceeded.Used,
+		"max":      exceeded.Max,
+		"reset_at": exceeded.ResetAt.Format(time.RFC3339),
+	})
+}
+
+// contentBlocked aplica as regras de moderação à mensagem antes de salvá-la.
+// Quando a mensagem é recusada, a resposta de erro já foi escrita e devolve true.
+func (h *ChatHandler) contentBlocked(c *fiber.Ctx, content string) (bool, error) {
+	err := h.openaiService.Moderate(content)
+	if err == nil {
+		return false, nil
 	}
 
-	for i, msg := range messages {
-		response.Messages[i] = ResponseChat{
-			ID:        msg.ID,
-			Content:   msg.Content,
-			Role:      msg.Role,
-			Status:    string(msg.Status),
-			CreatedAt: msg.CreatedAt.Format("2006-01-02 15:04:05"),
-		}
+	var blocked *services.ContentBlockedError
+	if !errors.As(err, &blocked) {
+		return true, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao verificar a mensagem",
+		})
+	}
+	return true, c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+		"error": "Mensagem bloqueada: " + blocked.Reason,
+		"rule":  blocked.Rule,
+	})
+}
+
+func messageError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrMessageNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Mensagem não encontrada",
+		})
 	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Erro ao buscar mensagem",
+	})
+}
 
-	return c.Status(fiber.StatusOK).JSON(response)
-} 
+func newResponseChat(msg *models.Message) ResponseChat {
+	response := ResponseChat{
+		ID:        msg.ID,
+		Content:   msg.Content,
+		Role:      msg.Role,
+		Status:    string(msg.Status),
+		Error:     msg.Error,
+		ParentID:  msg.ParentID,
+		BranchID:  msg.BranchID,
+		RunID:     msg.RunID,
+		Attempts:  msg.Attempts,
+		PersonaID: msg.PersonaID,
+		CreatedAt: msg.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	for _, attachment := range msg.Attachments {
+		response.Attachments = append(response.Attachments, ResponseAttachment{
+			ID:          attachment.ID,
+			FileName:    attachment.FileName,
+			ContentType: attachment.ContentType,
+			Size:        attachment.Size,
+			Truncated:   attachment.Truncated,
+		})
+	}
+	return response
+}
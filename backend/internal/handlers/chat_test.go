@@ -0,0 +1,715 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"meu-pdi-estrategico/backend/internal/models"
+	"meu-pdi-estrategico/backend/internal/openaistub"
+	"meu-pdi-estrategico/backend/internal/services"
+)
+
+func setupChatTestApp(t *testing.T, provider services.Provider) (*fiber.App, *models.PDI) {
+	return setupChatTestAppWithQuota(t, provider, models.QuotaPlan{})
+}
+
+func setupChatTestAppWithQuota(t *testing.T, provider services.Provider, limits models.QuotaPlan) (*fiber.App, *models.PDI) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Erro ao conectar com o banco de dados: %v", err)
+	}
+	// Cada conexão a ":memory:" abre um banco vazio: o worker precisa usar a mesma
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&models.User{}, &models.PDI{}, &models.Message{}, &models.QuotaPlan{}, &models.Persona{}, &models.Attachment{}, &models.ConversationArchive{}, &models.Goal{}, &models.KeyResult{}, &models.ActionItem{}, &models.Skill{}, &models.PDIRevision{}, &models.PDITransition{}, &models.UsageRecord{}); err != nil {
+		t.Fatalf("Erro ao migrar banco de dados: %v", err)
+	}
+
+	user, err := services.NewUserService(db).CreateUser("teste@exemplo.com", "Senha@123", "teste")
+	if err != nil {
+		t.Fatalf("Erro ao criar usuário para teste: %v", err)
+	}
+
+	pdiService := services.NewPDIService(db)
+	pdi, err := pdiService.CreatePDI(user.ID.String(), services.CreatePDIRequest{Name: "Meu PDI"})
+	if err != nil {
+		t.Fatalf("Erro ao criar PDI para teste: %v", err)
+	}
+
+	chatService := services.NewChatService(db)
+	openaiService := services.NewOpenAIService(db, provider, nil)
+	worker := services.NewChatWorkerPool(openaiService, chatService, services.ChatWorkerConfig{Workers: 1, QueueSize: 10, Timeout: time.Minute})
+	worker.Start()
+	t.Cleanup(worker.Shutdown)
+
+	handler := NewChatHandler(chatService, pdiService, openaiService, worker, services.NewQuotaService(db, limits), services.NewAttachmentExtractor(services.AttachmentConfig{MaxBytes: 1 << 20, MaxFiles: 2, MaxChars: 1000}))
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", user.ID.String())
+		return c.Next()
+	})
+	app.Get("/api/pdis/:id/chat", handler.GetMessages)
+	app.Post("/api/pdis/:id/chat", handler.CreateMessage)
+	app.Post("/api/pdis/:id/chat/stream", handler.StreamMessage)
+	app.Post("/api/pdis/:id/chat/rehydrate", handler.RehydrateThread)
+	app.Get("/api/pdis/:id/chat/rehydrate", handler.GetRehydrateStatus)
+	app.Get("/api/pdis/:id/chat/export", handler.ExportMessages)
+	app.Post("/api/pdis/:id/chat/reset", handler.ResetConversation)
+	app.Get("/api/pdis/:id/chat/archives", handler.ListArchives)
+	app.Get("/api/pdis/:id/chat/archives/:archiveId", handler.GetArchive)
+	app.Post("/api/pdis/:id/chat/archives/:archiveId/restore", handler.RestoreArchive)
+	app.Get("/api/pdis/:id/chat/branches", handler.ListBranches)
+	app.Post("/api/pdis/:id/chat/branches/:branchId/activate", handler.SwitchBranch)
+	app.Get("/api/pdis/:id/chat/:messageId", handler.GetMessageStatus)
+	app.Get("/api/pdis/:id/chat/:messageId/events", handler.SubscribeMessage)
+	app.Post("/api/pdis/:id/chat/:messageId/retry", handler.RetryMessage)
+	app.Post("/api/pdis/:id/chat/:messageId/regenerate", handler.RegenerateMessage)
+	app.Post("/api/pdis/:id/chat/:messageId/edit", handler.EditMessage)
+
+	return app, pdi
+}
+
+func TestChatHandler_CreateMessage(t *testing.T) {
+	app, pdi := setupChatTestApp(t, services.NewFakeProvider())
+
+	payload, _ := json.Marshal(RequestChat{Content: "Olá", Role: "user"})
+	req := httptest.NewRequest(http.MethodPost, "/api/pdis/"+pdi.ID+"/chat", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Erro ao fazer requisição: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Status esperado %v, recebido %v", http.StatusAccepted, resp.StatusCode)
+	}
+
+	var created ResponseChat
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("Erro ao decodificar resposta: %v", err)
+	}
+	if created.Status != string(models.MessageStatusPending) {
+		t.Errorf("Status da mensagem esperado pending, recebido %v", created.Status)
+	}
+
+	// Consultar o status até o processamento terminar
+	var status ResponseChatStatus
+	for i := 0; i < 50; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/pdis/"+pdi.ID+"/chat/"+created.ID.String(), nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Erro ao fazer requisição: %v", err)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			t.Fatalf("Erro ao decodificar resposta: %v", err)
+		}
+		if status.Message.Status != string(models.MessageStatusPending) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if status.Message.Status != string(models.MessageStatusCompleted) {
+		t.Fatalf("Status esperado completed, recebido %v", status.Message.Status)
+	}
+	if status.Reply == nil || status.Reply.Content != "Resposta simulada: Olá" {
+		t.Errorf("Resposta inesperada: %+v", status.Reply)
+	}
+}
+
+func TestChatHandler_CreateMessage_ProviderFailure(t *testing.T) {
+	app, pdi := setupChatTestApp(t, failingProvider{})
+
+	payload, _ := json.Marshal(RequestChat{Content: "Olá", Role: "user"})
+	req := httptest.NewRequest(http.MethodPost, "/api/pdis/"+pdi.ID+"/chat", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Erro ao fazer requisição: %v", err)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Status esperado %v, recebido %v", http.StatusAccepted, resp.StatusCode)
+	}
+
+	var created ResponseChat
+	json.NewDecoder(resp.Body).Decode(&created)
+
+	// A inscrição devolve o estado final mesmo após o término do processamento
+	req = httptest.NewRequest(http.MethodGet, "/api/pdis/"+pdi.ID+"/chat/"+created.ID.String()+"/events", nil)
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Erro ao fazer requisição: %v", err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `"status":"failed"`) || !strings.Contains(string(body), "provedor indisponível") {
+		t.Errorf("Eventos inesperados: %s", body)
+	}
+}
+
+type failingProvider struct{}
+
+func (failingProvider) Name() string { return "failing" }
+
+func (failingProvider) Run(ctx context.Context, req services.ProviderRequest) (*services.ProviderResponse, error) {
+	return nil, errors.New("provedor indisponível")
+}
+
+func TestChatHandler_StreamMessage(t *testing.T) {
+	provider := services.NewFakeProvider(services.FakeReply{
+		Content:   "Plano salvo com sucesso",
+		ToolCalls: []services.ToolCall{{Name: "save_pdi", Arguments: `{"goals":[],"self_assessment_questions":[]}`}},
+	})
+	app, pdi := setupChatTestApp(t, provider)
+
+	payload, _ := json.Marshal(RequestChat{Content: "Salve meu PDI", Role: "user"})
+	req := httptest.NewRequest(http.MethodPost, "/api/pdis/"+pdi.ID+"/chat/stream", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Erro ao fazer requisição: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Status esperado %v, recebido %v", http.StatusOK, resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Content-Type esperado text/event-stream, recebido %v", contentType)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var events []string
+	var tokens strings.Builder
+	for _, block := range strings.Split(strings.TrimSpace(string(body)), "\n\n") {
+		lines := strings.SplitN(block, "\n", 2)
+		event := strings.TrimPrefix(lines[0], "event: ")
+		events = append(events, event)
+
+		if event == services.StreamEventToken {
+			var data services.StreamEvent
+			json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &data)
+			tokens.WriteString(data.Content)
+		}
+	}
+
+	last := len(events) - 1
+	if events[0] != "message" || events[1] != services.StreamEventToolCall || events[last-1] != "message" || events[last] != services.StreamEventStatus {
+		t.Errorf("Sequência de eventos inesperada: %v", events)
+	}
+	if tokens.String() != "Plano salvo com sucesso" {
+		t.Errorf("Tokens esperados 'Plano salvo com sucesso', recebidos '%v'", tokens.String())
+	}
+}
+
+// Conversa de duas mensagens com a API de Assistants reproduzida do cassete:
+// a thread criada na primeira mensagem é reutilizada na segunda.
+func TestChatHandler_CreateMessage_AssistantsThread(t *testing.T) {
+	stub := openaistub.New(t, "testdata/cassettes/chat_thread.json")
+	provider := services.NewAssistantsProvider(stub.Client(), "asst_stub", services.RunPollConfig{InitialInterval: time.Millisecond})
+	app, pdi := setupChatTestApp(t, provider)
+
+	replies := map[string]string{
+		"Como começo meu PDI?": "Comece pela sua meta de carreira.",
+		"Quero ser tech lead":  "Ótimo objetivo! Vamos definir as habilidades.",
+	}
+	for _, content := range []string{"Como começo meu PDI?", "Quero ser tech lead"} {
+		status, created := postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat", RequestChat{Content: content, Role: "user"})
+		if status != http.StatusAccepted {
+			t.Fatalf("Status esperado %v, recebido %v", http.StatusAccepted, status)
+		}
+
+		result := waitMessage(t, app, pdi.ID, created.ID.String())
+		if result.Message.Status != string(models.MessageStatusCompleted) {
+			t.Fatalf("Status esperado completed, recebido %v", result.Message.Status)
+		}
+		if result.Reply == nil || result.Reply.Content != replies[content] {
+			t.Errorf("Resposta inesperada para %q: %+v", content, result.Reply)
+		}
+	}
+}
+
+func TestChatHandler_EditMessage(t *testing.T) {
+	app, pdi := setupChatTestApp(t, services.NewFakeProvider())
+
+	_, first := postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat", RequestChat{Content: "Olá", Role: "user"})
+	firstStatus := waitMessage(t, app, pdi.ID, first.ID.String())
+	_, second := postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat", RequestChat{Content: "Quero ser gerente", Role: "user"})
+	waitMessage(t, app, pdi.ID, second.ID.String())
+
+	code, _ := postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat/"+firstStatus.Reply.ID.String()+"/edit", RequestEditMessage{Content: "Outra"})
+	if code != http.StatusConflict {
+		t.Errorf("Edição de resposta do assistente: status esperado %v, recebido %v", http.StatusConflict, code)
+	}
+
+	code, edited := postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat/"+second.ID.String()+"/edit", RequestEditMessage{Content: "Quero ser especialista"})
+	if code != http.StatusAccepted {
+		t.Fatalf("Status esperado %v, recebido %v", http.StatusAccepted, code)
+	}
+	if edited.BranchID == second.BranchID || edited.ParentID == nil || *edited.ParentID != firstStatus.Reply.ID {
+		t.Errorf("Mensagem editada inesperada: %+v", edited)
+	}
+	status := waitMessage(t, app, pdi.ID, edited.ID.String())
+	if status.Reply == nil || status.Reply.Content != "Resposta simulada: Quero ser especialista" || status.Reply.BranchID != edited.BranchID {
+		t.Errorf("Resposta inesperada: %+v", status.Reply)
+	}
+
+	var list ResponseChatList
+	resp, _ := app.Test(httptest.NewRequest(http.MethodGet, "/api/pdis/"+pdi.ID+"/chat", nil))
+	json.NewDecoder(resp.Body).Decode(&list)
+	if len(list.Messages) != 4 || list.Messages[2].ID != edited.ID {
+		t.Errorf("Mensagens do ramo ativo = %+v", list.Messages)
+	}
+
+	var branches ResponseBranchList
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, "/api/pdis/"+pdi.ID+"/chat/branches", nil))
+	json.NewDecoder(resp.Body).Decode(&branches)
+	if len(branches.Branches) != 2 || branches.ActiveBranchID != edited.BranchID {
+		t.Fatalf("Ramos inesperados: %+v", branches)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest(http.MethodPost, "/api/pdis/"+pdi.ID+"/chat/branches/"+second.BranchID.String()+"/activate", nil))
+	list = ResponseChatList{}
+	json.NewDecoder(resp.Body).Decode(&list)
+	if resp.StatusCode != http.StatusOK || len(list.Messages) != 4 || list.Messages[2].ID != second.ID {
+		t.Errorf("Troca para o ramo principal: status %v, mensagens %+v", resp.StatusCode, list.Messages)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest(http.MethodPost, "/api/pdis/"+pdi.ID+"/chat/branches/"+pdi.ID+"0/activate", nil))
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Ramo inexistente: status esperado %v, recebido %v", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+// flakyProvider falha na primeira execução e responde normalmente nas demais.
+type flakyProvider struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (p *flakyProvider) Name() string { return "flaky" }
+
+func (p *flakyProvider) Run(ctx context.Context, req services.ProviderRequest) (*services.ProviderResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.calls == 1 {
+		return nil, &services.RunError{RunID: "run-1", InputMessageID: "msg-1", Err: errors.New("run expirado")}
+	}
+	if req.InputMessageID != "msg-1" {
+		return nil, errors.New("mensagem reenviada ao thread")
+	}
+	return &services.ProviderResponse{RunID: "run-2", Content: "Resposta após nova tentativa", InputMessageID: req.InputMessageID}, nil
+}
+
+func postMessage(t *testing.T, app *fiber.App, url string, body interface{}) (int, ResponseChat) {
+	var payload io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		payload = bytes.NewBuffer(data)
+	}
+	req := httptest.NewRequest(http.MethodPost, url, payload)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Erro ao fazer requisição: %v", err)
+	}
+
+	var message ResponseChat
+	json.NewDecoder(resp.Body).Decode(&message)
+	return resp.StatusCode, message
+}
+
+// waitMessage consulta o status da mensagem até o processamento terminar.
+func waitMessage(t *testing.T, app *fiber.App, pdiID string, messageID string) ResponseChatStatus {
+	var status ResponseChatStatus
+	for i := 0; i < 50; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/pdis/"+pdiID+"/chat/"+messageID, nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Erro ao fazer requisição: %v", err)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			t.Fatalf("Erro ao decodificar resposta: %v", err)
+		}
+		if status.Message.Status != string(models.MessageStatusPending) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return status
+}
+
+func TestChatHandler_RetryMessage(t *testing.T) {
+	app, pdi := setupChatTestApp(t, &flakyProvider{})
+
+	_, created := postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat", RequestChat{Content: "Olá", Role: "user"})
+	status := waitMessage(t, app, pdi.ID, created.ID.String())
+	if status.Message.Status != string(models.MessageStatusFailed) {
+		t.Fatalf("Status esperado failed, recebido %v", status.Message.Status)
+	}
+	if status.Message.RunID != "run-1" {
+		t.Errorf("RunID esperado run-1, recebido %v", status.Message.RunID)
+	}
+
+	code, retried := postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat/"+created.ID.String()+"/retry", nil)
+	if code != http.StatusAccepted {
+		t.Fatalf("Status esperado %v, recebido %v", http.StatusAccepted, code)
+	}
+	if retried.Status != string(models.MessageStatusPending) {
+		t.Errorf("Status esperado pending, recebido %v", retried.Status)
+	}
+
+	status = waitMessage(t, app, pdi.ID, created.ID.String())
+	if status.Message.Status != string(models.MessageStatusCompleted) {
+		t.Fatalf("Status esperado completed, recebido %v", status.Message.Status)
+	}
+	if status.Message.RunID != "run-2" || status.Message.Attempts != 2 {
+		t.Errorf("Run inesperado: run_id=%v attempts=%v", status.Message.RunID, status.Message.Attempts)
+	}
+	if status.Reply == nil || status.Reply.RunID != "run-2" {
+		t.Errorf("Resposta inesperada: %+v", status.Reply)
+	}
+
+	// Mensagens concluídas não podem ser reenviadas
+	code, _ = postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat/"+created.ID.String()+"/retry", nil)
+	if code != http.StatusConflict {
+		t.Errorf("Status esperado %v, recebido %v", http.StatusConflict, code)
+	}
+}
+
+func TestChatHandler_RegenerateMessage(t *testing.T) {
+	provider := services.NewFakeProvider(
+		services.FakeReply{Content: "Primeira resposta"},
+		services.FakeReply{Content: "Segunda resposta"},
+	)
+	app, pdi := setupChatTestApp(t, provider)
+
+	_, created := postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat", RequestChat{Content: "Olá", Role: "user"})
+	status := waitMessage(t, app, pdi.ID, created.ID.String())
+	if status.Reply == nil || status.Reply.Content != "Primeira resposta" {
+		t.Fatalf("Resposta inesperada: %+v", status.Reply)
+	}
+	first := status.Reply
+
+	// Apenas respostas do assistente podem ser regeneradas
+	code, _ := postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat/"+created.ID.String()+"/regenerate", nil)
+	if code != http.StatusConflict {
+		t.Errorf("Status esperado %v, recebido %v", http.StatusConflict, code)
+	}
+
+	code, _ = postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat/"+first.ID.String()+"/regenerate", nil)
+	if code != http.StatusAccepted {
+		t.Fatalf("Status esperado %v, recebido %v", http.StatusAccepted, code)
+	}
+
+	status = waitMessage(t, app, pdi.ID, created.ID.String())
+	if status.Reply == nil || status.Reply.Content != "Segunda resposta" {
+		t.Fatalf("Resposta inesperada: %+v", status.Reply)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/pdis/"+pdi.ID+"/chat", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Erro ao fazer requisição: %v", err)
+	}
+	var list ResponseChatList
+	json.NewDecoder(resp.Body).Decode(&list)
+	if len(list.Messages) != 2 {
+		t.Errorf("Esperadas 2 mensagens após regenerar, recebidas %d", len(list.Messages))
+	}
+}
+
+func TestChatHandler_CreateMessage_QuotaExceeded(t *testing.T) {
+	app, pdi := setupChatTestAppWithQuota(t, services.NewFakeProvider(), models.QuotaPlan{MessagesPerDay: 1})
+
+	code, created := postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat", RequestChat{Content: "Olá", Role: "user"})
+	if code != http.StatusAccepted {
+		t.Fatalf("Status esperado %v, recebido %v", http.StatusAccepted, code)
+	}
+	waitMessage(t, app, pdi.ID, created.ID.String())
+
+	payload, _ := json.Marshal(RequestChat{Content: "De novo", Role: "user"})
+	req := httptest.NewRequest(http.MethodPost, "/api/pdis/"+pdi.ID+"/chat", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Erro ao fazer requisição: %v", err)
+	}
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Status esperado %v, recebido %v", http.StatusTooManyRequests, resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("Header Retry-After ausente")
+	}
+
+	var body map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if body["limit"] != services.QuotaMessagesPerDay || body["reset_at"] == nil {
+		t.Errorf("Resposta inesperada: %v", body)
+	}
+}
+
+// threadedProvider responde sempre e simula as threads do provedor.
+type threadedProvider struct{}
+
+func (threadedProvider) Name() string { return "threaded" }
+
+func (threadedProvider) Run(ctx context.Context, req services.ProviderRequest) (*services.ProviderResponse, error) {
+	return &services.ProviderResponse{Content: "Resposta", RunID: "run-1"}, nil
+}
+
+func (threadedProvider) CreateThread(ctx context.Context) (string, error) {
+	return "thread-1", nil
+}
+
+func (threadedProvider) ReplayThread(ctx context.Context, turns []services.Turn) (string, []string, error) {
+	return "thread-2", make([]string, len(turns)), nil
+}
+
+func TestChatHandler_RehydrateThread(t *testing.T) {
+	app, pdi := setupChatTestApp(t, threadedProvider{})
+
+	_, created := postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat", RequestChat{Content: "Olá", Role: "user"})
+	waitMessage(t, app, pdi.ID, created.ID.String())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/pdis/"+pdi.ID+"/chat/rehydrate", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Erro ao fazer requisição: %v", err)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Status esperado %v, recebido %v", http.StatusAccepted, resp.StatusCode)
+	}
+	var status services.RehydrateStatus
+	json.NewDecoder(resp.Body).Decode(&status)
+	if status.Status != models.MessageStatusPending {
+		t.Errorf("Status da reconstrução esperado pending, recebido %v", status.Status)
+	}
+
+	// A reconstrução roda no worker: consultar até terminar
+	for i := 0; i < 50 && status.Status == models.MessageStatusPending; i++ {
+		time.Sleep(10 * time.Millisecond)
+		resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/api/pdis/"+pdi.ID+"/chat/rehydrate", nil))
+		if err != nil {
+			t.Fatalf("Erro ao fazer requisição: %v", err)
+		}
+		json.NewDecoder(resp.Body).Decode(&status)
+	}
+	if result := status.Result; status.Status != models.MessageStatusCompleted || result == nil ||
+		result.ThreadID != "thread-2" || result.PreviousThreadID != "thread-1" || result.Messages != 2 {
+		t.Errorf("Resultado inesperado: %+v", status)
+	}
+
+	// Provedores sem thread não podem recriá-la
+	app, pdi = setupChatTestApp(t, services.NewFakeProvider())
+	code, _ := postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat/rehydrate", nil)
+	if code != http.StatusBadRequest {
+		t.Errorf("Status esperado %v, recebido %v", http.StatusBadRequest, code)
+	}
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, "/api/pdis/"+pdi.ID+"/chat/rehydrate", nil))
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Status esperado %v, recebido %v", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+// postAttachments envia a mensagem como multipart, com um arquivo por entrada de files.
+func postAttachments(t *testing.T, app *fiber.App, url string, content string, files map[string]string) *http.Response {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("content", content)
+	writer.WriteField("role", "user")
+	for name, data := range files {
+		part, _ := writer.CreateFormFile("attachments", name)
+		part.Write([]byte(data))
+	}
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, url, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Erro ao fazer requisição: %v", err)
+	}
+	return resp
+}
+
+func TestChatHandler_CreateMessage_Attachments(t *testing.T) {
+	app, pdi := setupChatTestApp(t, services.NewFakeProvider())
+	url := "/api/pdis/" + pdi.ID + "/chat"
+
+	resp := postAttachments(t, app, url, "Analise a vaga", map[string]string{"vaga.md": "# Staff Engineer\n\nLiderança técnica"})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Status esperado %v, recebido %v", http.StatusAccepted, resp.StatusCode)
+	}
+	var created ResponseChat
+	json.NewDecoder(resp.Body).Decode(&created)
+	if created.Content != "Analise a vaga" || len(created.Attachments) != 1 || created.Attachments[0].FileName != "vaga.md" {
+		t.Fatalf("Mensagem inesperada: %+v", created)
+	}
+
+	// O texto do anexo chega ao provedor junto com a mensagem
+	status := waitMessage(t, app, pdi.ID, created.ID.String())
+	if status.Reply == nil || !strings.Contains(status.Reply.Content, "[Anexo: vaga.md]") || !strings.Contains(status.Reply.Content, "Liderança técnica") {
+		t.Errorf("Resposta inesperada: %+v", status.Reply)
+	}
+	if len(status.Message.Attachments) != 1 {
+		t.Errorf("Anexos esperados na mensagem: %+v", status.Message)
+	}
+
+	resp = postAttachments(t, app, url, "Veja a foto", map[string]string{"foto.png": "png"})
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("Status esperado %v, recebido %v", http.StatusUnsupportedMediaType, resp.StatusCode)
+	}
+
+	resp = postAttachments(t, app, url, "Muitos arquivos", map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Status esperado %v, recebido %v", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+func TestChatHandler_GetMessages_Pagination(t *testing.T) {
+	app, pdi := setupChatTestApp(t, services.NewFakeProvider())
+	url := "/api/pdis/" + pdi.ID + "/chat"
+
+	for _, content := range []string{"Primeira", "Segunda"} {
+		_, created := postMessage(t, app, url, RequestChat{Content: content, Role: "user"})
+		waitMessage(t, app, pdi.ID, created.ID.String())
+	}
+
+	getPage := func(query string) (int, ResponseChatList) {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, url+query, nil))
+		if err != nil {
+			t.Fatalf("Erro ao fazer requisição: %v", err)
+		}
+		var page ResponseChatList
+		json.NewDecoder(resp.Body).Decode(&page)
+		return resp.StatusCode, page
+	}
+
+	status, latest := getPage("?limit=3")
+	if status != http.StatusOK || latest.Total != 4 || !latest.HasBefore || latest.HasAfter || len(latest.Messages) != 3 {
+		t.Fatalf("Página mais recente: status %v, %+v", status, latest)
+	}
+	if _, err := time.Parse(time.RFC3339, latest.Messages[0].CreatedAt); err != nil {
+		t.Errorf("created_at fora do ISO-8601: %v", latest.Messages[0].CreatedAt)
+	}
+
+	status, older := getPage("?limit=3&before=" + latest.Messages[0].ID.String())
+	if status != http.StatusOK || len(older.Messages) != 1 || older.HasBefore || !older.HasAfter || older.Messages[0].Content != "Primeira" {
+		t.Errorf("Página anterior: status %v, %+v", status, older)
+	}
+
+	for _, query := range []string{"?limit=abc", "?limit=500", "?before=" + pdi.ID, "?before=a&after=b"} {
+		if status, _ := getPage(query); status != http.StatusBadRequest {
+			t.Errorf("GET %s: status esperado %v, recebido %v", query, http.StatusBadRequest, status)
+		}
+	}
+}
+
+func TestChatHandler_ExportMessages(t *testing.T) {
+	app, pdi := setupChatTestApp(t, services.NewFakeProvider())
+	_, created := postMessage(t, app, "/api/pdis/"+pdi.ID+"/chat", RequestChat{Content: "Olá <b>coach</b>", Role: "user"})
+	waitMessage(t, app, pdi.ID, created.ID.String())
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/pdis/"+pdi.ID+"/chat/export?format=html", nil))
+	if err != nil {
+		t.Fatalf("Erro ao fazer requisição: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("Status %v, Content-Type %v", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(resp.Header.Get("Content-Disposition"), "conversa-"+pdi.ID+".html") {
+		t.Errorf("Content-Disposition inesperado: %v", resp.Header.Get("Content-Disposition"))
+	}
+	if !strings.Contains(string(body), "Olá &lt;b&gt;coach&lt;/b&gt;") || !strings.Contains(string(body), "Resposta simulada") {
+		t.Errorf("Transcrição inesperada:\n%s", body)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, "/api/pdis/"+pdi.ID+"/chat/export", nil))
+	body, _ = io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(string(body), "# Meu PDI\n") {
+		t.Errorf("Markdown: status %v\n%s", resp.StatusCode, body)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, "/api/pdis/"+pdi.ID+"/chat/export?format=pdf", nil))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Status esperado %v, recebido %v", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+func TestChatHandler_ResetConversation(t *testing.T) {
+	app, pdi := setupChatTestApp(t, services.NewFakeProvider())
+	url := "/api/pdis/" + pdi.ID + "/chat"
+
+	_, created := postMessage(t, app, url, RequestChat{Content: "Olá", Role: "user"})
+	waitMessage(t, app, pdi.ID, created.ID.String())
+
+	req := httptest.NewRequest(http.MethodPost, url+"/reset", strings.NewReader(`{"keep_content": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Erro ao fazer requisição: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Status esperado %v, recebido %v", http.StatusCreated, resp.StatusCode)
+	}
+	var archive models.ConversationArchive
+	json.NewDecoder(resp.Body).Decode(&archive)
+
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, url, nil))
+	var list ResponseChatList
+	json.NewDecoder(resp.Body).Decode(&list)
+	if list.Total != 0 {
+		t.Errorf("Mensagens após reinício = %d, esperado 0", list.Total)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest(http.MethodPost, url+"/reset", nil))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Reinício sem mensagens: status esperado %v, recebido %v", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, url+"/archives/"+archive.ID.String(), nil))
+	var archived ResponseArchive
+	json.NewDecoder(resp.Body).Decode(&archived)
+	if resp.StatusCode != http.StatusOK || archived.Archive.MessageCount != 2 || len(archived.Messages) != 2 {
+		t.Errorf("Conversa arquivada: status %v, %+v", resp.StatusCode, archived)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest(http.MethodPost, url+"/archives/"+archive.ID.String()+"/restore", nil))
+	var restored ResponseRestoreArchive
+	json.NewDecoder(resp.Body).Decode(&restored)
+	if resp.StatusCode != http.StatusOK || restored.Restored != archive.ID || restored.Archived != nil {
+		t.Errorf("Restauração: status %v, %+v", resp.StatusCode, restored)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, url, nil))
+	json.NewDecoder(resp.Body).Decode(&list)
+	if list.Total != 2 {
+		t.Errorf("Mensagens após restauração = %d, esperado 2", list.Total)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest(http.MethodGet, url+"/archives/"+archive.ID.String(), nil))
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Arquivo restaurado: status esperado %v, recebido %v", http.StatusNotFound, resp.StatusCode)
+	}
+}
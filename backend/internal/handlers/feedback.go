@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"log"
+
+	"meu-pdi-estrategico/backend/internal/models"
+	"meu-pdi-estrategico/backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FeedbackHandler struct {
+	feedbackService *services.FeedbackService
+	chatService     *services.ChatService
+	pdiService      *services.PDIService
+}
+
+func NewFeedbackHandler(feedbackService *services.FeedbackService, chatService *services.ChatService, pdiService *services.PDIService) *FeedbackHandler {
+	return &FeedbackHandler{
+		feedbackService: feedbackService,
+		chatService:     chatService,
+		pdiService:      pdiService,
+	}
+}
+
+// CreateFeedback grava a avaliação (up ou down, com comentário opcional) de
+// uma resposta do assistente. Avaliar de novo substitui a avaliação anterior.
+func (h *FeedbackHandler) CreateFeedback(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Usuário não autenticado",
+		})
+	}
+
+	pdi, err := h.pdiService.GetPDIByID(userID, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "PDI não encontrado",
+		})
+	}
+
+	if !pdi.Activated {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "PDI não está ativo",
+		})
+	}
+
+	message, err := h.chatService.GetMessageByID(pdi.ID, c.Params("messageId"))
+	if err != nil {
+		return messageError(c, err)
+	}
+
+	var request services.FeedbackRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Erro ao processar a avaliação",
+		})
+	}
+
+	feedback, created, err := h.feedbackService.SaveFeedback(message, userID, request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidFeedback):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrFeedbackNotAllowed):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Apenas respostas concluídas do assistente podem ser avaliadas",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Erro ao salvar avaliação",
+			})
+		}
+	}
+
+	if created {
+		return c.Status(fiber.StatusCreated).JSON(feedback)
+	}
+	return c.Status(fiber.StatusOK).JSON(feedback)
+}
+
+// ExportFeedback devolve as avaliações em JSONL, com a mensagem do usuário, a
+// resposta e a versão de persona. Aceita os filtros opcionais from, to e
+// rating.
+func (h *FeedbackHandler) ExportFeedback(c *fiber.Ctx) error {
+	period, err := usagePeriod(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Período inválido: use datas no formato AAAA-MM-DD ou RFC 3339",
+		})
+	}
+
+	rating := models.FeedbackRating(c.Query("rating"))
+	if rating != "" && rating != models.FeedbackUp && rating != models.FeedbackDown {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Filtro rating deve ser up ou down",
+		})
+	}
+
+	filter := services.FeedbackExportFilter{Period: period, Rating: rating}
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="feedback.jsonl"`)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// As linhas vão para a resposta à medida que são lidas; com a
+		// resposta iniciada, um erro só pode ir para o log
+		if _, err := h.feedbackService.Export(w, filter); err != nil {
+			log.Printf("[Feedback] Erro ao exportar avaliações: %v", err)
+		}
+	})
+	return nil
+}
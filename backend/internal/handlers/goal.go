@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+
+	"meu-pdi-estrategico/backend/internal/models"
+	"meu-pdi-estrategico/backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type GoalHandler struct {
+	goalService *services.GoalService
+	pdiService  *services.PDIService
+}
+
+func NewGoalHandler(goalService *services.GoalService, pdiService *services.PDIService) *GoalHandler {
+	return &GoalHandler{
+		goalService: goalService,
+		pdiService:  pdiService,
+	}
+}
+
+// ListGoals devolve os objetivos do PDI com resultados-chave, ações e
+// competências.
+func (h *GoalHandler) ListGoals(c *fiber.Ctx) error {
+	pdi, err := h.userPDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	goals, err := h.goalService.ListGoals(pdi)
+	if err != nil {
+		return goalError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"goals": goals})
+}
+
+// UpdateGoal altera a descrição, o alinhamento ou o progresso de um objetivo.
+func (h *GoalHandler) UpdateGoal(c *fiber.Ctx) error {
+	pdi, err := h.userPDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	var req services.GoalUpdate
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Dados inválidos",
+		})
+	}
+
+	goal, err := h.goalService.UpdateGoal(pdi, c.Params("goalId"), req, userRevision(services.RevisionReasonGoalUpdate))
+	if err != nil {
+		return goalError(c, err)
+	}
+
+	return c.JSON(goal)
+}
+
+// UpdateKeyResult altera a descrição de um resultado-chave.
+func (h *GoalHandler) UpdateKeyResult(c *fiber.Ctx) error {
+	pdi, err := h.userPDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	var req services.KeyResultUpdate
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Dados inválidos",
+		})
+	}
+
+	keyResult, err := h.goalService.UpdateKeyResult(pdi, c.Params("keyResultId"), req, userRevision(services.RevisionReasonKeyResultUpdate))
+	if err != nil {
+		return goalError(c, err)
+	}
+
+	return c.JSON(keyResult)
+}
+
+// UpdateActionItem altera a descrição de uma ação ou a marca como concluída.
+func (h *GoalHandler) UpdateActionItem(c *fiber.Ctx) error {
+	pdi, err := h.userPDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	var req services.ActionItemUpdate
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Dados inválidos",
+		})
+	}
+
+	item, err := h.goalService.UpdateActionItem(pdi, c.Params("actionItemId"), req, userRevision(services.RevisionReasonActionItemUpdate))
+	if err != nil {
+		return goalError(c, err)
+	}
+
+	return c.JSON(item)
+}
+
+func userRevision(reason string) services.RevisionSource {
+	return services.RevisionSource{Author: models.RevisionAuthorUser, Reason: reason}
+}
+
+func (h *GoalHandler) userPDI(c *fiber.Ctx) (*models.PDI, error) {
+	userID := c.Locals("user_id").(string)
+	if userID == "" {
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "usuário não autenticado",
+		})
+	}
+
+	pdi, err := h.pdiService.GetPDIByID(userID, c.Params("id"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "PDI não encontrado",
+		})
+	}
+	return pdi, nil
+}
+
+func goalError(c *fiber.Ctx, err error) error {
+	var validation *services.PDIValidationError
+	switch {
+	case errors.As(err, &validation):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":             "Conteúdo do PDI inválido",
+			"validation_errors": validation.Errors,
+		})
+	case errors.Is(err, services.ErrGoalNotFound),
+		errors.Is(err, services.ErrKeyResultNotFound),
+		errors.Is(err, services.ErrActionItemNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao atualizar objetivos do PDI",
+		})
+	}
+}
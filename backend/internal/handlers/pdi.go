@@ -1,17 +1,20 @@
 package handlers
 
 import (
+	"errors"
+
 	"meu-pdi-estrategico/backend/internal/services"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type PDIHandler struct {
-	pdiService *services.PDIService
+	pdiService      *services.PDIService
+	workflowService *services.PDIWorkflowService
 }
 
-func NewPDIHandler(pdiService *services.PDIService) *PDIHandler {
-	return &PDIHandler{pdiService: pdiService}
+func NewPDIHandler(pdiService *services.PDIService, workflowService *services.PDIWorkflowService) *PDIHandler {
+	return &PDIHandler{pdiService: pdiService, workflowService: workflowService}
 }
 
 func (h *PDIHandler) GetUserPDIs(c *fiber.Ctx) error {
@@ -49,6 +52,11 @@ func (h *PDIHandler) CreatePDI(c *fiber.Ctx) error {
 
 	pdi, err := h.pdiService.CreatePDI(userID, req)
 	if err != nil {
+		if errors.Is(err, services.ErrPersonaNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Persona não encontrada",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -81,6 +89,11 @@ func (h *PDIHandler) UpdatePDI(c *fiber.Ctx) error {
 
 	pdi, err := h.pdiService.UpdatePDI(userID, pdiID, req)
 	if err != nil {
+		if errors.Is(err, services.ErrPersonaNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Persona não encontrada",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -112,4 +125,94 @@ func (h *PDIHandler) GetPDIByID(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(pdi)
-} 
\ No newline at end of file
+}
+
+// Transition muda o status do PDI seguindo o fluxo DRAFT, PENDING,
+// IN_PROGRESS e DONE.
+func (h *PDIHandler) Transition(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "usuário não autenticado",
+		})
+	}
+
+	var req services.TransitionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Dados inválidos",
+		})
+	}
+
+	pdi, err := h.pdiService.GetPDIByID(userID, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "PDI não encontrado",
+		})
+	}
+
+	from := pdi.Status
+	transition, err := h.workflowService.Transition(pdi, userID, req)
+	if err != nil {
+		var blocked *services.TransitionBlockedError
+		switch {
+		case errors.Is(err, services.ErrInvalidPDIStatus):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrTransitionNotAllowed):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":   err.Error(),
+				"allowed": services.AllowedTransitions(from),
+			})
+		case errors.Is(err, services.ErrTransitionConflict):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.As(err, &blocked):
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error":   "O PDI não cumpre as condições do novo status",
+				"reasons": blocked.Errors,
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Erro ao alterar status do PDI",
+			})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"pdi":        pdi,
+		"transition": transition,
+	})
+}
+
+// ListTransitions devolve o histórico de status do PDI.
+func (h *PDIHandler) ListTransitions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "usuário não autenticado",
+		})
+	}
+
+	pdi, err := h.pdiService.GetPDIByID(userID, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "PDI não encontrado",
+		})
+	}
+
+	transitions, err := h.workflowService.ListTransitions(pdi.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao buscar histórico de status",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":      pdi.Status,
+		"allowed":     services.AllowedTransitions(pdi.Status),
+		"transitions": transitions,
+	})
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"meu-pdi-estrategico/backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PersonaHandler struct {
+	personaService *services.PersonaService
+}
+
+func NewPersonaHandler(personaService *services.PersonaService) *PersonaHandler {
+	return &PersonaHandler{personaService: personaService}
+}
+
+type RequestPersonaWeight struct {
+	Weight int `json:"weight"`
+}
+
+// ListPersonas devolve as personas que podem ser escolhidas para um PDI.
+func (h *PersonaHandler) ListPersonas(c *fiber.Ctx) error {
+	personas, err := h.personaService.ListAvailable()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao buscar personas",
+		})
+	}
+	return c.JSON(personas)
+}
+
+// ListVersions devolve todas as versões de personas, com os pesos atuais.
+// Aceita o filtro opcional slug.
+func (h *PersonaHandler) ListVersions(c *fiber.Ctx) error {
+	versions, err := h.personaService.ListVersions(c.Query("slug"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao buscar personas",
+		})
+	}
+	return c.JSON(versions)
+}
+
+// CreateVersion cria uma nova versão de persona.
+func (h *PersonaHandler) CreateVersion(c *fiber.Ctx) error {
+	var req services.CreatePersonaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Erro ao processar a persona",
+		})
+	}
+
+	persona, err := h.personaService.CreateVersion(req)
+	if err != nil {
+		return personaError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(persona)
+}
+
+// UpdateWeight altera o peso de uma versão para testes A/B.
+func (h *PersonaHandler) UpdateWeight(c *fiber.Ctx) error {
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Versão inválida",
+		})
+	}
+
+	var req RequestPersonaWeight
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Erro ao processar o peso",
+		})
+	}
+
+	persona, err := h.personaService.SetWeight(c.Params("slug"), version, req.Weight)
+	if err != nil {
+		return personaError(c, err)
+	}
+	return c.JSON(persona)
+}
+
+// ActivateVersion torna a versão a única servida, por exemplo para voltar a
+// uma versão anterior.
+func (h *PersonaHandler) ActivateVersion(c *fiber.Ctx) error {
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Versão inválida",
+		})
+	}
+
+	persona, err := h.personaService.Activate(c.Params("slug"), version)
+	if err != nil {
+		return personaError(c, err)
+	}
+	return c.JSON(persona)
+}
+
+func personaError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrPersonaNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Persona não encontrada",
+		})
+	case errors.Is(err, services.ErrInvalidPersona):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao salvar persona",
+		})
+	}
+}
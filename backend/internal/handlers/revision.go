@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"meu-pdi-estrategico/backend/internal/models"
+	"meu-pdi-estrategico/backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RevisionHandler struct {
+	revisionService *services.RevisionService
+	pdiService      *services.PDIService
+}
+
+func NewRevisionHandler(revisionService *services.RevisionService, pdiService *services.PDIService) *RevisionHandler {
+	return &RevisionHandler{
+		revisionService: revisionService,
+		pdiService:      pdiService,
+	}
+}
+
+// ListRevisions devolve o histórico do conteúdo do PDI, da revisão mais
+// recente à mais antiga.
+func (h *RevisionHandler) ListRevisions(c *fiber.Ctx) error {
+	pdi, err := h.userPDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	revisions, err := h.revisionService.ListRevisions(pdi.ID)
+	if err != nil {
+		return revisionError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"revisions": revisions})
+}
+
+// GetRevision devolve uma revisão com o conteúdo completo.
+func (h *RevisionHandler) GetRevision(c *fiber.Ctx) error {
+	pdi, err := h.userPDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	number, err := strconv.Atoi(c.Params("number"))
+	if err != nil || number < 1 {
+		return revisionError(c, services.ErrRevisionNotFound)
+	}
+
+	revision, err := h.revisionService.GetRevision(pdi.ID, number)
+	if err != nil {
+		return revisionError(c, err)
+	}
+
+	return c.JSON(revision)
+}
+
+// DiffRevisions compara duas revisões. Sem to, usa a mais recente; sem from,
+// a anterior a to.
+func (h *RevisionHandler) DiffRevisions(c *fiber.Ctx) error {
+	pdi, err := h.userPDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	from, to := c.QueryInt("from", 0), c.QueryInt("to", 0)
+	if from < 0 || to < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "from e to devem ser números de revisão",
+		})
+	}
+
+	diff, err := h.revisionService.Diff(pdi.ID, from, to)
+	if err != nil {
+		return revisionError(c, err)
+	}
+
+	return c.JSON(diff)
+}
+
+// RestoreRevision volta o conteúdo do PDI ao de uma revisão anterior, gerando
+// uma nova revisão.
+func (h *RevisionHandler) RestoreRevision(c *fiber.Ctx) error {
+	pdi, err := h.userPDI(c)
+	if pdi == nil {
+		return err
+	}
+
+	number, err := strconv.Atoi(c.Params("number"))
+	if err != nil || number < 1 {
+		return revisionError(c, services.ErrRevisionNotFound)
+	}
+
+	revision, err := h.revisionService.Restore(pdi, number)
+	if err != nil {
+		return revisionError(c, err)
+	}
+
+	return c.JSON(revision)
+}
+
+func (h *RevisionHandler) userPDI(c *fiber.Ctx) (*models.PDI, error) {
+	userID := c.Locals("user_id").(string)
+	if userID == "" {
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "usuário não autenticado",
+		})
+	}
+
+	pdi, err := h.pdiService.GetPDIByID(userID, c.Params("id"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "PDI não encontrado",
+		})
+	}
+	return pdi, nil
+}
+
+// revisionError responde os erros das revisões. A restauração passa pela
+// validação do save_pdi e pode devolver os mesmos erros de conteúdo.
+func revisionError(c *fiber.Ctx, err error) error {
+	var validation *services.PDIValidationError
+	switch {
+	case errors.As(err, &validation):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":             "Conteúdo do PDI inválido",
+			"validation_errors": validation.Errors,
+		})
+	case errors.Is(err, services.ErrRevisionNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao buscar revisões do PDI",
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"meu-pdi-estrategico/backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SearchHandler struct {
+	searchService *services.SearchService
+}
+
+func NewSearchHandler(searchService *services.SearchService) *SearchHandler {
+	return &SearchHandler{
+		searchService: searchService,
+	}
+}
+
+type ResponseSearch struct {
+	Query   string                  `json:"query"`
+	Results []services.SearchResult `json:"results"`
+}
+
+// Search busca o termo q nos PDIs do usuário autenticado, no conteúdo deles e
+// nas mensagens do chat. Aceita o limite opcional limit (padrão 20, até 50).
+func (h *SearchHandler) Search(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "usuário não autenticado",
+		})
+	}
+
+	request := services.SearchRequest{Query: c.Query("q")}
+	if limit := c.Query("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": services.ErrInvalidSearchLimit.Error(),
+			})
+		}
+		request.Limit = parsed
+	}
+
+	results, err := h.searchService.Search(userID, request)
+	if err != nil {
+		if errors.Is(err, services.ErrEmptySearchQuery) || errors.Is(err, services.ErrInvalidSearchLimit) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao buscar",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(ResponseSearch{
+		Query:   request.Query,
+		Results: results,
+	})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	sseWriteTimeout      = 10 * time.Second
+	sseKeepAliveInterval = 15 * time.Second
+)
+
+// sseStream escreve eventos Server-Sent Events na conexão. Qualquer falha de
+// escrita indica que o cliente desconectou e cancela o contexto da geração.
+type sseStream struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	conn   net.Conn
+	cancel context.CancelFunc
+	closed bool
+}
+
+func setSSEHeaders(c *fiber.Ctx) {
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+}
+
+func newSSEStream(w *bufio.Writer, conn net.Conn, cancel context.CancelFunc) *sseStream {
+	return &sseStream{w: w, conn: conn, cancel: cancel}
+}
+
+func (s *sseStream) Send(event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload)
+	s.flush()
+}
+
+// KeepAlive envia comentários periódicos para manter a conexão aberta
+// enquanto o provedor não produz eventos. A função devolvida encerra o envio.
+func (s *sseStream) KeepAlive(interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				if !s.closed {
+					fmt.Fprint(s.w, ": keep-alive\n\n")
+					s.flush()
+				}
+				s.mu.Unlock()
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+func (s *sseStream) flush() {
+	// O WriteTimeout do servidor vale para a resposta inteira; cada evento
+	// renova o prazo para que a transmissão possa durar mais que ele.
+	if s.conn != nil {
+		s.conn.SetWriteDeadline(time.Now().Add(sseWriteTimeout))
+	}
+	if err := s.w.Flush(); err != nil {
+		s.closed = true
+		s.cancel()
+	}
+}
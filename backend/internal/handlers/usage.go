@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"time"
+
+	"meu-pdi-estrategico/backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type UsageHandler struct {
+	usageService *services.UsageService
+	quotaService *services.QuotaService
+	pdiService   *services.PDIService
+}
+
+func NewUsageHandler(usageService *services.UsageService, quotaService *services.QuotaService, pdiService *services.PDIService) *UsageHandler {
+	return &UsageHandler{
+		usageService: usageService,
+		quotaService: quotaService,
+		pdiService:   pdiService,
+	}
+}
+
+// GetUserUsage devolve o consumo de tokens e o custo de todos os PDIs do
+// usuário autenticado. Aceita os filtros opcionais from e to.
+func (h *UsageHandler) GetUserUsage(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "usuário não autenticado",
+		})
+	}
+
+	period, err := usagePeriod(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Período inválido: use datas no formato AAAA-MM-DD ou RFC 3339",
+		})
+	}
+
+	report, err := h.usageService.GetUserUsage(userID, period)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao buscar consumo",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// GetPDIUsage devolve o consumo de tokens e o custo de um PDI.
+func (h *UsageHandler) GetPDIUsage(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "usuário não autenticado",
+		})
+	}
+
+	pdi, err := h.pdiService.GetPDIByID(userID, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "PDI não encontrado",
+		})
+	}
+
+	period, err := usagePeriod(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Período inválido: use datas no formato AAAA-MM-DD ou RFC 3339",
+		})
+	}
+
+	report, err := h.usageService.GetPDIUsage(userID, pdi.ID, period)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao buscar consumo",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// GetUserQuota devolve os limites do plano do usuário e o uso no dia e no mês.
+func (h *UsageHandler) GetUserQuota(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "usuário não autenticado",
+		})
+	}
+
+	status, err := h.quotaService.Status(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Erro ao buscar limites de uso",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(status)
+}
+
+// usagePeriod lê os parâmetros from e to. Uma data sem horário em to inclui
+// o dia inteiro.
+func usagePeriod(c *fiber.Ctx) (services.UsagePeriod, error) {
+	var period services.UsagePeriod
+
+	if from := c.Query("from"); from != "" {
+		parsed, _, err := parseUsageTime(from)
+		if err != nil {
+			return period, err
+		}
+		period.From = parsed
+	}
+
+	if to := c.Query("to"); to != "" {
+		parsed, dateOnly, err := parseUsageTime(to)
+		if err != nil {
+			return period, err
+		}
+		if dateOnly {
+			parsed = parsed.AddDate(0, 0, 1)
+		}
+		period.To = parsed
+	}
+
+	return period, nil
+}
+
+func parseUsageTime(value string) (time.Time, bool, error) {
+	if parsed, err := time.Parse("2006-01-02", value); err == nil {
+		return parsed, true, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	return parsed, false, err
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminMiddleware libera a rota apenas para administradores. Deve ser usado
+// depois de AuthMiddleware, que define o user_id.
+func AdminMiddleware(isAdmin func(userID string) (bool, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+		admin, err := isAdmin(userID)
+		if err != nil || !admin {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "acesso restrito a administradores",
+			})
+		}
+		return c.Next()
+	}
+}
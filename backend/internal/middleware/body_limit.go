@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyLimit recusa requisições com corpo maior que limit. O limite do
+// servidor (fiber.Config.BodyLimit) precisa comportar o envio de anexos; este
+// middleware devolve as demais rotas a um limite menor. Requisições para as
+// quais skip devolve true não são limitadas.
+func BodyLimit(limit int, skip func(c *fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if skip != nil && skip(c) {
+			return c.Next()
+		}
+		if c.Request().Header.ContentLength() > limit || len(c.Body()) > limit {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error": "corpo da requisição maior que o permitido",
+			})
+		}
+		return c.Next()
+	}
+}
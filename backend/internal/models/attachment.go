@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Attachment é um documento enviado junto com uma mensagem do usuário. Só o
+// texto extraído é guardado; o arquivo original é descartado.
+type Attachment struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	MessageID   uuid.UUID `gorm:"type:uuid;not null;index" json:"message_id"`
+	FileName    string    `gorm:"type:text;not null" json:"file_name"`
+	ContentType string    `gorm:"type:varchar(100);not null" json:"content_type"`
+	Size        int64     `gorm:"not null" json:"size"`
+	SHA256      string    `gorm:"type:varchar(64);not null" json:"sha256"`
+	Text        string    `gorm:"type:text;not null" json:"-"`
+	Truncated   bool      `gorm:"not null;default:false" json:"truncated"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (a *Attachment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ConversationArchive guarda o estado de uma conversa reiniciada: as mensagens
+// arquivadas apontam para ele (messages.archive_id) e ficam excluídas
+// logicamente. A thread do provedor e o resumo são mantidos para que a
+// conversa possa ser restaurada como estava.
+type ConversationArchive struct {
+	ID                     uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	PDIID                  string     `gorm:"type:uuid;not null;index" json:"pdi_id"`
+	ThreadID               string     `gorm:"type:text" json:"thread_id,omitempty"`
+	ActiveBranchID         *uuid.UUID `gorm:"type:uuid" json:"active_branch_id,omitempty"`
+	ContextSummary         string     `gorm:"type:text" json:"-"`
+	ContextSummarizedUntil *time.Time `json:"-"`
+	// Content só é preenchido quando o reinício também limpou o conteúdo do PDI
+	Content      *string   `gorm:"type:jsonb" json:"-"`
+	ContentReset bool      `gorm:"not null;default:false" json:"content_reset"`
+	MessageCount int       `gorm:"not null;default:0" json:"message_count"`
+	FirstMessage string    `gorm:"type:text" json:"first_message"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (a *ConversationArchive) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type FeedbackRating string
+
+const (
+	FeedbackUp   FeedbackRating = "up"
+	FeedbackDown FeedbackRating = "down"
+)
+
+// MessageFeedback é a avaliação de uma resposta do assistente. Cada usuário
+// tem no máximo uma avaliação por resposta.
+type MessageFeedback struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	MessageID uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_message_feedbacks_message_user" json:"message_id"`
+	UserID    uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_message_feedbacks_message_user" json:"user_id"`
+	Rating    FeedbackRating `gorm:"type:varchar(10);not null" json:"rating"`
+	Comment   string         `gorm:"type:text" json:"comment,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+func (f *MessageFeedback) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Goal é um objetivo do PDI. Position guarda a ordem dos objetivos no plano,
+// a mesma do array goals do conteúdo.
+type Goal struct {
+	ID           uuid.UUID    `gorm:"type:uuid;primary_key" json:"id"`
+	PDIID        string       `gorm:"type:uuid;not null;index" json:"pdi_id"`
+	Position     int          `gorm:"not null;default:0" json:"position"`
+	Description  string       `gorm:"type:text;not null" json:"description"`
+	Alignment    string       `gorm:"type:text" json:"alignment"`
+	Progress     float64      `gorm:"type:numeric(5,2);not null;default:0" json:"progress"`
+	ProgressNote string       `gorm:"type:text" json:"progress_note,omitempty"`
+	KeyResults   []KeyResult  `gorm:"foreignKey:GoalID" json:"key_results"`
+	ActionItems  []ActionItem `gorm:"foreignKey:GoalID" json:"action_items"`
+	Skills       []Skill      `gorm:"foreignKey:GoalID" json:"skills"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+func (g *Goal) BeforeCreate(tx *gorm.DB) error {
+	if g.ID == uuid.Nil {
+		g.ID = uuid.New()
+	}
+	return nil
+}
+
+// KeyResult é um resultado-chave que mede o objetivo. Done e DoneAt marcam o
+// resultado como atingido e, como nas ações, não fazem parte do conteúdo.
+type KeyResult struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	GoalID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"goal_id"`
+	Position    int        `gorm:"not null;default:0" json:"position"`
+	Description string     `gorm:"type:text;not null" json:"description"`
+	Done        bool       `gorm:"not null;default:false" json:"done"`
+	DoneAt      *time.Time `json:"done_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (k *KeyResult) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
+
+// ActionItem é uma ação do plano do objetivo. Done e DoneAt acompanham a
+// execução e não fazem parte do conteúdo do PDI.
+type ActionItem struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	GoalID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"goal_id"`
+	Position    int        `gorm:"not null;default:0" json:"position"`
+	Description string     `gorm:"type:text;not null" json:"description"`
+	Done        bool       `gorm:"not null;default:false" json:"done"`
+	DoneAt      *time.Time `json:"done_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (a *ActionItem) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+type SkillKind string
+
+const (
+	SkillHard SkillKind = "hard"
+	SkillSoft SkillKind = "soft"
+)
+
+// Skill é uma competência a desenvolver no objetivo.
+type Skill struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	GoalID    uuid.UUID `gorm:"type:uuid;not null;index" json:"goal_id"`
+	Kind      SkillKind `gorm:"type:varchar(10);not null" json:"kind"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	Name      string    `gorm:"type:text;not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *Skill) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
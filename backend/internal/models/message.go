@@ -16,19 +16,44 @@ const (
 )
 
 type Message struct {
-	ID        uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
-	PDIID     string         `gorm:"not null" json:"pdi_id"`
-	Content   string         `gorm:"type:text;not null" json:"content"`
-	Role      string         `gorm:"not null" json:"role"`
-	Status    MessageStatus  `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
-	CreatedAt time.Time      `gorm:"not null" json:"created_at"`
-	UpdatedAt time.Time      `gorm:"not null" json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	PDIID             string         `gorm:"not null" json:"pdi_id"`
+	Content           string         `gorm:"type:text;not null" json:"content"`
+	Role              string         `gorm:"not null" json:"role"`
+	Status            MessageStatus  `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Error             string         `gorm:"type:text" json:"error,omitempty"`
+	ParentID          *uuid.UUID     `gorm:"type:uuid;index" json:"parent_id,omitempty"`
+	BranchID          uuid.UUID      `gorm:"type:uuid;index" json:"branch_id"`
+	RunID             string         `gorm:"type:text" json:"run_id,omitempty"`
+	ProviderMessageID string         `gorm:"type:text" json:"-"`
+	Attempts          int            `gorm:"not null;default:0" json:"attempts"`
+	Model             string         `gorm:"type:text" json:"model,omitempty"`
+	PromptTokens      int            `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens  int            `gorm:"not null;default:0" json:"completion_tokens"`
+	TotalTokens       int            `gorm:"not null;default:0" json:"total_tokens"`
+	CostUSD           float64        `gorm:"type:numeric(12,6);not null;default:0" json:"cost_usd"`
+	PersonaID         *uuid.UUID     `gorm:"type:uuid" json:"persona_id,omitempty"`
+	Attachments       []Attachment   `gorm:"foreignKey:MessageID" json:"attachments,omitempty"`
+	ArchiveID         *uuid.UUID     `gorm:"type:uuid;index" json:"-"`
+	CreatedAt         time.Time      `gorm:"not null" json:"created_at"`
+	UpdatedAt         time.Time      `gorm:"not null" json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (m *Message) BeforeCreate(tx *gorm.DB) error {
 	if m.ID == uuid.Nil {
 		m.ID = uuid.New()
 	}
+	// Sem ramo informado, a mensagem fica no ramo principal do PDI
+	if m.BranchID == uuid.Nil {
+		m.BranchID = MainBranchID(m.PDIID)
+	}
 	return nil
-} 
+}
+
+// MainBranchID devolve o ID do ramo principal da conversa do PDI, que é o
+// próprio ID do PDI. Os demais ramos nascem da edição de mensagens.
+func MainBranchID(pdiID string) uuid.UUID {
+	id, _ := uuid.Parse(pdiID)
+	return id
+}
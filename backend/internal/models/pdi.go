@@ -18,6 +18,15 @@ const (
 	PDIStatusDone       PDIStatus = "DONE"
 )
 
+// Valid informa se o status é um dos status do fluxo do PDI.
+func (s PDIStatus) Valid() bool {
+	switch s {
+	case PDIStatusDraft, PDIStatusPending, PDIStatusInProgress, PDIStatusDone:
+		return true
+	}
+	return false
+}
+
 type PDI struct {
 	ID                     string     `gorm:"type:uuid;primary_key" json:"id"`
 	Name                   string     `gorm:"type:text;not null" json:"name"`
@@ -26,6 +35,10 @@ type PDI struct {
 	Activated              bool       `gorm:"default:true" json:"activated"`
 	Status                 PDIStatus  `gorm:"type:varchar(20);not null;default:'DRAFT'" json:"status"`
 	Content                string     `gorm:"type:jsonb" json:"content"`
+	Persona                string     `gorm:"type:varchar(100)" json:"persona"`
+	ActiveBranchID         *uuid.UUID `gorm:"type:uuid" json:"active_branch_id,omitempty"`
+	ContextSummary         string     `gorm:"type:text" json:"-"`
+	ContextSummarizedUntil *time.Time `json:"-"`
 	CreatedAt              time.Time  `json:"created_at"`
 	UpdatedAt              time.Time  `json:"updated_at"`
 	DeletedAt              *time.Time `gorm:"index" json:"deleted_at,omitempty"`
@@ -38,6 +51,14 @@ func (p *PDI) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// BranchID devolve o ramo ativo da conversa do PDI.
+func (p *PDI) BranchID() uuid.UUID {
+	if p.ActiveBranchID != nil {
+		return *p.ActiveBranchID
+	}
+	return MainBranchID(p.ID)
+}
+
 func (p *PDI) BeforeSave(tx *gorm.DB) error {
 	if p.Name == "" {
 		return errors.New("nome é obrigatório")
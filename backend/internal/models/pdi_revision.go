@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RevisionAuthor string
+
+const (
+	RevisionAuthorUser      RevisionAuthor = "user"
+	RevisionAuthorAssistant RevisionAuthor = "assistant"
+	// RevisionAuthorSystem marca o conteúdo que o PDI já tinha antes do
+	// histórico começar a ser registrado.
+	RevisionAuthorSystem RevisionAuthor = "system"
+)
+
+// PDIRevision é uma versão do conteúdo do PDI. Number é sequencial por PDI;
+// MessageID é a mensagem do chat que levou o assistente a fazer a alteração.
+type PDIRevision struct {
+	ID           uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	PDIID        string         `gorm:"type:uuid;not null;uniqueIndex:idx_pdi_revisions_pdi_number" json:"pdi_id"`
+	Number       int            `gorm:"not null;uniqueIndex:idx_pdi_revisions_pdi_number" json:"number"`
+	Content      string         `gorm:"type:jsonb;not null" json:"content,omitempty"`
+	Author       RevisionAuthor `gorm:"type:varchar(20);not null" json:"author"`
+	MessageID    *uuid.UUID     `gorm:"type:uuid" json:"message_id,omitempty"`
+	Reason       string         `gorm:"type:varchar(50)" json:"reason"`
+	RestoredFrom *int           `json:"restored_from,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+func (r *PDIRevision) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PDITransition registra uma mudança de status do PDI e quem a fez.
+type PDITransition struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	PDIID      string    `gorm:"type:uuid;not null;index" json:"pdi_id"`
+	FromStatus PDIStatus `gorm:"type:varchar(20);not null" json:"from"`
+	ToStatus   PDIStatus `gorm:"type:varchar(20);not null" json:"to"`
+	UserID     string    `gorm:"type:uuid;not null" json:"user_id"`
+	Note       string    `gorm:"type:text" json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (t *PDITransition) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
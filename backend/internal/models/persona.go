@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Persona é uma versão imutável do comportamento do assistente: instruções,
+// modelo, temperatura e ferramentas. Versões do mesmo slug com peso maior que
+// zero são servidas aos PDIs na proporção dos pesos, o que permite testes A/B
+// e voltar a uma versão anterior.
+type Persona struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	Slug         string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_personas_slug_version" json:"slug"`
+	Version      int       `gorm:"not null;uniqueIndex:idx_personas_slug_version" json:"version"`
+	Name         string    `gorm:"type:text;not null" json:"name"`
+	Description  string    `gorm:"type:text" json:"description"`
+	Instructions string    `gorm:"type:text;not null" json:"instructions"`
+	Model        string    `gorm:"type:varchar(100)" json:"model"`
+	Temperature  *float64  `json:"temperature"`
+	// Tools restringe as ferramentas disponíveis; vazio, todas são oferecidas.
+	Tools     []string  `gorm:"type:jsonb;serializer:json" json:"tools"`
+	Weight    int       `gorm:"not null;default:0" json:"weight"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (p *Persona) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
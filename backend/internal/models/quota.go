@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// DefaultPlan é o plano atribuído a novos usuários.
+const DefaultPlan = "free"
+
+// QuotaPlan define os limites de uso do LLM de um plano. Limites iguais a
+// zero não restringem o uso.
+type QuotaPlan struct {
+	Name               string    `gorm:"type:varchar(50);primary_key" json:"name"`
+	MessagesPerDay     int       `gorm:"not null;default:0" json:"messages_per_day"`
+	TokensPerMonth     int64     `gorm:"not null;default:0" json:"tokens_per_month"`
+	MaxMonthlySpendUSD float64   `gorm:"type:numeric(12,6);not null;default:0" json:"max_monthly_spend_usd"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
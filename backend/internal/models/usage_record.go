@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UsageKindSummary identifica o consumo dos resumos da janela de contexto.
+const UsageKindSummary = "summary"
+
+// UsageRecord registra o consumo de chamadas ao modelo que não geram uma
+// mensagem, como os resumos da conversa. Ele entra no consumo e na cota do
+// usuário junto com as respostas do assistente.
+type UsageRecord struct {
+	ID               uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	PDIID            string    `gorm:"type:uuid;not null;index" json:"pdi_id"`
+	Kind             string    `gorm:"type:varchar(30);not null" json:"kind"`
+	Model            string    `gorm:"type:text" json:"model,omitempty"`
+	PromptTokens     int       `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens int       `gorm:"not null;default:0" json:"completion_tokens"`
+	TotalTokens      int       `gorm:"not null;default:0" json:"total_tokens"`
+	CostUSD          float64   `gorm:"type:numeric(12,6);not null;default:0" json:"cost_usd"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func (r *UsageRecord) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -7,6 +7,11 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID                  uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
 	Nickname            string         `gorm:"type:text;not null" json:"nickname"`
@@ -21,6 +26,8 @@ type User struct {
 	LastLogin           *time.Time     `json:"last_login"`
 	FailedLoginAttempts int            `gorm:"default:0" json:"-"`
 	AccountLockedUntil  *time.Time     `json:"-"`
+	Plan                string         `gorm:"type:varchar(50);not null;default:'free'" json:"plan"`
+	Role                string         `gorm:"type:varchar(20);not null;default:'user'" json:"role"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
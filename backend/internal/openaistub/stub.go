@@ -0,0 +1,225 @@
+// Package openaistub é um servidor HTTP que reproduz interações gravadas com a
+// API da OpenAI (cassetes), para testar o chat sem acesso à rede.
+//
+// Por padrão o servidor reproduz o cassete. Com OPENAI_STUB_RECORD=1 e uma
+// OPENAI_API_KEY válida, ele repassa as requisições para a API real e grava o
+// cassete ao fim do teste.
+package openaistub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const defaultUpstream = "https://api.openai.com"
+
+// Request identifica a requisição esperada. BodyContains lista trechos que o
+// corpo da requisição deve conter.
+type Request struct {
+	Method       string   `json:"method"`
+	Path         string   `json:"path"`
+	BodyContains []string `json:"body_contains,omitempty"`
+}
+
+type Response struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette é a sequência de interações de um cenário, reproduzida em ordem.
+type Cassette struct {
+	Name         string        `json:"name"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Server reproduz ou grava um cassete.
+type Server struct {
+	*httptest.Server
+
+	t         testing.TB
+	path      string
+	recording bool
+	upstream  string
+
+	mu       sync.Mutex
+	cassette Cassette
+	next     int
+}
+
+// New inicia o servidor para o cassete em path. Ao fim do teste, confere se
+// todas as interações foram consumidas ou, gravando, salva o cassete.
+func New(t testing.TB, path string) *Server {
+	t.Helper()
+
+	s := &Server{
+		t:         t,
+		path:      path,
+		recording: os.Getenv("OPENAI_STUB_RECORD") == "1",
+		upstream:  defaultUpstream,
+	}
+	if upstream := os.Getenv("OPENAI_STUB_UPSTREAM"); upstream != "" {
+		s.upstream = upstream
+	}
+
+	if s.recording {
+		s.cassette.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Erro ao ler cassete %s: %v", path, err)
+		}
+		if err := json.Unmarshal(data, &s.cassette); err != nil {
+			t.Fatalf("Cassete %s inválido: %v", path, err)
+		}
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.finish)
+	return s
+}
+
+// Client devolve um cliente da OpenAI apontado para o servidor.
+func (s *Server) Client() *openai.Client {
+	key := "stub"
+	if s.recording {
+		key = os.Getenv("OPENAI_API_KEY")
+	}
+	config := openai.DefaultConfig(key)
+	config.BaseURL = s.URL + "/v1"
+	return openai.NewClientWithConfig(config)
+}
+
+// Remaining devolve quantas interações do cassete ainda não foram usadas.
+func (s *Server) Remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.cassette.Interactions) - s.next
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+
+	if s.recording {
+		s.record(w, r, path, body)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next >= len(s.cassette.Interactions) {
+		s.fail(w, "requisição inesperada %s %s: cassete %s esgotado", r.Method, path, s.cassette.Name)
+		return
+	}
+
+	interaction := s.cassette.Interactions[s.next]
+	expected := interaction.Request
+	if expected.Method != r.Method || expected.Path != path {
+		s.fail(w, "interação %d do cassete %s: esperado %s %s, recebido %s %s", s.next, s.cassette.Name, expected.Method, expected.Path, r.Method, path)
+		return
+	}
+	for _, fragment := range expected.BodyContains {
+		if !bytes.Contains(body, []byte(fragment)) {
+			s.fail(w, "interação %d do cassete %s: corpo sem %q: %s", s.next, s.cassette.Name, fragment, body)
+			return
+		}
+	}
+	s.next++
+
+	status := interaction.Response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(interaction.Response.Body)
+}
+
+// fail responde com um erro no formato da OpenAI e marca o teste como falho.
+func (s *Server) fail(w http.ResponseWriter, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	s.t.Errorf("[openaistub] %s", message)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"message": message, "type": "stub_error"},
+	})
+}
+
+// record repassa a requisição para a API real e guarda a interação.
+func (s *Server) record(w http.ResponseWriter, r *http.Request, path string, body []byte) {
+	upstream, err := http.NewRequestWithContext(r.Context(), r.Method, s.upstream+r.URL.RequestURI(), bytes.NewReader(body))
+	if err != nil {
+		s.fail(w, "erro ao montar requisição: %v", err)
+		return
+	}
+	upstream.Header = r.Header.Clone()
+	// Sem Accept-Encoding, o cliente HTTP pede gzip por conta própria e
+	// devolve o corpo descompactado, que é o que o cassete guarda
+	upstream.Header.Del("Accept-Encoding")
+
+	resp, err := http.DefaultClient.Do(upstream)
+	if err != nil {
+		s.fail(w, "erro ao chamar a API: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+
+	s.mu.Lock()
+	s.cassette.Interactions = append(s.cassette.Interactions, Interaction{
+		Request:  Request{Method: r.Method, Path: path},
+		Response: Response{Status: resp.StatusCode, Body: json.RawMessage(respBody)},
+	})
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	w.Write(respBody)
+}
+
+func (s *Server) finish() {
+	s.Server.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.recording {
+		data, err := json.MarshalIndent(s.cassette, "", "  ")
+		if err != nil {
+			s.t.Errorf("Erro ao serializar cassete: %v", err)
+			return
+		}
+		if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+			s.t.Errorf("Erro ao criar diretório do cassete: %v", err)
+			return
+		}
+		if err := os.WriteFile(s.path, append(data, '\n'), 0o644); err != nil {
+			s.t.Errorf("Erro ao gravar cassete: %v", err)
+		}
+		return
+	}
+
+	if remaining := len(s.cassette.Interactions) - s.next; remaining > 0 {
+		next := s.cassette.Interactions[s.next].Request
+		s.t.Errorf("[openaistub] cassete %s com %d interações não usadas, a próxima é %s %s", s.cassette.Name, remaining, next.Method, next.Path)
+	}
+}
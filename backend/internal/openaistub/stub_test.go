@@ -0,0 +1,58 @@
+package openaistub
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServer_RecordGzipUpstream(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			io.WriteString(w, `{"id":"asst_1"}`)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		io.WriteString(gz, `{"id":"asst_1"}`)
+		gz.Close()
+	}))
+	defer upstream.Close()
+
+	t.Setenv("OPENAI_STUB_RECORD", "1")
+	t.Setenv("OPENAI_STUB_UPSTREAM", upstream.URL)
+	path := filepath.Join(t.TempDir(), "gzip.json")
+
+	t.Run("record", func(t *testing.T) {
+		stub := New(t, path)
+		req, _ := http.NewRequest(http.MethodGet, stub.URL+"/v1/assistants/asst_1", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Erro na requisição: %v", err)
+		}
+		resp.Body.Close()
+	})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Cassete não gravado: %v", err)
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil || len(cassette.Interactions) != 1 {
+		t.Fatalf("Cassete inválido: %v\n%s", err, data)
+	}
+	var body struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(cassette.Interactions[0].Response.Body, &body); err != nil || body.ID != "asst_1" {
+		t.Errorf("Corpo gravado = %s, %v", cassette.Interactions[0].Response.Body, err)
+	}
+}
@@ -2,15 +2,42 @@
 package routes
 
 import (
+	"regexp"
+
 	"meu-pdi-estrategico/backend/internal/handlers"
 	"meu-pdi-estrategico/backend/internal/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// uploadPath casa com as rotas que recebem anexos: o envio de mensagens, com e
+// sem streaming.
+var uploadPath = regexp.MustCompile(`^/api/pdis/[^/]+/chat(/stream)?/?$`)
+
+// IsUploadRequest indica se a requisição pode trazer anexos e, por isso, usa o
+// limite de corpo dos anexos.
+func IsUploadRequest(c *fiber.Ctx) bool {
+	return c.Method() == fiber.MethodPost && uploadPath.MatchString(c.Path())
+}
+
 func SetupChatRoutes(app *fiber.App, handler *handlers.ChatHandler) {
 	pdiGroup := app.Group("/api/pdis", middleware.AuthMiddleware())
 	
 	pdiGroup.Get("/:id/chat", handler.GetMessages)
 	pdiGroup.Post("/:id/chat", handler.CreateMessage)
+	pdiGroup.Post("/:id/chat/stream", handler.StreamMessage)
+	pdiGroup.Post("/:id/chat/rehydrate", handler.RehydrateThread)
+	pdiGroup.Get("/:id/chat/rehydrate", handler.GetRehydrateStatus)
+	pdiGroup.Get("/:id/chat/export", handler.ExportMessages)
+	pdiGroup.Post("/:id/chat/reset", handler.ResetConversation)
+	pdiGroup.Get("/:id/chat/archives", handler.ListArchives)
+	pdiGroup.Get("/:id/chat/archives/:archiveId", handler.GetArchive)
+	pdiGroup.Post("/:id/chat/archives/:archiveId/restore", handler.RestoreArchive)
+	pdiGroup.Get("/:id/chat/branches", handler.ListBranches)
+	pdiGroup.Post("/:id/chat/branches/:branchId/activate", handler.SwitchBranch)
+	pdiGroup.Get("/:id/chat/:messageId", handler.GetMessageStatus)
+	pdiGroup.Get("/:id/chat/:messageId/events", handler.SubscribeMessage)
+	pdiGroup.Post("/:id/chat/:messageId/retry", handler.RetryMessage)
+	pdiGroup.Post("/:id/chat/:messageId/regenerate", handler.RegenerateMessage)
+	pdiGroup.Post("/:id/chat/:messageId/edit", handler.EditMessage)
 } 
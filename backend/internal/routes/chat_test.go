@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"meu-pdi-estrategico/backend/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestBodyLimit_UploadRoutes(t *testing.T) {
+	app := fiber.New(fiber.Config{BodyLimit: 1 << 20})
+	app.Use(middleware.BodyLimit(1024, IsUploadRequest))
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Post("/api/pdis/:id/chat", ok)
+	app.Post("/api/pdis/:id/chat/stream", ok)
+	app.Post("/api/pdis/:id/chat/:messageId/edit", ok)
+	app.Post("/api/pdis", ok)
+
+	body := strings.Repeat("a", 4096)
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/api/pdis/1/chat", http.StatusOK},
+		{"/api/pdis/1/chat/stream", http.StatusOK},
+		{"/api/pdis/1/chat/2/edit", http.StatusRequestEntityTooLarge},
+		{"/api/pdis", http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(body)))
+		if err != nil {
+			t.Fatalf("Erro na requisição: %v", err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("POST %s = %d, esperado %d", tt.path, resp.StatusCode, tt.status)
+		}
+	}
+}
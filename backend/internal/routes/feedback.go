@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"meu-pdi-estrategico/backend/internal/handlers"
+	"meu-pdi-estrategico/backend/internal/middleware"
+	"meu-pdi-estrategico/backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupFeedbackRoutes(app *fiber.App, handler *handlers.FeedbackHandler, userService *services.UserService) {
+	pdiGroup := app.Group("/api/pdis", middleware.AuthMiddleware())
+	pdiGroup.Post("/:id/chat/:messageId/feedback", handler.CreateFeedback)
+
+	adminGroup := app.Group("/api/admin/feedback", middleware.AuthMiddleware(), middleware.AdminMiddleware(userService.IsAdmin))
+	adminGroup.Get("/export", handler.ExportFeedback)
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"meu-pdi-estrategico/backend/internal/handlers"
+	"meu-pdi-estrategico/backend/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupGoalRoutes(app *fiber.App, handler *handlers.GoalHandler) {
+	pdiGroup := app.Group("/api/pdis", middleware.AuthMiddleware())
+
+	pdiGroup.Get("/:id/goals", handler.ListGoals)
+	pdiGroup.Patch("/:id/goals/:goalId", handler.UpdateGoal)
+	pdiGroup.Patch("/:id/key-results/:keyResultId", handler.UpdateKeyResult)
+	pdiGroup.Patch("/:id/action-items/:actionItemId", handler.UpdateActionItem)
+}
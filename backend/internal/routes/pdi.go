@@ -14,4 +14,6 @@ func SetupPDIRoutes(app *fiber.App, pdiHandler *handlers.PDIHandler) {
 	pdiGroup.Post("", pdiHandler.CreatePDI)
 	pdiGroup.Get("/:id", pdiHandler.GetPDIByID)
 	pdiGroup.Patch("/:id", pdiHandler.UpdatePDI)
+	pdiGroup.Get("/:id/transitions", pdiHandler.ListTransitions)
+	pdiGroup.Post("/:id/transitions", pdiHandler.Transition)
 } 
\ No newline at end of file
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"meu-pdi-estrategico/backend/internal/handlers"
+	"meu-pdi-estrategico/backend/internal/middleware"
+	"meu-pdi-estrategico/backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupPersonaRoutes(app *fiber.App, handler *handlers.PersonaHandler, userService *services.UserService) {
+	app.Get("/api/personas", middleware.AuthMiddleware(), handler.ListPersonas)
+
+	adminGroup := app.Group("/api/admin/personas", middleware.AuthMiddleware(), middleware.AdminMiddleware(userService.IsAdmin))
+	adminGroup.Get("", handler.ListVersions)
+	adminGroup.Post("", handler.CreateVersion)
+	adminGroup.Patch("/:slug/versions/:version", handler.UpdateWeight)
+	adminGroup.Post("/:slug/versions/:version/activate", handler.ActivateVersion)
+}
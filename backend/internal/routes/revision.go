@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"meu-pdi-estrategico/backend/internal/handlers"
+	"meu-pdi-estrategico/backend/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupRevisionRoutes(app *fiber.App, handler *handlers.RevisionHandler) {
+	pdiGroup := app.Group("/api/pdis", middleware.AuthMiddleware())
+
+	pdiGroup.Get("/:id/revisions", handler.ListRevisions)
+	// Registrada antes de :number, que também casaria com "diff"
+	pdiGroup.Get("/:id/revisions/diff", handler.DiffRevisions)
+	pdiGroup.Get("/:id/revisions/:number", handler.GetRevision)
+	pdiGroup.Post("/:id/revisions/:number/restore", handler.RestoreRevision)
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"meu-pdi-estrategico/backend/internal/handlers"
+	"meu-pdi-estrategico/backend/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupSearchRoutes(app *fiber.App, handler *handlers.SearchHandler) {
+	app.Get("/api/search", middleware.AuthMiddleware(), handler.Search)
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"meu-pdi-estrategico/backend/internal/handlers"
+	"meu-pdi-estrategico/backend/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupUsageRoutes(app *fiber.App, handler *handlers.UsageHandler) {
+	meGroup := app.Group("/api/me", middleware.AuthMiddleware())
+	meGroup.Get("/usage", handler.GetUserUsage)
+	meGroup.Get("/quota", handler.GetUserQuota)
+
+	pdiGroup := app.Group("/api/pdis", middleware.AuthMiddleware())
+	pdiGroup.Get("/:id/usage", handler.GetPDIUsage)
+}
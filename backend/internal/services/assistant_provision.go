@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	// assistantManagedBy marca, nos metadados, os assistentes criados pela aplicação.
+	assistantManagedBy = "meu-pdi-estrategico"
+
+	ProvisionCreated   = "created"
+	ProvisionUpdated   = "updated"
+	ProvisionUnchanged = "unchanged"
+)
+
+//go:embed assistant/assistant.json
+var assistantConfig []byte
+
+//go:embed assistant/instructions.md
+var assistantInstructions string
+
+// defaultCoachInstructions são as instruções versionadas do assistente,
+// usadas também pelos provedores sem assistente remoto.
+var defaultCoachInstructions = strings.TrimSpace(assistantInstructions)
+
+// AssistantSpec é a definição do assistente mantida no repositório.
+type AssistantSpec struct {
+	Name         string           `json:"name"`
+	Description  string           `json:"description"`
+	Model        string           `json:"model"`
+	Temperature  *float32         `json:"temperature,omitempty"`
+	Instructions string           `json:"instructions"`
+	Tools        []ToolDefinition `json:"tools"`
+}
+
+// DefaultAssistantSpec monta a definição a partir de assistant/assistant.json,
+// assistant/instructions.md e das ferramentas registradas.
+func DefaultAssistantSpec() (AssistantSpec, error) {
+	var spec AssistantSpec
+	if err := json.Unmarshal(assistantConfig, &spec); err != nil {
+		return spec, fmt.Errorf("definição do assistente inválida: %v", err)
+	}
+	spec.Instructions = defaultCoachInstructions
+	spec.Tools = NewDefaultToolRegistry(nil).Definitions()
+	return spec, nil
+}
+
+// Hash identifica o conteúdo da definição; fica nos metadados do assistente
+// para evitar atualizações desnecessárias.
+func (s AssistantSpec) Hash() string {
+	data, _ := json.Marshal(s)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s AssistantSpec) request() openai.AssistantRequest {
+	return openai.AssistantRequest{
+		Model:        s.Model,
+		Name:         &s.Name,
+		Description:  &s.Description,
+		Instructions: &s.Instructions,
+		Temperature:  s.Temperature,
+		Tools:        assistantTools(s.Tools),
+		Metadata: map[string]any{
+			"managed_by": assistantManagedBy,
+			"spec_hash":  s.Hash(),
+		},
+	}
+}
+
+func assistantTools(definitions []ToolDefinition) []openai.AssistantTool {
+	tools := make([]openai.AssistantTool, 0, len(definitions))
+	for _, tool := range openAITools(definitions) {
+		tools = append(tools, openai.AssistantTool{
+			Type:     openai.AssistantToolTypeFunction,
+			Function: tool.Function,
+		})
+	}
+	return tools
+}
+
+// ProvisionResult informa o assistente provisionado e o que foi feito nele.
+type ProvisionResult struct {
+	AssistantID string
+	Action      string
+}
+
+// ProvisionAssistant cria ou atualiza o assistente para que ele corresponda à
+// definição. Com assistantID, atualiza esse assistente; sem ele, procura o
+// assistente gerenciado pela aplicação com o mesmo nome e só cria um novo se
+// não encontrar. Rodar de novo sem mudanças na definição não altera nada.
+func ProvisionAssistant(ctx context.Context, client *openai.Client, spec AssistantSpec, assistantID string) (*ProvisionResult, error) {
+	var current *openai.Assistant
+	if assistantID != "" {
+		assistant, err := client.RetrieveAssistant(ctx, assistantID)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao buscar assistente %s: %v", assistantID, err)
+		}
+		current = &assistant
+	} else {
+		found, err := findManagedAssistant(ctx, client, spec.Name)
+		if err != nil {
+			return nil, err
+		}
+		current = found
+	}
+
+	if current == nil {
+		created, err := client.CreateAssistant(ctx, spec.request())
+		if err != nil {
+			return nil, fmt.Errorf("erro ao criar assistente: %v", err)
+		}
+		log.Printf("[OpenAI] Assistente %s criado", created.ID)
+		return &ProvisionResult{AssistantID: created.ID, Action: ProvisionCreated}, nil
+	}
+
+	if hash, _ := current.Metadata["spec_hash"].(string); hash == spec.Hash() {
+		log.Printf("[OpenAI] Assistente %s já está atualizado", current.ID)
+		return &ProvisionResult{AssistantID: current.ID, Action: ProvisionUnchanged}, nil
+	}
+
+	if _, err := client.ModifyAssistant(ctx, current.ID, spec.request()); err != nil {
+		return nil, fmt.Errorf("erro ao atualizar assistente %s: %v", current.ID, err)
+	}
+	log.Printf("[OpenAI] Assistente %s atualizado", current.ID)
+	return &ProvisionResult{AssistantID: current.ID, Action: ProvisionUpdated}, nil
+}
+
+// findManagedAssistant percorre a lista de assistentes da conta em busca do
+// criado pela aplicação com o nome informado.
+func findManagedAssistant(ctx context.Context, client *openai.Client, name string) (*openai.Assistant, error) {
+	limit := 100
+	var after *string
+	for {
+		list, err := client.ListAssistants(ctx, &limit, nil, after, nil)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao listar assistentes: %v", err)
+		}
+		for i := range list.Assistants {
+			assistant := &list.Assistants[i]
+			managedBy, _ := assistant.Metadata["managed_by"].(string)
+			if managedBy == assistantManagedBy && assistant.Name != nil && *assistant.Name == name {
+				return assistant, nil
+			}
+		}
+		if !list.HasMore || list.LastID == nil {
+			return nil, nil
+		}
+		after = list.LastID
+	}
+}
+
+// ProvisionAssistantFromEnv provisiona o assistente com a chave de
+// OPENAI_API_KEY, atualizando OPENAI_ASSISTANT_ID quando informado.
+func ProvisionAssistantFromEnv() (*ProvisionResult, error) {
+	client, err := newOpenAIClientFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	spec, err := DefaultAssistantSpec()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return ProvisionAssistant(ctx, client, spec, os.Getenv("OPENAI_ASSISTANT_ID"))
+}
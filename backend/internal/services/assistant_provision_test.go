@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// fakeAssistantStore simula os endpoints de assistentes da OpenAI.
+type fakeAssistantStore struct {
+	mu         sync.Mutex
+	assistants []openai.Assistant
+	creates    int
+	updates    int
+}
+
+func (f *fakeAssistantStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+	switch {
+	case r.Method == http.MethodGet && path == "/assistants":
+		json.NewEncoder(w).Encode(openai.AssistantsList{Assistants: f.assistants})
+	case r.Method == http.MethodPost && path == "/assistants":
+		f.creates++
+		assistant := f.decode(r)
+		assistant.ID = fmt.Sprintf("asst-%d", f.creates)
+		f.assistants = append(f.assistants, assistant)
+		json.NewEncoder(w).Encode(assistant)
+	case strings.HasPrefix(path, "/assistants/"):
+		id := strings.TrimPrefix(path, "/assistants/")
+		for i := range f.assistants {
+			if f.assistants[i].ID != id {
+				continue
+			}
+			if r.Method == http.MethodPost {
+				f.updates++
+				assistant := f.decode(r)
+				assistant.ID = id
+				f.assistants[i] = assistant
+			}
+			json.NewEncoder(w).Encode(f.assistants[i])
+			return
+		}
+		http.NotFound(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeAssistantStore) decode(r *http.Request) openai.Assistant {
+	var assistant openai.Assistant
+	json.NewDecoder(r.Body).Decode(&assistant)
+	return assistant
+}
+
+func (f *fakeAssistantStore) calls() (creates, updates int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.creates, f.updates
+}
+
+func TestProvisionAssistant(t *testing.T) {
+	store := &fakeAssistantStore{}
+	server := httptest.NewServer(store)
+	t.Cleanup(server.Close)
+
+	config := openai.DefaultConfig("test")
+	config.BaseURL = server.URL + "/v1"
+	client := openai.NewClientWithConfig(config)
+
+	spec, err := DefaultAssistantSpec()
+	if err != nil {
+		t.Fatalf("DefaultAssistantSpec() error = %v", err)
+	}
+	if spec.Instructions == "" || len(spec.Tools) == 0 || spec.Tools[0].Name != "save_pdi" {
+		t.Fatalf("Definição incompleta: %+v", spec)
+	}
+
+	ctx := context.Background()
+	result, err := ProvisionAssistant(ctx, client, spec, "")
+	if err != nil || result.Action != ProvisionCreated {
+		t.Fatalf("ProvisionAssistant() = %+v, %v", result, err)
+	}
+
+	// Sem mudanças, o assistente existente é reaproveitado
+	again, err := ProvisionAssistant(ctx, client, spec, "")
+	if err != nil || again.Action != ProvisionUnchanged || again.AssistantID != result.AssistantID {
+		t.Fatalf("ProvisionAssistant() = %+v, %v", again, err)
+	}
+
+	spec.Instructions += "\nSeja breve."
+	updated, err := ProvisionAssistant(ctx, client, spec, result.AssistantID)
+	if err != nil || updated.Action != ProvisionUpdated || updated.AssistantID != result.AssistantID {
+		t.Fatalf("ProvisionAssistant() = %+v, %v", updated, err)
+	}
+
+	if creates, updates := store.calls(); creates != 1 || updates != 1 {
+		t.Errorf("creates = %d, updates = %d", creates, updates)
+	}
+	if _, err := ProvisionAssistant(ctx, client, spec, "asst-inexistente"); err == nil {
+		t.Error("ProvisionAssistant() deve falhar com assistente inexistente")
+	}
+}
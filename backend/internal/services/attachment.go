@@ -0,0 +1,278 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"meu-pdi-estrategico/backend/internal/models"
+
+	"github.com/ledongthuc/pdf"
+)
+
+var (
+	ErrUnsupportedAttachment = errors.New("tipo de anexo não suportado")
+	ErrAttachmentTooLarge    = errors.New("anexo maior que o permitido")
+	ErrTooManyAttachments    = errors.New("anexos demais na mensagem")
+	ErrAttachmentUnreadable  = errors.New("não foi possível extrair o texto do anexo")
+)
+
+const (
+	mimePDF      = "application/pdf"
+	mimeDOCX     = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	mimeMarkdown = "text/markdown"
+	mimeText     = "text/plain"
+)
+
+// attachmentTypes associa as extensões aceitas ao tipo de conteúdo.
+var attachmentTypes = map[string]string{
+	".pdf":      mimePDF,
+	".docx":     mimeDOCX,
+	".md":       mimeMarkdown,
+	".markdown": mimeMarkdown,
+	".txt":      mimeText,
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// maxDOCXDocumentBytes limita o word/document.xml descompactado, que pode ser
+// muito maior que o arquivo enviado.
+const maxDOCXDocumentBytes = 32 << 20
+
+// AttachmentConfig limita os anexos de uma mensagem: tamanho de cada arquivo,
+// quantidade por mensagem e caracteres de texto guardados por arquivo.
+type AttachmentConfig struct {
+	MaxBytes int64
+	MaxFiles int
+	MaxChars int
+}
+
+// AttachmentConfigFromEnv lê ATTACHMENT_MAX_BYTES, ATTACHMENT_MAX_FILES e
+// ATTACHMENT_MAX_CHARS.
+func AttachmentConfigFromEnv() AttachmentConfig {
+	return AttachmentConfig{
+		MaxBytes: int64(envInt("ATTACHMENT_MAX_BYTES", 10<<20)),
+		MaxFiles: envInt("ATTACHMENT_MAX_FILES", 3),
+		MaxChars: envInt("ATTACHMENT_MAX_CHARS", 30000),
+	}
+}
+
+// BodyLimit é o tamanho de requisição que comporta todos os anexos permitidos.
+func (c AttachmentConfig) BodyLimit() int {
+	return int(c.MaxBytes)*c.MaxFiles + 1<<20
+}
+
+// AttachmentExtractor valida os arquivos enviados e extrai o texto que segue
+// para o assistente.
+type AttachmentExtractor struct {
+	config AttachmentConfig
+}
+
+func NewAttachmentExtractor(config AttachmentConfig) *AttachmentExtractor {
+	return &AttachmentExtractor{config: config}
+}
+
+func (e *AttachmentExtractor) Config() AttachmentConfig {
+	return e.config
+}
+
+// CheckCount confere a quantidade de anexos de uma mensagem.
+func (e *AttachmentExtractor) CheckCount(count int) error {
+	if e.config.MaxFiles > 0 && count > e.config.MaxFiles {
+		return fmt.Errorf("%w: envie no máximo %d anexos por mensagem", ErrTooManyAttachments, e.config.MaxFiles)
+	}
+	return nil
+}
+
+// Extract lê o arquivo e devolve o anexo com o texto extraído, ainda sem
+// mensagem associada. O tipo vem da extensão ou, sem ela, do content type.
+func (e *AttachmentExtractor) Extract(fileName, contentType string, r io.Reader) (*models.Attachment, error) {
+	kind := attachmentTypes[strings.ToLower(filepath.Ext(fileName))]
+	if kind == "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+			for _, known := range attachmentTypes {
+				if mediaType == known {
+					kind = known
+				}
+			}
+		}
+	}
+	if kind == "" {
+		return nil, fmt.Errorf("%w: %s (use PDF, DOCX, Markdown ou texto)", ErrUnsupportedAttachment, fileName)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, e.config.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler anexo: %v", err)
+	}
+	if int64(len(data)) > e.config.MaxBytes {
+		return nil, fmt.Errorf("%w: %s tem mais de %d bytes", ErrAttachmentTooLarge, fileName, e.config.MaxBytes)
+	}
+
+	var text string
+	switch kind {
+	case mimePDF:
+		text, err = pdfText(data)
+	case mimeDOCX:
+		text, err = docxText(data, maxDOCXDocumentBytes)
+	default:
+		text = plainText(data)
+	}
+	if errors.Is(err, ErrAttachmentTooLarge) {
+		return nil, fmt.Errorf("%w (%s)", err, fileName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrAttachmentUnreadable, fileName, err)
+	}
+
+	text = normalizeText(text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: %s não tem texto", ErrAttachmentUnreadable, fileName)
+	}
+
+	attachment := &models.Attachment{
+		FileName:    filepath.Base(fileName),
+		ContentType: kind,
+		Size:        int64(len(data)),
+		Text:        text,
+	}
+	sum := sha256.Sum256(data)
+	attachment.SHA256 = hex.EncodeToString(sum[:])
+	if e.config.MaxChars > 0 && utf8.RuneCountInString(text) > e.config.MaxChars {
+		attachment.Text = string([]rune(text)[:e.config.MaxChars])
+		attachment.Truncated = true
+	}
+	return attachment, nil
+}
+
+func plainText(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	return strings.ToValidUTF8(string(data), "")
+}
+
+// pdfText extrai o texto das páginas. A biblioteca entra em pânico com alguns
+// arquivos malformados, tratados como ilegíveis.
+func pdfText(data []byte) (text string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("PDF inválido")
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	plain, err := reader.GetPlainText()
+	if err != nil {
+		return "", err
+	}
+	content, err := io.ReadAll(plain)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToValidUTF8(string(content), ""), nil
+}
+
+// docxText lê o texto de word/document.xml, com uma quebra de linha por
+// parágrafo. Documentos com mais de maxXML bytes descompactados são recusados
+// antes da leitura.
+func docxText(data []byte, maxXML int64) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+
+	var document *zip.File
+	for _, file := range archive.File {
+		if file.Name == "word/document.xml" {
+			document = file
+			break
+		}
+	}
+	if document == nil {
+		return "", errors.New("documento sem word/document.xml")
+	}
+	if document.UncompressedSize64 > uint64(maxXML) {
+		return "", fmt.Errorf("%w: word/document.xml tem mais de %d bytes descompactado", ErrAttachmentTooLarge, maxXML)
+	}
+
+	content, err := document.Open()
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+
+	// O tamanho declarado no zip pode mentir; a leitura para no limite
+	var out strings.Builder
+	decoder := xml.NewDecoder(io.LimitReader(content, maxXML))
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				out.WriteString("\t")
+			case "br", "cr":
+				out.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				out.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				out.Write(t)
+			}
+		}
+	}
+	return out.String(), nil
+}
+
+func normalizeText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\x00", "")
+	return strings.TrimSpace(blankLines.ReplaceAllString(text, "\n\n"))
+}
+
+// withAttachments acrescenta ao conteúdo da mensagem o texto dos anexos, que é
+// como eles chegam ao assistente.
+func withAttachments(content string, attachments []models.Attachment) string {
+	if len(attachments) == 0 {
+		return content
+	}
+
+	var out strings.Builder
+	out.WriteString(content)
+	for _, attachment := range attachments {
+		out.WriteString("\n\n[Anexo: " + attachment.FileName + "]\n")
+		out.WriteString(attachment.Text)
+		if attachment.Truncated {
+			out.WriteString("\n(texto truncado)")
+		}
+		out.WriteString("\n[Fim do anexo]")
+	}
+	return out.String()
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// minimalPDF monta um PDF de uma página com o texto informado.
+func minimalPDF(text string) []byte {
+	stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// minimalDOCX monta um DOCX com um parágrafo por item.
+func minimalDOCX(t *testing.T, paragraphs ...string) []byte {
+	var body strings.Builder
+	for _, paragraph := range paragraphs {
+		body.WriteString("<w:p><w:r><w:t>" + paragraph + "</w:t></w:r></w:p>")
+	}
+
+	var out bytes.Buffer
+	archive := zip.NewWriter(&out)
+	file, err := archive.Create("word/document.xml")
+	if err != nil {
+		t.Fatalf("Erro ao montar DOCX: %v", err)
+	}
+	fmt.Fprintf(file, `<?xml version="1.0" encoding="UTF-8"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>%s</w:body></w:document>`, body.String())
+	archive.Close()
+	return out.Bytes()
+}
+
+func TestAttachmentExtractor_Extract(t *testing.T) {
+	extractor := NewAttachmentExtractor(AttachmentConfig{MaxBytes: 1 << 20, MaxFiles: 3, MaxChars: 1000})
+
+	tests := []struct {
+		name     string
+		fileName string
+		data     []byte
+		kind     string
+		contains string
+	}{
+		{"texto", "notas.txt", []byte("\xef\xbb\xbfMetas do trimestre\r\n\r\n\r\n\r\nEntregar o projeto"), mimeText, "Metas do trimestre\n\nEntregar o projeto"},
+		{"markdown", "vaga.md", []byte("# Tech Lead\n\n- Liderar o time"), mimeMarkdown, "# Tech Lead"},
+		{"docx", "avaliacao.docx", minimalDOCX(t, "Pontos fortes", "Comunicação clara"), mimeDOCX, "Pontos fortes\nComunicação clara"},
+		{"pdf", "cv.pdf", minimalPDF("Engenheira de software"), mimePDF, "Engenheira de software"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attachment, err := extractor.Extract(tt.fileName, "", bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if attachment.ContentType != tt.kind || attachment.Size != int64(len(tt.data)) || len(attachment.SHA256) != 64 {
+				t.Errorf("Extract() = %+v", attachment)
+			}
+			if !strings.Contains(attachment.Text, tt.contains) {
+				t.Errorf("Texto extraído = %q, esperado conter %q", attachment.Text, tt.contains)
+			}
+		})
+	}
+}
+
+func TestAttachmentExtractor_Limits(t *testing.T) {
+	extractor := NewAttachmentExtractor(AttachmentConfig{MaxBytes: 100, MaxFiles: 1, MaxChars: 10})
+
+	if _, err := extractor.Extract("foto.png", "image/png", strings.NewReader("png")); !errors.Is(err, ErrUnsupportedAttachment) {
+		t.Errorf("Extract() error = %v, esperado ErrUnsupportedAttachment", err)
+	}
+	if _, err := extractor.Extract("grande.txt", "", strings.NewReader(strings.Repeat("a", 101))); !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Errorf("Extract() error = %v, esperado ErrAttachmentTooLarge", err)
+	}
+	if _, err := extractor.Extract("vazio.md", "", strings.NewReader(" \n ")); !errors.Is(err, ErrAttachmentUnreadable) {
+		t.Errorf("Extract() error = %v, esperado ErrAttachmentUnreadable", err)
+	}
+	if _, err := extractor.Extract("quebrado.pdf", "", strings.NewReader("%PDF-1.4 quebrado")); !errors.Is(err, ErrAttachmentUnreadable) {
+		t.Errorf("Extract() error = %v, esperado ErrAttachmentUnreadable", err)
+	}
+
+	if err := extractor.CheckCount(2); !errors.Is(err, ErrTooManyAttachments) {
+		t.Errorf("CheckCount(2) error = %v, esperado ErrTooManyAttachments", err)
+	}
+
+	// O XML do DOCX é limitado descompactado, não pelo tamanho do arquivo
+	docx := minimalDOCX(t, strings.Repeat("a", 4096))
+	if _, err := docxText(docx, 1024); !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Errorf("docxText() acima do limite error = %v, esperado ErrAttachmentTooLarge", err)
+	}
+	if text, err := docxText(docx, maxDOCXDocumentBytes); err != nil || len(text) < 4096 {
+		t.Errorf("docxText() = %d caracteres, %v", len(text), err)
+	}
+
+	// Sem extensão conhecida, o tipo vem do content type
+	attachment, err := extractor.Extract("descricao", "text/plain; charset=utf-8", strings.NewReader("Descrição da vaga de liderança"))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if !attachment.Truncated || attachment.Text != "Descrição " {
+		t.Errorf("Texto truncado = %q (%v)", attachment.Text, attachment.Truncated)
+	}
+}
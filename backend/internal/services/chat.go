@@ -1,12 +1,36 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"time"
+
 	"meu-pdi-estrategico/backend/internal/models"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrMessageNotFound    = errors.New("mensagem não encontrada")
+	ErrBranchNotFound     = errors.New("ramo da conversa não encontrado")
+	ErrMessageNotEditable = errors.New("apenas mensagens do usuário no ramo ativo podem ser editadas")
+	ErrInvalidCursor      = errors.New("cursor inválido: informe before ou after com o ID de uma mensagem da conversa")
+	ErrInvalidPageSize    = errors.New("limit deve estar entre 1 e 200")
+)
+
+// Branch resume um ramo da conversa. ParentMessageID é a mensagem a partir da
+// qual o ramo continua; nos ramos que começam do zero ele é vazio.
+type Branch struct {
+	ID              uuid.UUID  `json:"id"`
+	ParentMessageID *uuid.UUID `json:"parent_message_id,omitempty"`
+	FirstMessage    string     `json:"first_message"`
+	Messages        int        `json:"messages"`
+	Active          bool       `json:"active"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
 type ChatService struct {
 	db *gorm.DB
 }
@@ -17,7 +41,16 @@ func NewChatService(db *gorm.DB) *ChatService {
 	}
 }
 
+// CreateMessage salva a mensagem. Sem ramo informado, ela entra no ramo ativo
+// do PDI.
 func (s *ChatService) CreateMessage(message *models.Message) (*models.Message, error) {
+	if message.BranchID == uuid.Nil {
+		var pdi models.PDI
+		if err := s.db.Select("id", "active_branch_id").Where("id = ?", message.PDIID).Take(&pdi).Error; err == nil {
+			message.BranchID = pdi.BranchID()
+		}
+	}
+
 	if err := s.db.Create(message).Error; err != nil {
 		return nil, fmt.Errorf("erro ao criar mensagem: %v", err)
 	}
@@ -36,6 +69,327 @@ func (s *ChatService) GetMessagesByPDIID(pdiID string) ([]*models.Message, error
 	return messages, nil
 }
 
+// GetBranchMessages devolve a conversa como vista do ramo: as mensagens dos
+// ramos de origem até o ponto em que ele saiu deles, seguidas das suas, em
+// ordem de criação.
+func (s *ChatService) GetBranchMessages(pdiID string, branchID uuid.UUID) ([]*models.Message, error) {
+	scope, err := s.branchScope(pdiID, branchID)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*models.Message
+	if err := scope.Preload("Attachments").
+		Order("created_at ASC, id ASC").
+		Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("erro ao buscar mensagens: %v", err)
+	}
+	return messages, nil
+}
+
+// MessagePageRequest seleciona uma página da conversa. Before e After são IDs
+// de mensagens do ramo: a página traz as mensagens anteriores ou posteriores
+// a elas. Sem cursor, traz as mensagens mais recentes.
+type MessagePageRequest struct {
+	Before string
+	After  string
+	Limit  int
+}
+
+// MessagePage é uma página da conversa, em ordem de criação. HasBefore e
+// HasAfter indicam se há mensagens antes da primeira e depois da última.
+type MessagePage struct {
+	Messages  []*models.Message
+	Total     int64
+	HasBefore bool
+	HasAfter  bool
+}
+
+const (
+	DefaultMessagePageSize = 50
+	MaxMessagePageSize     = 200
+)
+
+// GetBranchMessagesPage devolve uma página da conversa do ramo, ordenada por
+// created_at e id, o que mantém o cursor estável mesmo com horários repetidos.
+func (s *ChatService) GetBranchMessagesPage(pdiID string, branchID uuid.UUID, req MessagePageRequest) (*MessagePage, error) {
+	if req.Before != "" && req.After != "" {
+		return nil, ErrInvalidCursor
+	}
+	if req.Limit < 0 || req.Limit > MaxMessagePageSize {
+		return nil, ErrInvalidPageSize
+	}
+	if req.Limit == 0 {
+		req.Limit = DefaultMessagePageSize
+	}
+
+	scope, err := s.branchScope(pdiID, branchID)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &MessagePage{}
+	if err := scope.Session(&gorm.Session{}).Model(&models.Message{}).Count(&page.Total).Error; err != nil {
+		return nil, fmt.Errorf("erro ao contar mensagens: %v", err)
+	}
+
+	query := scope.Session(&gorm.Session{}).Preload("Attachments")
+	cursorID := req.Before
+	if cursorID == "" {
+		cursorID = req.After
+	}
+	if cursorID != "" {
+		// Um cursor que não é UUID faria o Postgres recusar a consulta
+		id, err := uuid.Parse(cursorID)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		var cursor models.Message
+		if err := scope.Session(&gorm.Session{}).Where("id = ?", id).Take(&cursor).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrInvalidCursor
+			}
+			return nil, fmt.Errorf("erro ao buscar mensagens: %v", err)
+		}
+		if req.Before != "" {
+			query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+		} else {
+			query = query.Where("created_at > ? OR (created_at = ? AND id > ?)", cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+		}
+	}
+
+	// Uma mensagem a mais indica se há outras na direção da página
+	var messages []*models.Message
+	if req.After != "" {
+		query = query.Order("created_at ASC, id ASC")
+	} else {
+		query = query.Order("created_at DESC, id DESC")
+	}
+	if err := query.Limit(req.Limit + 1).Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("erro ao buscar mensagens: %v", err)
+	}
+	more := len(messages) > req.Limit
+	if more {
+		messages = messages[:req.Limit]
+	}
+
+	if req.After != "" {
+		page.HasBefore = true
+		page.HasAfter = more
+	} else {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+		page.HasBefore = more
+		page.HasAfter = req.Before != ""
+	}
+	page.Messages = messages
+	return page, nil
+}
+
+// branchScope filtra as mensagens que compõem a conversa do ramo: as suas e,
+// em cada ramo de origem, as criadas até a mensagem em que o ramo saiu dele.
+func (s *ChatService) branchScope(pdiID string, branchID uuid.UUID) (*gorm.DB, error) {
+	conditions := s.db.Where("branch_id = ?", branchID)
+
+	seen := map[uuid.UUID]bool{branchID: true}
+	current := branchID
+	for {
+		// A primeira mensagem do ramo aponta para a mensagem em que ele começou
+		var first models.Message
+		err := s.db.Select("id", "parent_id").
+			Where("pdi_id = ? AND branch_id = ?", pdiID, current).
+			Order("created_at ASC, id ASC").
+			Take(&first).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && first.ParentID == nil) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao buscar mensagens: %v", err)
+		}
+
+		var fork models.Message
+		if err := s.db.Where("id = ? AND pdi_id = ?", first.ParentID, pdiID).Take(&fork).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				break
+			}
+			return nil, fmt.Errorf("erro ao buscar mensagens: %v", err)
+		}
+		if seen[fork.BranchID] {
+			break
+		}
+		seen[fork.BranchID] = true
+
+		conditions = conditions.Or("branch_id = ? AND (created_at < ? OR (created_at = ? AND id <= ?))",
+			fork.BranchID, fork.CreatedAt, fork.CreatedAt, fork.ID)
+		current = fork.BranchID
+	}
+
+	return s.db.Model(&models.Message{}).Where("pdi_id = ?", pdiID).Where(conditions), nil
+}
+
+// GetAttachments devolve os anexos da mensagem, na ordem em que foram enviados.
+func (s *ChatService) GetAttachments(messageID uuid.UUID) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	if err := s.db.Where("message_id = ?", messageID).
+		Order("created_at ASC").
+		Find(&attachments).Error; err != nil {
+		return nil, fmt.Errorf("erro ao buscar anexos: %v", err)
+	}
+	return attachments, nil
+}
+
+// ListBranches devolve os ramos da conversa do PDI, do mais antigo ao mais
+// recente.
+func (s *ChatService) ListBranches(pdi *models.PDI) ([]Branch, error) {
+	var messages []*models.Message
+	if err := s.db.Where("pdi_id = ?", pdi.ID).
+		Order("created_at ASC").
+		Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("erro ao buscar mensagens: %v", err)
+	}
+
+	active := pdi.BranchID()
+	branches := []Branch{}
+	index := make(map[uuid.UUID]int)
+	for _, msg := range messages {
+		i, ok := index[msg.BranchID]
+		if !ok {
+			i = len(branches)
+			index[msg.BranchID] = i
+			branches = append(branches, Branch{
+				ID:              msg.BranchID,
+				ParentMessageID: msg.ParentID,
+				FirstMessage:    msg.Content,
+				Active:          msg.BranchID == active,
+				CreatedAt:       msg.CreatedAt,
+			})
+		}
+		branches[i].Messages++
+		branches[i].UpdatedAt = msg.CreatedAt
+	}
+	return branches, nil
+}
+
+// EditMessage cria um ramo novo em que a mensagem do usuário é substituída
+// pelo conteúdo editado. O ramo continua a partir da mensagem anterior à
+// editada e passa a ser o ramo ativo; as mensagens seguintes ficam no ramo
+// antigo. Devolve a nova mensagem, pendente de processamento.
+func (s *ChatService) EditMessage(pdi *models.PDI, messageID, content string) (*models.Message, error) {
+	messages, err := s.GetBranchMessages(pdi.ID, pdi.BranchID())
+	if err != nil {
+		return nil, err
+	}
+
+	position := -1
+	for i, msg := range messages {
+		if msg.ID.String() == messageID {
+			position = i
+		}
+		if msg.Role == "user" && msg.Status == models.MessageStatusPending {
+			return nil, ErrConversationBusy
+		}
+	}
+	if position < 0 {
+		if _, err := s.GetMessageByID(pdi.ID, messageID); err != nil {
+			return nil, err
+		}
+		return nil, ErrMessageNotEditable
+	}
+	if messages[position].Role != "user" {
+		return nil, ErrMessageNotEditable
+	}
+
+	edited := &models.Message{
+		PDIID:    pdi.ID,
+		Content:  content,
+		Role:     "user",
+		Status:   models.MessageStatusPending,
+		BranchID: uuid.New(),
+	}
+	// Os anexos da mensagem original continuam valendo na editada
+	for _, attachment := range messages[position].Attachments {
+		attachment.ID = uuid.Nil
+		attachment.MessageID = uuid.Nil
+		edited.Attachments = append(edited.Attachments, attachment)
+	}
+	if position > 0 {
+		edited.ParentID = &messages[position-1].ID
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(edited).Error; err != nil {
+			return err
+		}
+		return activateBranch(tx, pdi, edited.BranchID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao editar mensagem: %v", err)
+	}
+
+	return edited, nil
+}
+
+// SwitchBranch torna ativo outro ramo da conversa do PDI.
+func (s *ChatService) SwitchBranch(pdi *models.PDI, branchID string) error {
+	id, err := uuid.Parse(branchID)
+	if err != nil {
+		return ErrBranchNotFound
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Message{}).
+		Where("pdi_id = ? AND branch_id = ?", pdi.ID, id).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("erro ao buscar ramo: %v", err)
+	}
+	if count == 0 {
+		return ErrBranchNotFound
+	}
+	if id == pdi.BranchID() {
+		return nil
+	}
+
+	if err := s.db.Model(&models.Message{}).
+		Where("pdi_id = ? AND branch_id = ? AND role = ? AND status = ?", pdi.ID, pdi.BranchID(), "user", models.MessageStatusPending).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("erro ao buscar mensagens: %v", err)
+	}
+	if count > 0 {
+		return ErrConversationBusy
+	}
+
+	if err := activateBranch(s.db, pdi, id); err != nil {
+		return fmt.Errorf("erro ao trocar ramo: %v", err)
+	}
+	return nil
+}
+
+// activateBranch troca o ramo ativo. O contexto do provedor deixa de valer:
+// a thread remota é recriada com as mensagens do ramo na próxima mensagem e o
+// resumo da conversa é refeito.
+func activateBranch(tx *gorm.DB, pdi *models.PDI, branchID uuid.UUID) error {
+	var active *uuid.UUID
+	if branchID != models.MainBranchID(pdi.ID) {
+		active = &branchID
+	}
+
+	if err := tx.Model(pdi).Where("id = ?", pdi.ID).Updates(map[string]interface{}{
+		"active_branch_id":         active,
+		"thread_id":                "",
+		"context_summary":          "",
+		"context_summarized_until": nil,
+	}).Error; err != nil {
+		return err
+	}
+
+	pdi.ActiveBranchID = active
+	pdi.ThreadID = ""
+	pdi.ContextSummary = ""
+	pdi.ContextSummarizedUntil = nil
+	return nil
+}
+
 func (s *ChatService) UpdateMessageStatus(messageID string, status models.MessageStatus) error {
 	if err := s.db.Model(&models.Message{}).
 		Where("id = ?", messageID).
@@ -46,3 +400,127 @@ func (s *ChatService) UpdateMessageStatus(messageID string, status models.Messag
 	return nil
 }
 
+func (s *ChatService) GetMessageByID(pdiID, messageID string) (*models.Message, error) {
+	if _, err := uuid.Parse(messageID); err != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	var message models.Message
+	if err := s.db.Preload("Attachments").Where("id = ? AND pdi_id = ?", messageID, pdiID).First(&message).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar mensagem: %v", err)
+	}
+
+	return &message, nil
+}
+
+// GetReply devolve a resposta mais recente do assistente para a mensagem informada.
+func (s *ChatService) GetReply(messageID uuid.UUID) (*models.Message, error) {
+	var reply models.Message
+	if err := s.db.Where("parent_id = ? AND role = ?", messageID, "assistant").
+		Order("created_at DESC").
+		First(&reply).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar resposta: %v", err)
+	}
+
+	return &reply, nil
+}
+
+func (s *ChatService) MarkMessageFailed(messageID uuid.UUID, reason string) error {
+	if err := s.db.Model(&models.Message{}).
+		Where("id = ?", messageID).
+		Updates(map[string]interface{}{
+			"status": models.MessageStatusFailed,
+			"error":  reason,
+		}).Error; err != nil {
+		return fmt.Errorf("erro ao atualizar status da mensagem: %v", err)
+	}
+
+	return nil
+}
+
+// FailPendingMessages marca como falhas as mensagens pendentes sem alteração
+// há mais de olderThan, por exemplo quando o servidor reiniciou durante o
+// processamento. As mais recentes podem estar com outra réplica.
+func (s *ChatService) FailPendingMessages(reason string, olderThan time.Duration) (int64, error) {
+	result := s.db.Model(&models.Message{}).
+		Where("status = ? AND updated_at < ?", models.MessageStatusPending, time.Now().Add(-olderThan)).
+		Updates(map[string]interface{}{
+			"status": models.MessageStatusFailed,
+			"error":  reason,
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("erro ao atualizar mensagens pendentes: %v", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// GetLastMessage devolve a mensagem mais recente do ramo com o papel informado.
+func (s *ChatService) GetLastMessage(pdiID string, branchID uuid.UUID, role string) (*models.Message, error) {
+	var message models.Message
+	if err := s.db.Where("pdi_id = ? AND branch_id = ? AND role = ?", pdiID, branchID, role).
+		Order("created_at DESC").
+		First(&message).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar mensagem: %v", err)
+	}
+
+	return &message, nil
+}
+
+// ResetMessage devolve a mensagem ao status pendente para uma nova tentativa.
+func (s *ChatService) ResetMessage(message *models.Message) error {
+	if err := s.db.Model(&models.Message{}).
+		Where("id = ?", message.ID).
+		Updates(map[string]interface{}{
+			"status": models.MessageStatusPending,
+			"error":  "",
+		}).Error; err != nil {
+		return fmt.Errorf("erro ao atualizar status da mensagem: %v", err)
+	}
+
+	message.Status = models.MessageStatusPending
+	message.Error = ""
+	return nil
+}
+
+// IncrementAttempts contabiliza uma tentativa de processamento da mensagem.
+func (s *ChatService) IncrementAttempts(messageID uuid.UUID) error {
+	if err := s.db.Model(&models.Message{}).
+		Where("id = ?", messageID).
+		Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+		return fmt.Errorf("erro ao registrar tentativa da mensagem: %v", err)
+	}
+
+	return nil
+}
+
+// RecordRun guarda na mensagem o run do provedor e o ID dela na thread.
+func (s *ChatService) RecordRun(message *models.Message, runID, providerMessageID string) error {
+	updates := map[string]interface{}{}
+	if runID != "" {
+		updates["run_id"] = runID
+		message.RunID = runID
+	}
+	if providerMessageID != "" {
+		updates["provider_message_id"] = providerMessageID
+		message.ProviderMessageID = providerMessageID
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	if err := s.db.Model(&models.Message{}).Where("id = ?", message.ID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("erro ao registrar run da mensagem: %v", err)
+	}
+
+	return nil
+}
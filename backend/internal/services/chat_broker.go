@@ -0,0 +1,68 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const chatBrokerBuffer = 256
+
+// ChatBroker distribui em memória os eventos de processamento de cada
+// mensagem para os clientes inscritos.
+type ChatBroker struct {
+	mu   sync.Mutex
+	subs map[uuid.UUID][]chan StreamEvent
+}
+
+func NewChatBroker() *ChatBroker {
+	return &ChatBroker{subs: make(map[uuid.UUID][]chan StreamEvent)}
+}
+
+// Subscribe inscreve o chamador nos eventos da mensagem. O canal é fechado
+// quando o processamento termina; a função devolvida cancela a inscrição.
+func (b *ChatBroker) Subscribe(messageID uuid.UUID) (<-chan StreamEvent, func()) {
+	ch := make(chan StreamEvent, chatBrokerBuffer)
+
+	b.mu.Lock()
+	b.subs[messageID] = append(b.subs[messageID], ch)
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subs := b.subs[messageID]
+		for i, sub := range subs {
+			if sub == ch {
+				b.subs[messageID] = append(subs[:i], subs[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+		if len(b.subs[messageID]) == 0 {
+			delete(b.subs, messageID)
+		}
+	}
+}
+
+// Publish envia o evento sem bloquear; inscritos lentos demais perdem eventos.
+func (b *ChatBroker) Publish(messageID uuid.UUID, event StreamEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.subs[messageID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Close encerra todas as inscrições da mensagem.
+func (b *ChatBroker) Close(messageID uuid.UUID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.subs[messageID] {
+		close(ch)
+	}
+	delete(b.subs, messageID)
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+// ExportFormat é o formato da transcrição da conversa.
+type ExportFormat string
+
+const (
+	ExportMarkdown ExportFormat = "md"
+	ExportHTML     ExportFormat = "html"
+)
+
+var ErrInvalidExportFormat = errors.New("formato inválido: use md ou html")
+
+// ContentType devolve o tipo MIME do formato.
+func (f ExportFormat) ContentType() string {
+	if f == ExportHTML {
+		return "text/html; charset=utf-8"
+	}
+	return "text/markdown; charset=utf-8"
+}
+
+// Transcript é a conversa de um PDI pronta para exportação.
+type Transcript struct {
+	PDIName    string
+	ExportedAt time.Time
+	Messages   []*models.Message
+}
+
+// NewTranscript monta a transcrição com as mensagens da conversa, deixando de
+// fora as que falharam, como na reconstrução da thread.
+func NewTranscript(pdi *models.PDI, messages []*models.Message, exportedAt time.Time) Transcript {
+	transcript := Transcript{PDIName: pdi.Name, ExportedAt: exportedAt}
+	for _, msg := range messages {
+		if msg.Status != models.MessageStatusFailed {
+			transcript.Messages = append(transcript.Messages, msg)
+		}
+	}
+	return transcript
+}
+
+// ExportTranscript escreve a transcrição no formato pedido. No HTML, todo o
+// texto vindo do usuário e do assistente é escapado.
+func ExportTranscript(w io.Writer, format ExportFormat, transcript Transcript) error {
+	switch format {
+	case ExportMarkdown:
+		return exportMarkdown(w, transcript)
+	case ExportHTML:
+		return transcriptTemplate.Execute(w, transcriptView(transcript))
+	default:
+		return ErrInvalidExportFormat
+	}
+}
+
+func roleLabel(role string) string {
+	if role == "assistant" {
+		return "Assistente"
+	}
+	return "Usuário"
+}
+
+func transcriptTime(t time.Time) string {
+	return t.UTC().Format("02/01/2006 15:04") + " UTC"
+}
+
+func exportMarkdown(w io.Writer, transcript Transcript) error {
+	var out strings.Builder
+	fmt.Fprintf(&out, "# %s\n\n", transcript.PDIName)
+	fmt.Fprintf(&out, "Conversa exportada em %s.\n", transcriptTime(transcript.ExportedAt))
+
+	for _, msg := range transcript.Messages {
+		fmt.Fprintf(&out, "\n---\n\n### %s · %s\n\n", roleLabel(msg.Role), transcriptTime(msg.CreatedAt))
+		out.WriteString(strings.TrimSpace(msg.Content))
+		out.WriteString("\n")
+		for _, attachment := range msg.Attachments {
+			fmt.Fprintf(&out, "\n> Anexo: %s\n", attachment.FileName)
+		}
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+type transcriptMessage struct {
+	Role        string
+	Label       string
+	Time        string
+	Content     string
+	Attachments []string
+}
+
+type transcriptPage struct {
+	PDIName    string
+	ExportedAt string
+	Messages   []transcriptMessage
+}
+
+func transcriptView(transcript Transcript) transcriptPage {
+	page := transcriptPage{
+		PDIName:    transcript.PDIName,
+		ExportedAt: transcriptTime(transcript.ExportedAt),
+	}
+	for _, msg := range transcript.Messages {
+		message := transcriptMessage{
+			Role:    msg.Role,
+			Label:   roleLabel(msg.Role),
+			Time:    transcriptTime(msg.CreatedAt),
+			Content: strings.TrimSpace(msg.Content),
+		}
+		for _, attachment := range msg.Attachments {
+			message.Attachments = append(message.Attachments, attachment.FileName)
+		}
+		page.Messages = append(page.Messages, message)
+	}
+	return page
+}
+
+// O html/template escapa cada valor, então o conteúdo das mensagens nunca vira
+// marcação; as quebras de linha são mantidas pelo white-space do CSS.
+var transcriptTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html lang="pt-BR">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="Content-Security-Policy" content="default-src 'none'; style-src 'unsafe-inline'">
+<title>{{.PDIName}}</title>
+<style>
+body { font-family: sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #1a1a1a; }
+.message { border-top: 1px solid #e5e5e5; padding: 1rem 0; }
+.assistant { background: #f7f7f8; padding: 1rem; }
+.meta { color: #6b6b6b; font-size: 0.875rem; margin-bottom: 0.5rem; }
+.content { white-space: pre-wrap; word-wrap: break-word; }
+.attachment { color: #6b6b6b; font-size: 0.875rem; }
+</style>
+</head>
+<body>
+<h1>{{.PDIName}}</h1>
+<p class="meta">Conversa exportada em {{.ExportedAt}}.</p>
+{{range .Messages}}<div class="message {{if eq .Role "assistant"}}assistant{{else}}user{{end}}">
+<div class="meta"><strong>{{.Label}}</strong> · {{.Time}}</div>
+<div class="content">{{.Content}}</div>
+{{range .Attachments}}<div class="attachment">Anexo: {{.}}</div>
+{{end}}</div>
+{{end}}</body>
+</html>
+`))
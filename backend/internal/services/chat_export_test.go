@@ -0,0 +1,54 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+func TestExportTranscript(t *testing.T) {
+	at := time.Date(2025, 3, 10, 14, 30, 0, 0, time.UTC)
+	pdi := &models.PDI{Name: "Rumo a <Staff>"}
+	messages := []*models.Message{
+		{Role: "user", Content: "Quero <script>alert(1)</script> crescer", Status: models.MessageStatusCompleted, CreatedAt: at,
+			Attachments: []models.Attachment{{FileName: "cv.pdf"}}},
+		{Role: "user", Content: "Mensagem com falha", Status: models.MessageStatusFailed, CreatedAt: at},
+		{Role: "assistant", Content: "**Vamos** planejar\n\n- Meta 1", Status: models.MessageStatusCompleted, CreatedAt: at.Add(time.Minute)},
+	}
+	transcript := NewTranscript(pdi, messages, at.Add(time.Hour))
+
+	var md bytes.Buffer
+	if err := ExportTranscript(&md, ExportMarkdown, transcript); err != nil {
+		t.Fatalf("ExportTranscript(md) error = %v", err)
+	}
+	for _, expected := range []string{"# Rumo a <Staff>", "### Usuário · 10/03/2025 14:30 UTC", "> Anexo: cv.pdf", "### Assistente · 10/03/2025 14:31 UTC\n\n**Vamos** planejar\n\n- Meta 1"} {
+		if !strings.Contains(md.String(), expected) {
+			t.Errorf("Markdown sem %q:\n%s", expected, md.String())
+		}
+	}
+	if strings.Contains(md.String(), "Mensagem com falha") {
+		t.Errorf("Mensagem com falha exportada:\n%s", md.String())
+	}
+
+	var page bytes.Buffer
+	if err := ExportTranscript(&page, ExportHTML, transcript); err != nil {
+		t.Fatalf("ExportTranscript(html) error = %v", err)
+	}
+	out := page.String()
+	if strings.Contains(out, "<script>") || strings.Contains(out, "<Staff>") {
+		t.Errorf("HTML sem escapar:\n%s", out)
+	}
+	for _, expected := range []string{"<title>Rumo a &lt;Staff&gt;</title>", "Quero &lt;script&gt;alert(1)&lt;/script&gt; crescer", `<div class="message assistant">`, "Anexo: cv.pdf"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("HTML sem %q:\n%s", expected, out)
+		}
+	}
+
+	if err := ExportTranscript(&page, ExportFormat("pdf"), transcript); !errors.Is(err, ErrInvalidExportFormat) {
+		t.Errorf("ExportTranscript(pdf) error = %v, esperado ErrInvalidExportFormat", err)
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+func TestChatService_EditMessage(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	chatService := NewChatService(db)
+
+	base := time.Now().Add(-time.Hour)
+	var conversation []*models.Message
+	for i, content := range []string{"Quero ser gerente", "Vamos planejar", "Em seis meses", "Prazo curto"} {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		msg := &models.Message{PDIID: pdi.ID, Role: role, Content: content, Status: models.MessageStatusCompleted, CreatedAt: base.Add(time.Duration(i) * time.Second)}
+		if _, err := chatService.CreateMessage(msg); err != nil {
+			t.Fatalf("Erro ao criar mensagem: %v", err)
+		}
+		conversation = append(conversation, msg)
+	}
+	main := models.MainBranchID(pdi.ID)
+	if conversation[0].BranchID != main {
+		t.Fatalf("Ramo da mensagem = %v, esperado o ramo principal %v", conversation[0].BranchID, main)
+	}
+
+	if _, err := chatService.EditMessage(pdi, conversation[1].ID.String(), "Outra resposta"); !errors.Is(err, ErrMessageNotEditable) {
+		t.Errorf("EditMessage() em resposta do assistente error = %v, esperado ErrMessageNotEditable", err)
+	}
+
+	db.Model(pdi).Where("id = ?", pdi.ID).Updates(map[string]interface{}{"thread_id": "thread-velha", "context_summary": "resumo"})
+	pdi.ThreadID = "thread-velha"
+
+	edited, err := chatService.EditMessage(pdi, conversation[2].ID.String(), "Em um ano")
+	if err != nil {
+		t.Fatalf("EditMessage() error = %v", err)
+	}
+	if edited.BranchID == main || edited.ParentID == nil || *edited.ParentID != conversation[1].ID {
+		t.Errorf("Mensagem editada com ramo %v e pai %v", edited.BranchID, edited.ParentID)
+	}
+
+	var stored models.PDI
+	db.First(&stored, "id = ?", pdi.ID)
+	if stored.BranchID() != edited.BranchID || stored.ThreadID != "" || stored.ContextSummary != "" {
+		t.Errorf("PDI após edição: ramo %v, thread %q, resumo %q", stored.BranchID(), stored.ThreadID, stored.ContextSummary)
+	}
+
+	messages, _ := chatService.GetBranchMessages(pdi.ID, edited.BranchID)
+	if len(messages) != 3 || messages[1].ID != conversation[1].ID || messages[2].ID != edited.ID {
+		t.Fatalf("Mensagens do ramo editado = %d", len(messages))
+	}
+
+	if err := chatService.SwitchBranch(pdi, main.String()); !errors.Is(err, ErrConversationBusy) {
+		t.Errorf("SwitchBranch() com mensagem pendente error = %v, esperado ErrConversationBusy", err)
+	}
+
+	// O contexto enviado ao provedor vem do ramo ativo
+	provider := &threadRecorder{}
+	if _, err := NewOpenAIService(db, provider, nil).ProcessMessage(context.Background(), edited, user.ID.String()); err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+	if len(provider.replayed) != 2 || provider.replayed[1].Content != "Vamos planejar" {
+		t.Errorf("Thread recriada com %+v", provider.replayed)
+	}
+	if provider.last.Input != "Em um ano" || provider.last.ThreadID != "thread-nova" || provider.last.InputMessageID != "" {
+		t.Errorf("Requisição ao provedor = %+v", provider.last)
+	}
+
+	chatService.UpdateMessageStatus(edited.ID.String(), models.MessageStatusCompleted)
+
+	branches, err := chatService.ListBranches(pdi)
+	if err != nil {
+		t.Fatalf("ListBranches() error = %v", err)
+	}
+	if len(branches) != 2 || branches[0].ID != main || branches[0].Messages != 4 || !branches[1].Active || branches[1].Messages != 2 {
+		t.Errorf("ListBranches() = %+v", branches)
+	}
+
+	if err := chatService.SwitchBranch(pdi, main.String()); err != nil {
+		t.Fatalf("SwitchBranch() error = %v", err)
+	}
+	messages, _ = chatService.GetBranchMessages(pdi.ID, pdi.BranchID())
+	if len(messages) != 4 || messages[3].Content != "Prazo curto" {
+		t.Errorf("Mensagens do ramo principal = %d", len(messages))
+	}
+
+	if err := chatService.SwitchBranch(pdi, "00000000-0000-0000-0000-000000000001"); !errors.Is(err, ErrBranchNotFound) {
+		t.Errorf("SwitchBranch() error = %v, esperado ErrBranchNotFound", err)
+	}
+}
+
+func TestChatService_GetBranchMessagesPage(t *testing.T) {
+	db, _, pdi := setupChatTestDB(t)
+	chatService := NewChatService(db)
+
+	// Mensagens com o mesmo horário são desempatadas pelo ID
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var conversation []*models.Message
+	for i := 0; i < 5; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		msg := &models.Message{PDIID: pdi.ID, Role: role, Content: "mensagem", Status: models.MessageStatusCompleted, CreatedAt: base.Add(time.Duration(i/2) * time.Second)}
+		if _, err := chatService.CreateMessage(msg); err != nil {
+			t.Fatalf("Erro ao criar mensagem: %v", err)
+		}
+		conversation = append(conversation, msg)
+	}
+	all, _ := chatService.GetBranchMessages(pdi.ID, pdi.BranchID())
+
+	latest, err := chatService.GetBranchMessagesPage(pdi.ID, pdi.BranchID(), MessagePageRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("GetBranchMessagesPage() error = %v", err)
+	}
+	if latest.Total != 5 || !latest.HasBefore || latest.HasAfter || len(latest.Messages) != 2 || latest.Messages[1].ID != all[4].ID {
+		t.Fatalf("Página mais recente = %+v", latest)
+	}
+
+	// Percorrer para trás e para frente devolve a conversa completa, sem repetir
+	var seen []*models.Message
+	page := latest
+	for {
+		seen = append(page.Messages, seen...)
+		if !page.HasBefore {
+			break
+		}
+		page, err = chatService.GetBranchMessagesPage(pdi.ID, pdi.BranchID(), MessagePageRequest{Before: page.Messages[0].ID.String(), Limit: 2})
+		if err != nil {
+			t.Fatalf("GetBranchMessagesPage() error = %v", err)
+		}
+	}
+	if len(seen) != 5 {
+		t.Fatalf("Mensagens percorridas = %d, esperado 5", len(seen))
+	}
+	for i := range all {
+		if seen[i].ID != all[i].ID {
+			t.Errorf("Mensagem %d = %v, esperado %v", i, seen[i].ID, all[i].ID)
+		}
+	}
+
+	after, _ := chatService.GetBranchMessagesPage(pdi.ID, pdi.BranchID(), MessagePageRequest{After: all[1].ID.String(), Limit: 2})
+	if !after.HasBefore || !after.HasAfter || len(after.Messages) != 2 || after.Messages[0].ID != all[2].ID {
+		t.Errorf("Página posterior = %+v", after)
+	}
+
+	// No ramo editado, as mensagens do ramo de origem entram até o ponto de saída
+	edited, err := chatService.EditMessage(pdi, conversation[4].ID.String(), "Outra pergunta")
+	if err != nil {
+		t.Fatalf("EditMessage() error = %v", err)
+	}
+	branch, _ := chatService.GetBranchMessagesPage(pdi.ID, edited.BranchID, MessagePageRequest{})
+	if branch.Total != 5 || branch.HasBefore || branch.Messages[3].ID != all[3].ID || branch.Messages[4].ID != edited.ID {
+		t.Errorf("Página do ramo editado = %+v", branch)
+	}
+
+	if _, err := chatService.GetBranchMessagesPage(pdi.ID, edited.BranchID, MessagePageRequest{Before: conversation[4].ID.String()}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Cursor fora do ramo error = %v, esperado ErrInvalidCursor", err)
+	}
+	if _, err := chatService.GetBranchMessagesPage(pdi.ID, edited.BranchID, MessagePageRequest{After: "nao-e-uuid"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Cursor que não é UUID error = %v, esperado ErrInvalidCursor", err)
+	}
+	if _, err := chatService.GetBranchMessagesPage(pdi.ID, pdi.BranchID(), MessagePageRequest{Limit: MaxMessagePageSize + 1}); !errors.Is(err, ErrInvalidPageSize) {
+		t.Errorf("Limite acima do máximo error = %v, esperado ErrInvalidPageSize", err)
+	}
+}
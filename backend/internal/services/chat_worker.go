@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"meu-pdi-estrategico/backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrChatQueueFull = errors.New("fila de processamento de mensagens cheia")
+	ErrChatCancelled = errors.New("processamento cancelado")
+	ErrChatStopped   = errors.New("o servidor está sendo encerrado")
+)
+
+// ChatJob é uma mensagem do usuário aguardando a resposta do assistente ou,
+// com RehydratePDIID, a reconstrução da thread desse PDI.
+type ChatJob struct {
+	Message        *models.Message
+	UserID         string
+	RehydratePDIID string
+}
+
+// RehydrateStatus é o andamento da reconstrução da thread de um PDI: pending
+// enquanto ela está na fila ou rodando, depois completed, com o resultado, ou
+// failed, com o erro.
+type RehydrateStatus struct {
+	Status models.MessageStatus `json:"status"`
+	Result *RehydrateResult     `json:"result,omitempty"`
+	Error  string               `json:"error,omitempty"`
+}
+
+// ChatWorkerConfig configura o pool. StaleAfter é o tempo a partir do qual
+// uma mensagem pendente é considerada abandonada; deve passar do Timeout com
+// folga para a espera na fila.
+type ChatWorkerConfig struct {
+	Workers    int
+	QueueSize  int
+	Timeout    time.Duration
+	StaleAfter time.Duration
+}
+
+// ChatWorkerConfigFromEnv lê CHAT_WORKERS, CHAT_QUEUE_SIZE, CHAT_JOB_TIMEOUT
+// e CHAT_STALE_AFTER.
+func ChatWorkerConfigFromEnv() ChatWorkerConfig {
+	return ChatWorkerConfig{
+		Workers:    envInt("CHAT_WORKERS", 4),
+		QueueSize:  envInt("CHAT_QUEUE_SIZE", 100),
+		Timeout:    envDuration("CHAT_JOB_TIMEOUT", 2*time.Minute),
+		StaleAfter: envDuration("CHAT_STALE_AFTER", 10*time.Minute),
+	}
+}
+
+// ChatWorkerPool processa as mensagens do chat em segundo plano e mantém o
+// status da mensagem do usuário: pending -> completed ou failed.
+type ChatWorkerPool struct {
+	assistant   Assistant
+	chatService *ChatService
+	broker      *ChatBroker
+	config      ChatWorkerConfig
+	jobs        chan ChatJob
+	wg          sync.WaitGroup
+
+	mu        sync.Mutex
+	stopped   bool
+	running   map[uuid.UUID]context.CancelFunc
+	cancelled map[uuid.UUID]bool
+	// rehydrations guarda, por PDI, a última reconstrução de thread pedida a
+	// este processo
+	rehydrations map[string]RehydrateStatus
+}
+
+func NewChatWorkerPool(assistant Assistant, chatService *ChatService, config ChatWorkerConfig) *ChatWorkerPool {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 1
+	}
+	return &ChatWorkerPool{
+		assistant:    assistant,
+		chatService:  chatService,
+		broker:       NewChatBroker(),
+		config:       config,
+		jobs:         make(chan ChatJob, config.QueueSize),
+		running:      make(map[uuid.UUID]context.CancelFunc),
+		cancelled:    make(map[uuid.UUID]bool),
+		rehydrations: make(map[string]RehydrateStatus),
+	}
+}
+
+func (p *ChatWorkerPool) Start() {
+	for i := 0; i < p.config.Workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for job := range p.jobs {
+				if job.RehydratePDIID != "" {
+					p.rehydrate(job)
+					continue
+				}
+				p.process(job)
+			}
+		}()
+	}
+	log.Printf("[Chat] %d workers iniciados", p.config.Workers)
+}
+
+// Shutdown para de aceitar mensagens e aguarda as que já estão na fila.
+func (p *ChatWorkerPool) Shutdown() {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return
+	}
+	p.stopped = true
+	close(p.jobs)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+func (p *ChatWorkerPool) Enqueue(job ChatJob) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return ErrChatStopped
+	}
+	delete(p.cancelled, job.Message.ID)
+
+	select {
+	case p.jobs <- job:
+		return nil
+	default:
+		return ErrChatQueueFull
+	}
+}
+
+// EnqueueRehydrate coloca na fila a reconstrução da thread do PDI. Devolve
+// ErrConversationBusy se já houver uma pendente para o mesmo PDI.
+func (p *ChatWorkerPool) EnqueueRehydrate(userID, pdiID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return ErrChatStopped
+	}
+	if p.rehydrations[pdiID].Status == models.MessageStatusPending {
+		return ErrConversationBusy
+	}
+
+	select {
+	case p.jobs <- ChatJob{UserID: userID, RehydratePDIID: pdiID}:
+		p.rehydrations[pdiID] = RehydrateStatus{Status: models.MessageStatusPending}
+		return nil
+	default:
+		return ErrChatQueueFull
+	}
+}
+
+// RehydrateStatus devolve o andamento da última reconstrução da thread do PDI
+// pedida a este processo.
+func (p *ChatWorkerPool) RehydrateStatus(pdiID string) (RehydrateStatus, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status, ok := p.rehydrations[pdiID]
+	return status, ok
+}
+
+// Cancel interrompe o processamento da mensagem, cancelando o run no
+// provedor. Se ela ainda estiver na fila, é descartada quando sair dela.
+func (p *ChatWorkerPool) Cancel(messageID uuid.UUID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cancel, ok := p.running[messageID]; ok {
+		cancel()
+		return
+	}
+	p.cancelled[messageID] = true
+}
+
+// Subscribe inscreve o chamador nos eventos de processamento da mensagem.
+func (p *ChatWorkerPool) Subscribe(messageID uuid.UUID) (<-chan StreamEvent, func()) {
+	return p.broker.Subscribe(messageID)
+}
+
+func (p *ChatWorkerPool) process(job ChatJob) {
+	messageID := job.Message.ID
+	defer p.broker.Close(messageID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout)
+	defer cancel()
+
+	if !p.start(messageID, cancel) {
+		p.fail(messageID, ErrChatCancelled)
+		return
+	}
+	defer p.finish(messageID)
+
+	if err := p.chatService.IncrementAttempts(messageID); err != nil {
+		log.Printf("[Chat] %v", err)
+	}
+
+	reply, err := p.assistant.StreamMessage(ctx, job.Message, job.UserID, func(event StreamEvent) {
+		p.broker.Publish(messageID, event)
+	})
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			err = ErrChatCancelled
+		}
+		p.fail(messageID, err)
+		return
+	}
+
+	if err := p.chatService.UpdateMessageStatus(messageID.String(), models.MessageStatusCompleted); err != nil {
+		log.Printf("[Chat] %v", err)
+	}
+	p.broker.Publish(messageID, StreamEvent{Type: StreamEventMessage, Message: reply})
+	p.broker.Publish(messageID, StreamEvent{Type: StreamEventStatus, Status: models.MessageStatusCompleted})
+}
+
+func (p *ChatWorkerPool) rehydrate(job ChatJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout)
+	defer cancel()
+
+	status := RehydrateStatus{Status: models.MessageStatusCompleted}
+	result, err := p.assistant.RehydrateThread(ctx, job.UserID, job.RehydratePDIID)
+	if err != nil {
+		log.Printf("[Chat] Erro ao recriar a thread do PDI %s: %v", job.RehydratePDIID, err)
+		status = RehydrateStatus{Status: models.MessageStatusFailed, Error: err.Error()}
+	}
+	status.Result = result
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rehydrations[job.RehydratePDIID] = status
+}
+
+// start registra a mensagem como em processamento. Devolve false se ela foi
+// cancelada enquanto aguardava na fila.
+func (p *ChatWorkerPool) start(messageID uuid.UUID, cancel context.CancelFunc) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancelled[messageID] {
+		delete(p.cancelled, messageID)
+		return false
+	}
+	p.running[messageID] = cancel
+	return true
+}
+
+func (p *ChatWorkerPool) finish(messageID uuid.UUID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.running, messageID)
+}
+
+func (p *ChatWorkerPool) fail(messageID uuid.UUID, err error) {
+	log.Printf("[Chat] Erro ao processar mensagem %s: %v", messageID, err)
+	if err := p.chatService.MarkMessageFailed(messageID, err.Error()); err != nil {
+		log.Printf("[Chat] %v", err)
+	}
+	p.broker.Publish(messageID, StreamEvent{Type: StreamEventStatus, Status: models.MessageStatusFailed, Error: err.Error()})
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+// blockingProvider só termina quando o contexto do run é cancelado.
+type blockingProvider struct {
+	started chan struct{}
+}
+
+func (p *blockingProvider) Name() string { return "blocking" }
+
+func (p *blockingProvider) Run(ctx context.Context, req ProviderRequest) (*ProviderResponse, error) {
+	close(p.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestChatWorkerPool_Cancel(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	chatService := NewChatService(db)
+	provider := &blockingProvider{started: make(chan struct{})}
+
+	pool := NewChatWorkerPool(NewOpenAIService(db, provider, nil), chatService, ChatWorkerConfig{Workers: 1, QueueSize: 2, Timeout: time.Minute})
+	pool.Start()
+
+	running, err := chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Content: "Olá", Role: "user", Status: models.MessageStatusPending})
+	if err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+	queued, err := chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Content: "Tudo bem?", Role: "user", Status: models.MessageStatusPending})
+	if err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+
+	events, unsubscribe := pool.Subscribe(running.ID)
+	defer unsubscribe()
+
+	pool.Enqueue(ChatJob{Message: running, UserID: user.ID.String()})
+	pool.Enqueue(ChatJob{Message: queued, UserID: user.ID.String()})
+	<-provider.started
+
+	// A mensagem na fila é descartada e a em execução é interrompida
+	pool.Cancel(queued.ID)
+	pool.Cancel(running.ID)
+	for range events {
+	}
+	pool.Shutdown()
+
+	for _, message := range []*models.Message{running, queued} {
+		stored, err := chatService.GetMessageByID(pdi.ID, message.ID.String())
+		if err != nil {
+			t.Fatalf("Erro ao buscar mensagem: %v", err)
+		}
+		if stored.Status != models.MessageStatusFailed || stored.Error != ErrChatCancelled.Error() {
+			t.Errorf("Mensagem %q: status = %v, erro = %v", stored.Content, stored.Status, stored.Error)
+		}
+	}
+
+	if err := pool.Enqueue(ChatJob{Message: running, UserID: user.ID.String()}); !errors.Is(err, ErrChatStopped) {
+		t.Errorf("Enqueue() após Shutdown error = %v, esperado ErrChatStopped", err)
+	}
+	pool.Shutdown()
+}
+
+func TestChatService_FailPendingMessages(t *testing.T) {
+	db, _, pdi := setupChatTestDB(t)
+	chatService := NewChatService(db)
+
+	stale, _ := chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Content: "Antiga", Role: "user", Status: models.MessageStatusPending})
+	recent, _ := chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Content: "Recente", Role: "user", Status: models.MessageStatusPending})
+	db.Model(&models.Message{}).Where("id = ?", stale.ID).UpdateColumn("updated_at", time.Now().Add(-time.Hour))
+
+	// Só a mensagem parada há mais tempo que o limite é marcada como falha
+	failed, err := chatService.FailPendingMessages("reinício", 10*time.Minute)
+	if err != nil || failed != 1 {
+		t.Fatalf("FailPendingMessages() = %d, error = %v", failed, err)
+	}
+	for message, status := range map[*models.Message]models.MessageStatus{stale: models.MessageStatusFailed, recent: models.MessageStatusPending} {
+		stored, _ := chatService.GetMessageByID(pdi.ID, message.ID.String())
+		if stored.Status != status {
+			t.Errorf("Mensagem %q: status = %v, esperado %v", stored.Content, stored.Status, status)
+		}
+	}
+}
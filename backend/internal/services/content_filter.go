@@ -0,0 +1,396 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	// RedactionTokenize troca o dado por um token que é restaurado na resposta.
+	RedactionTokenize = "tokenize"
+	// RedactionMask troca o dado por um marcador fixo, sem volta.
+	RedactionMask = "redact"
+
+	// maxRedactionToken é o tamanho máximo de um token, usado para segurar
+	// trechos incompletos durante o streaming.
+	maxRedactionToken = 32
+)
+
+var ErrContentBlocked = errors.New("conteúdo bloqueado")
+
+// ContentBlockedError é a recusa de uma mensagem por uma regra de moderação.
+type ContentBlockedError struct {
+	Rule   string
+	Reason string
+}
+
+func (e *ContentBlockedError) Error() string {
+	return fmt.Sprintf("conteúdo bloqueado pela regra %s: %s", e.Rule, e.Reason)
+}
+
+func (e *ContentBlockedError) Is(target error) bool {
+	return target == ErrContentBlocked
+}
+
+// Moderator recusa conteúdo que não pode ser enviado ao provedor.
+type Moderator interface {
+	Moderate(text string) error
+}
+
+// Redactor substitui dados pessoais do texto por valores do vault.
+type Redactor interface {
+	Redact(text string, vault *RedactionVault) string
+}
+
+// FilterPipeline roda as regras de moderação e de remoção de dados pessoais
+// sobre o que sai do servidor rumo ao provedor. Um pipeline nil não altera
+// nada.
+type FilterPipeline struct {
+	Moderators []Moderator
+	Redactors  []Redactor
+	// Mode é RedactionTokenize (padrão) ou RedactionMask.
+	Mode string
+	// Secret torna os tokens imprevisíveis para quem não conhece o valor.
+	Secret string
+}
+
+// Moderate devolve ContentBlockedError na primeira regra que recusar o texto.
+func (p *FilterPipeline) Moderate(text string) error {
+	if p == nil {
+		return nil
+	}
+	for _, moderator := range p.Moderators {
+		if err := moderator.Moderate(text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Redact aplica as regras de remoção na ordem configurada.
+func (p *FilterPipeline) Redact(text string, vault *RedactionVault) string {
+	if p == nil {
+		return text
+	}
+	for _, redactor := range p.Redactors {
+		text = redactor.Redact(text, vault)
+	}
+	return text
+}
+
+// Mask troca os dados pessoais do texto por marcadores fixos, sem volta,
+// qualquer que seja o Mode. Serve para textos que saem do servidor por outro
+// caminho que não o provedor, como as exportações.
+func (p *FilterPipeline) Mask(text string) string {
+	if p == nil {
+		return text
+	}
+	vault := &RedactionVault{mode: RedactionMask}
+	for _, redactor := range p.Redactors {
+		text = redactor.Redact(text, vault)
+	}
+	return text
+}
+
+// NewVault cria o vault de uma execução, que guarda os valores substituídos
+// para restaurá-los na resposta.
+func (p *FilterPipeline) NewVault() *RedactionVault {
+	if p == nil {
+		return &RedactionVault{mode: RedactionTokenize, values: map[string]string{}}
+	}
+	return &RedactionVault{mode: p.Mode, secret: p.Secret, values: map[string]string{}}
+}
+
+// RedactionVault associa os tokens aos valores originais. O token de um valor
+// é sempre o mesmo, então conversas em threads remotas continuam coerentes
+// entre mensagens.
+type RedactionVault struct {
+	mu     sync.Mutex
+	mode   string
+	secret string
+	values map[string]string
+}
+
+// Replace devolve o token ou o marcador que substitui o valor.
+func (v *RedactionVault) Replace(kind, value string) string {
+	if v.mode == RedactionMask {
+		return "[" + kind + "]"
+	}
+
+	sum := sha256.Sum256([]byte(v.secret + kind + ":" + value))
+	token := "[" + kind + "_" + hex.EncodeToString(sum[:3]) + "]"
+
+	v.mu.Lock()
+	v.values[token] = value
+	v.mu.Unlock()
+	return token
+}
+
+// Restore troca os tokens conhecidos pelos valores originais.
+func (v *RedactionVault) Restore(text string) string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.values) == 0 || !strings.Contains(text, "[") {
+		return text
+	}
+
+	pairs := make([]string, 0, len(v.values)*2)
+	for token, value := range v.values {
+		pairs = append(pairs, token, value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// RestoreStream envolve onToken para restaurar os tokens nos trechos da
+// resposta. Um token pode chegar dividido entre trechos, então o texto a
+// partir de um "[" sem fechamento fica retido até completar o token. flush
+// envia o que restar ao fim da resposta.
+func (v *RedactionVault) RestoreStream(onToken TokenHandler) (TokenHandler, func()) {
+	if onToken == nil {
+		return nil, func() {}
+	}
+
+	var pending string
+	handler := func(token string) {
+		pending += token
+		held := ""
+		if open := strings.LastIndex(pending, "["); open >= 0 && !strings.Contains(pending[open:], "]") && len(pending)-open < maxRedactionToken {
+			pending, held = pending[:open], pending[open:]
+		}
+		if pending != "" {
+			onToken(v.Restore(pending))
+		}
+		pending = held
+	}
+	flush := func() {
+		if pending != "" {
+			onToken(v.Restore(pending))
+			pending = ""
+		}
+	}
+	return handler, flush
+}
+
+// PatternRedactor substitui os trechos que casam com a expressão. Com Group
+// maior que zero, só o grupo capturado é substituído; se a expressão tiver
+// alternativas com um grupo cada, vale o primeiro deles a partir de Group que
+// participou do casamento. Valid, se informado, descarta falsos positivos.
+type PatternRedactor struct {
+	Kind    string
+	Pattern *regexp.Regexp
+	Group   int
+	Valid   func(string) bool
+}
+
+func (r *PatternRedactor) Redact(text string, vault *RedactionVault) string {
+	matches := r.Pattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[2*r.Group], match[2*r.Group+1]
+		for group := r.Group + 1; start < 0 && group < len(match)/2; group++ {
+			start, end = match[2*group], match[2*group+1]
+		}
+		if start < 0 {
+			continue
+		}
+		value := text[start:end]
+		if r.Valid != nil && !r.Valid(value) {
+			continue
+		}
+		out.WriteString(text[last:start])
+		out.WriteString(vault.Replace(r.Kind, value))
+		last = end
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+// TermModerator bloqueia textos com algum dos termos, sem diferenciar
+// maiúsculas de minúsculas.
+type TermModerator struct {
+	Rule    string
+	Reason  string
+	pattern *regexp.Regexp
+}
+
+func NewTermModerator(rule, reason string, terms []string) *TermModerator {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term != "" {
+			quoted = append(quoted, regexp.QuoteMeta(term))
+		}
+	}
+	moderator := &TermModerator{Rule: rule, Reason: reason}
+	if len(quoted) > 0 {
+		moderator.pattern = regexp.MustCompile(`(?i)(^|[^\pL\pN])(` + strings.Join(quoted, "|") + `)($|[^\pL\pN])`)
+	}
+	return moderator
+}
+
+func (m *TermModerator) Moderate(text string) error {
+	if m.pattern != nil && m.pattern.MatchString(text) {
+		return &ContentBlockedError{Rule: m.Rule, Reason: m.Reason}
+	}
+	return nil
+}
+
+// piiRedactors são as regras de dados pessoais disponíveis, por nome. A ordem
+// de aplicação é a de defaultPIIRules: o CPF sai antes do telefone, que casaria
+// com os mesmos dígitos.
+var piiRedactors = map[string]Redactor{
+	"cpf": &PatternRedactor{
+		Kind:    "CPF",
+		Pattern: regexp.MustCompile(`\b\d{3}\.?\d{3}\.?\d{3}-?\d{2}\b`),
+		Valid:   validCPF,
+	},
+	"email": &PatternRedactor{
+		Kind:    "EMAIL",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	},
+	"phone": &PatternRedactor{
+		Kind: "TELEFONE",
+		// Com DDD, fixo ou celular; sem DDD, só celular com separador, para
+		// não casar com qualquer número de 8 dígitos
+		Pattern: regexp.MustCompile(`(?:\+55\s?\d{2}\s?|\(\d{2}\)\s?|\b\d{2}\s)9?\d{4}[-\s]?\d{4}\b|\b9\d{4}[-\s]\d{4}\b`),
+		Valid:   validPhone,
+	},
+	"salary": &PatternRedactor{
+		Kind: "VALOR",
+		// Valores com moeda ("R$ 12 mil", "12 mil reais") em qualquer lugar;
+		// sem moeda ("12k", "12 mil"), só logo depois de falar em salário,
+		// para não casar com "5 mil pessoas"
+		Pattern: regexp.MustCompile(`(?i)((?:R\$|US\$|\$)\s?\d[\d.,]*(?:\s?(?:mil|k)\b)?|\b\d[\d.,]*\s?(?:mil\s+)?(?:reais|dólares|dolares)\b)|\b(?:salário|salario|remuneração|remuneracao|pretensão|pretensao|ganho|recebo)\b[^\d$\n]{0,20}?(\d[\d.,]*\s?(?:mil|k)\b)`),
+		Group:   1,
+	},
+	"manager": &PatternRedactor{
+		Kind:    "NOME",
+		Pattern: regexp.MustCompile(`(?i:\b(?:gestor|gestora|chefe|líder|lider|manager|coordenador|coordenadora|diretor|diretora)\b)[,:]?\s+(?:(?:é|e|se chama|chamad[oa])\s+)?(?:(?:o|a)\s+)?(\p{Lu}\p{Ll}+(?:\s+\p{Lu}\p{Ll}+)*)`),
+		Group:   1,
+	},
+}
+
+var defaultPIIRules = []string{"cpf", "email", "phone", "salary", "manager"}
+
+// FilterPipelineFromEnv lê LLM_PII_RULES (regras separadas por vírgula, todas
+// por padrão, "none" desativa), LLM_PII_MODE (tokenize ou redact),
+// LLM_PII_SECRET e LLM_BLOCKED_TERMS (termos que bloqueiam a mensagem).
+func FilterPipelineFromEnv() (*FilterPipeline, error) {
+	pipeline := &FilterPipeline{
+		Mode:   RedactionTokenize,
+		Secret: os.Getenv("LLM_PII_SECRET"),
+	}
+
+	switch mode := os.Getenv("LLM_PII_MODE"); mode {
+	case "", RedactionTokenize:
+	case RedactionMask:
+		pipeline.Mode = RedactionMask
+	default:
+		return nil, fmt.Errorf("LLM_PII_MODE desconhecido: %s", mode)
+	}
+
+	rules := defaultPIIRules
+	if value := os.Getenv("LLM_PII_RULES"); value == "none" {
+		rules = nil
+	} else if value != "" {
+		rules = splitList(value)
+	}
+	redactors, err := PIIRedactors(rules)
+	if err != nil {
+		return nil, err
+	}
+	pipeline.Redactors = redactors
+
+	if terms := splitList(os.Getenv("LLM_BLOCKED_TERMS")); len(terms) > 0 {
+		pipeline.Moderators = append(pipeline.Moderators, NewTermModerator("blocked_terms", "a mensagem contém termos não permitidos", terms))
+	}
+	return pipeline, nil
+}
+
+// PIIRedactors devolve as regras de dados pessoais pedidas, na ordem segura
+// de aplicação.
+func PIIRedactors(rules []string) ([]Redactor, error) {
+	requested := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if _, ok := piiRedactors[rule]; !ok {
+			return nil, fmt.Errorf("regra de dados pessoais desconhecida: %s", rule)
+		}
+		requested[rule] = true
+	}
+
+	redactors := make([]Redactor, 0, len(requested))
+	for _, rule := range defaultPIIRules {
+		if requested[rule] {
+			redactors = append(redactors, piiRedactors[rule])
+		}
+	}
+	return redactors, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// validCPF confere os dígitos verificadores do CPF.
+func validCPF(value string) bool {
+	digits := make([]int, 0, 11)
+	for _, r := range value {
+		if unicode.IsDigit(r) {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	if len(digits) != 11 {
+		return false
+	}
+
+	same := true
+	for _, d := range digits[1:] {
+		if d != digits[0] {
+			same = false
+			break
+		}
+	}
+	if same {
+		return false
+	}
+
+	for _, n := range []int{9, 10} {
+		sum := 0
+		for i := 0; i < n; i++ {
+			sum += digits[i] * (n + 1 - i)
+		}
+		check := sum * 10 % 11
+		if check == 10 {
+			check = 0
+		}
+		if check != digits[n] {
+			return false
+		}
+	}
+	return true
+}
+
+var yearPairPattern = regexp.MustCompile(`^(?:\d{2}\s)?(?:19|20)\d{2}[-\s]?(?:19|20)\d{2}$`)
+
+// validPhone descarta pares de anos, como "2019-2023", que o padrão do
+// telefone aceita quando precedidos de um número de dois dígitos.
+func validPhone(value string) bool {
+	return !yearPairPattern.MatchString(value)
+}
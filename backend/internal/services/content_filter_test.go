@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+func defaultTestPipeline(t *testing.T) *FilterPipeline {
+	redactors, err := PIIRedactors(defaultPIIRules)
+	if err != nil {
+		t.Fatalf("PIIRedactors() error = %v", err)
+	}
+	return &FilterPipeline{Redactors: redactors, Mode: RedactionTokenize, Secret: "teste"}
+}
+
+func TestFilterPipeline_Redact(t *testing.T) {
+	pipeline := defaultTestPipeline(t)
+
+	tests := []struct {
+		name  string
+		input string
+		kind  string
+		value string
+	}{
+		{"cpf", "Meu CPF é 529.982.247-25.", "CPF", "529.982.247-25"},
+		{"cpf sem pontuação", "CPF 52998224725", "CPF", "52998224725"},
+		{"email", "Escreva para ana.souza@empresa.com.br", "EMAIL", "ana.souza@empresa.com.br"},
+		{"telefone", "Meu celular é (11) 98765-4321", "TELEFONE", "(11) 98765-4321"},
+		{"telefone com +55", "Ligue +55 11 98765-4321", "TELEFONE", "+55 11 98765-4321"},
+		{"telefone fixo com DDD", "Ramal 11 3456-7890", "TELEFONE", "11 3456-7890"},
+		{"celular sem DDD", "Me chame no 98765-4321", "TELEFONE", "98765-4321"},
+		{"salário", "Ganho R$ 12.500,00 por mês", "VALOR", "R$ 12.500,00"},
+		{"salário em mil", "Ganho R$ 12 mil por mês", "VALOR", "R$ 12 mil"},
+		{"salário em reais", "Recebo 12 mil reais hoje", "VALOR", "12 mil reais"},
+		{"salário em reais sem mil", "A proposta é de 12.500 reais", "VALOR", "12.500 reais"},
+		{"salário em k", "Meu salário de 12k não acompanha o mercado", "VALOR", "12k"},
+		{"salário sem moeda", "O salário atual é 12 mil, quero 15 mil", "VALOR", "12 mil"},
+		{"pretensão em k", "Minha pretensão salarial é 15k", "VALOR", "15k"},
+		{"gestor", "Meu gestor é o João Silva e ele me apoia", "NOME", "João Silva"},
+		{"gestora", "minha gestora, Maria, pediu o PDI", "NOME", "Maria"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault := pipeline.NewVault()
+			redacted := pipeline.Redact(tt.input, vault)
+
+			if strings.Contains(redacted, tt.value) || !strings.Contains(redacted, "["+tt.kind+"_") {
+				t.Fatalf("Redact(%q) = %q", tt.input, redacted)
+			}
+			if restored := vault.Restore(redacted); restored != tt.input {
+				t.Errorf("Restore() = %q, esperado %q", restored, tt.input)
+			}
+		})
+	}
+}
+
+func TestFilterPipeline_KeepsNonPII(t *testing.T) {
+	pipeline := defaultTestPipeline(t)
+
+	for _, input := range []string{
+		"CPF inválido 123.456.789-10",
+		"Quero ser promovido em 2025 e liderar 5 pessoas",
+		"Meu gestor pediu mais autonomia",
+		"Trabalhei lá de 2019-2023 como analista",
+		"Fiquei na empresa entre 2019 2023",
+		"Foram 12 2019-2023 e 3 2024",
+		"Pedido 12345678 e matrícula 1234-5678",
+		"Quero liderar um time de 5 mil pessoas",
+		"Cheguei a 10k seguidores no blog",
+	} {
+		if redacted := pipeline.Redact(input, pipeline.NewVault()); redacted != input {
+			t.Errorf("Redact(%q) = %q, esperado sem alteração", input, redacted)
+		}
+	}
+}
+
+func TestFilterPipeline_StableTokensAndMask(t *testing.T) {
+	pipeline := defaultTestPipeline(t)
+
+	first := pipeline.Redact("ana@empresa.com", pipeline.NewVault())
+	second := pipeline.Redact("Oi ana@empresa.com", pipeline.NewVault())
+	if !strings.HasSuffix(second, first) {
+		t.Errorf("Tokens diferentes para o mesmo valor: %q e %q", first, second)
+	}
+
+	pipeline.Mode = RedactionMask
+	vault := pipeline.NewVault()
+	masked := pipeline.Redact("ana@empresa.com", vault)
+	if masked != "[EMAIL]" || vault.Restore(masked) != "[EMAIL]" {
+		t.Errorf("Redact() no modo redact = %q", masked)
+	}
+}
+
+func TestRedactionVault_RestoreStream(t *testing.T) {
+	pipeline := defaultTestPipeline(t)
+	vault := pipeline.NewVault()
+	token := pipeline.Redact("ana@empresa.com", vault)
+
+	var out strings.Builder
+	handler, flush := vault.RestoreStream(func(chunk string) {
+		if strings.Contains(chunk, "[EMAIL") {
+			t.Errorf("Token parcial enviado ao cliente: %q", chunk)
+		}
+		out.WriteString(chunk)
+	})
+	for _, chunk := range []string{"Vou escrever para ", token[:4], token[4:9], token[9:], " hoje [sem token"} {
+		handler(chunk)
+	}
+	flush()
+
+	if out.String() != "Vou escrever para ana@empresa.com hoje [sem token" {
+		t.Errorf("RestoreStream() = %q", out.String())
+	}
+}
+
+func TestTermModerator(t *testing.T) {
+	pipeline := &FilterPipeline{Moderators: []Moderator{
+		NewTermModerator("blocked_terms", "termo proibido", []string{"senha do banco", "cartão"}),
+	}}
+
+	err := pipeline.Moderate("Qual a SENHA DO BANCO?")
+	var blocked *ContentBlockedError
+	if !errors.As(err, &blocked) || !errors.Is(err, ErrContentBlocked) || blocked.Rule != "blocked_terms" {
+		t.Fatalf("Moderate() error = %v", err)
+	}
+	if err := pipeline.Moderate("Quero melhorar minha comunicação"); err != nil {
+		t.Errorf("Moderate() error = %v, esperado nil", err)
+	}
+	if err := pipeline.Moderate("cartãozinho"); err != nil {
+		t.Errorf("Moderate() deve considerar palavras inteiras: %v", err)
+	}
+}
+
+func TestFilterPipelineFromEnv(t *testing.T) {
+	t.Setenv("LLM_PII_RULES", "email,cpf")
+	t.Setenv("LLM_BLOCKED_TERMS", "proibido")
+	pipeline, err := FilterPipelineFromEnv()
+	if err != nil {
+		t.Fatalf("FilterPipelineFromEnv() error = %v", err)
+	}
+	if len(pipeline.Redactors) != 2 || len(pipeline.Moderators) != 1 {
+		t.Errorf("Pipeline = %d regras, %d moderadores", len(pipeline.Redactors), len(pipeline.Moderators))
+	}
+	if redacted := pipeline.Redact("(11) 98765-4321", pipeline.NewVault()); redacted != "(11) 98765-4321" {
+		t.Errorf("Telefone não deveria ser removido: %q", redacted)
+	}
+
+	t.Setenv("LLM_PII_RULES", "endereco")
+	if _, err := FilterPipelineFromEnv(); err == nil {
+		t.Error("FilterPipelineFromEnv() deve recusar regra desconhecida")
+	}
+}
+
+// echoProvider responde repetindo a entrada recebida.
+type echoProvider struct {
+	recordingProvider
+}
+
+func (p *echoProvider) Run(ctx context.Context, req ProviderRequest) (*ProviderResponse, error) {
+	p.last = req
+	if req.OnToken != nil {
+		req.OnToken("Anotei: " + req.Input)
+	}
+	return &ProviderResponse{Content: "Anotei: " + req.Input}, nil
+}
+
+func TestOpenAIService_RedactsPII(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	provider := &echoProvider{}
+	pipeline := defaultTestPipeline(t)
+	pipeline.Moderators = []Moderator{NewTermModerator("blocked_terms", "termo proibido", []string{"proibido"})}
+	service := NewOpenAIService(db, provider, nil)
+	service.SetFilters(pipeline)
+
+	content := "Meu email é ana@empresa.com"
+	message, err := NewChatService(db).CreateMessage(&models.Message{PDIID: pdi.ID, Content: content, Role: "user"})
+	if err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+
+	var streamed strings.Builder
+	reply, err := service.StreamMessage(context.Background(), message, user.ID.String(), func(event StreamEvent) {
+		if event.Type == StreamEventToken {
+			streamed.WriteString(event.Content)
+		}
+	})
+	if err != nil {
+		t.Fatalf("StreamMessage() error = %v", err)
+	}
+
+	if strings.Contains(provider.last.Input, "ana@empresa.com") {
+		t.Errorf("Email enviado ao provedor: %q", provider.last.Input)
+	}
+	if reply.Content != "Anotei: "+content || streamed.String() != reply.Content {
+		t.Errorf("Resposta sem restaurar: %q, stream %q", reply.Content, streamed.String())
+	}
+
+	blocked, _ := NewChatService(db).CreateMessage(&models.Message{PDIID: pdi.ID, Content: "algo proibido", Role: "user"})
+	if _, err := service.ProcessMessage(context.Background(), blocked, user.ID.String()); !errors.Is(err, ErrContentBlocked) {
+		t.Errorf("ProcessMessage() error = %v, esperado ErrContentBlocked", err)
+	}
+
+	// O texto dos anexos também passa pela moderação
+	attached, _ := NewChatService(db).CreateMessage(&models.Message{PDIID: pdi.ID, Content: "Veja o anexo", Role: "user", Attachments: []models.Attachment{
+		{FileName: "notas.txt", ContentType: "text/plain", Text: "um assunto proibido"},
+	}})
+	if _, err := service.ProcessMessage(context.Background(), attached, user.ID.String()); !errors.Is(err, ErrContentBlocked) {
+		t.Errorf("ProcessMessage() com anexo bloqueado error = %v, esperado ErrContentBlocked", err)
+	}
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"meu-pdi-estrategico/backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// ContextModeThread mantém o contexto na thread do provedor (Assistants).
+	ContextModeThread = "thread"
+	// ContextModeManaged monta o contexto a partir da tabela messages, com
+	// janela limitada por tokens e resumo das mensagens mais antigas.
+	ContextModeManaged = "managed"
+)
+
+const summaryInstructions = `Você resume conversas de coaching de carreira para dar continuidade ao atendimento.
+Atualize o resumo existente com as novas mensagens, preservando objetivos, competências, decisões, prazos e pendências da pessoa usuária.
+Escreva em português, em até 200 palavras, sem saudações nem comentários sobre o resumo.`
+
+// ContextConfig define a janela de contexto: o orçamento total de tokens e
+// quantas mensagens recentes são mantidas mesmo acima do orçamento.
+type ContextConfig struct {
+	MaxTokens  int
+	KeepRecent int
+}
+
+// ContextConfigFromEnv lê LLM_CONTEXT_MAX_TOKENS e LLM_CONTEXT_KEEP_RECENT.
+func ContextConfigFromEnv() ContextConfig {
+	return ContextConfig{
+		MaxTokens:  envInt("LLM_CONTEXT_MAX_TOKENS", 6000),
+		KeepRecent: envInt("LLM_CONTEXT_KEEP_RECENT", 4),
+	}
+}
+
+// EstimateTokens aproxima a contagem de tokens (cerca de 4 caracteres por
+// token, mais o custo fixo de cada mensagem).
+func EstimateTokens(text string) int {
+	return utf8.RuneCountInString(text)/4 + 4
+}
+
+// SummaryResult é o resumo gerado e o consumo da chamada que o gerou.
+type SummaryResult struct {
+	Summary string
+	Model   string
+	Usage   Usage
+}
+
+// Summarizer condensa o resumo anterior e as novas mensagens em um resumo.
+type Summarizer interface {
+	Summarize(ctx context.Context, summary string, turns []Turn) (*SummaryResult, error)
+}
+
+// ProviderSummarizer gera resumos com um provedor que não depende de thread.
+type ProviderSummarizer struct {
+	provider Provider
+}
+
+func NewProviderSummarizer(provider Provider) *ProviderSummarizer {
+	return &ProviderSummarizer{provider: provider}
+}
+
+func (s *ProviderSummarizer) Summarize(ctx context.Context, summary string, turns []Turn) (*SummaryResult, error) {
+	var transcript strings.Builder
+	if summary != "" {
+		fmt.Fprintf(&transcript, "Resumo atual:\n%s\n\n", summary)
+	}
+	transcript.WriteString("Novas mensagens:\n")
+	for _, turn := range turns {
+		fmt.Fprintf(&transcript, "%s: %s\n", turn.Role, turn.Content)
+	}
+
+	resp, err := s.provider.Run(ctx, ProviderRequest{
+		Input:        transcript.String(),
+		Instructions: summaryInstructions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao resumir conversa: %v", err)
+	}
+	return &SummaryResult{Summary: strings.TrimSpace(resp.Content), Model: resp.Model, Usage: resp.Usage}, nil
+}
+
+// summaryPrompt apresenta o resumo ao modelo como contexto adicional.
+func summaryPrompt(summary string) string {
+	return "Resumo da conversa anterior com a pessoa usuária:\n" + summary
+}
+
+// ContextManager monta o contexto da conversa a partir do histórico salvo,
+// de modo que a troca de modelo ou de provedor não perca a conversa.
+type ContextManager struct {
+	db         *gorm.DB
+	summarizer Summarizer
+	config     ContextConfig
+}
+
+func NewContextManager(db *gorm.DB, summarizer Summarizer, config ContextConfig) *ContextManager {
+	if config.MaxTokens <= 0 {
+		config.MaxTokens = 6000
+	}
+	if config.KeepRecent <= 0 {
+		config.KeepRecent = 1
+	}
+	return &ContextManager{db: db, summarizer: summarizer, config: config}
+}
+
+// NewContextManagerFromEnv lê LLM_CONTEXT_MODE. Sem valor, provedores com
+// thread usam o modo thread e os demais o modo managed. No modo thread não
+// há gerenciador e o resultado é nil.
+func NewContextManagerFromEnv(db *gorm.DB, provider Provider) (*ContextManager, error) {
+	mode := os.Getenv("LLM_CONTEXT_MODE")
+	if mode == "" {
+		mode = ContextModeManaged
+		if _, ok := provider.(ThreadProvider); ok {
+			mode = ContextModeThread
+		}
+	}
+
+	switch mode {
+	case ContextModeThread:
+		return nil, nil
+	case ContextModeManaged:
+		summarizer := NewProviderSummarizer(provider)
+		// Os resumos não usam thread; com Assistants eles vão para Chat Completions
+		if assistants, ok := provider.(*AssistantsProvider); ok {
+			summarizer = NewProviderSummarizer(NewChatCompletionsProvider(assistants.client, os.Getenv("OPENAI_SUMMARY_MODEL")))
+		}
+		return NewContextManager(db, summarizer, ContextConfigFromEnv()), nil
+	default:
+		return nil, fmt.Errorf("LLM_CONTEXT_MODE desconhecido: %s", mode)
+	}
+}
+
+// Window devolve as mensagens mais recentes que cabem no orçamento de tokens
+// e o resumo das anteriores. Quando mensagens saem da janela, elas são
+// incorporadas ao resumo salvo no PDI.
+func (m *ContextManager) Window(ctx context.Context, pdi *models.PDI, messages []*models.Message, input string) ([]Turn, string, error) {
+	// Mensagens já resumidas não entram de novo na janela
+	candidates := messages
+	if pdi.ContextSummarizedUntil != nil {
+		candidates = candidates[:0:0]
+		for _, msg := range messages {
+			if msg.CreatedAt.After(*pdi.ContextSummarizedUntil) {
+				candidates = append(candidates, msg)
+			}
+		}
+	}
+
+	budget := m.config.MaxTokens - EstimateTokens(input)
+	if pdi.ContextSummary != "" {
+		budget -= EstimateTokens(pdi.ContextSummary)
+	}
+
+	start := len(candidates)
+	used := 0
+	for i := len(candidates) - 1; i >= 0; i-- {
+		cost := EstimateTokens(candidates[i].Content)
+		kept := len(candidates) - start
+		if used+cost > budget && kept >= m.config.KeepRecent {
+			break
+		}
+		used += cost
+		start = i
+	}
+
+	summary := pdi.ContextSummary
+	if dropped := candidates[:start]; len(dropped) > 0 {
+		updated, err := m.summarizer.Summarize(ctx, summary, toTurns(dropped))
+		if err != nil {
+			// Sem resumo novo, as mensagens são resumidas na próxima tentativa
+			log.Printf("[Context] %v", err)
+		} else {
+			until := dropped[len(dropped)-1].CreatedAt
+			if err := m.saveSummary(pdi, updated, until); err != nil {
+				return nil, "", err
+			}
+			summary = updated.Summary
+		}
+	}
+
+	return toTurns(candidates[start:]), summary, nil
+}
+
+// saveSummary grava o resumo no PDI e o consumo da chamada que o gerou, que
+// entra no consumo e na cota do usuário.
+func (m *ContextManager) saveSummary(pdi *models.PDI, result *SummaryResult, until time.Time) error {
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(pdi).Where("id = ?", pdi.ID).Updates(map[string]interface{}{
+			"context_summary":          result.Summary,
+			"context_summarized_until": until,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UsageRecord{
+			PDIID:            pdi.ID,
+			Kind:             models.UsageKindSummary,
+			Model:            result.Model,
+			PromptTokens:     result.Usage.PromptTokens,
+			CompletionTokens: result.Usage.CompletionTokens,
+			TotalTokens:      result.Usage.TotalTokens,
+			CostUSD:          EstimateCost(result.Model, result.Usage),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao salvar resumo da conversa: %v", err)
+	}
+
+	pdi.ContextSummary = result.Summary
+	pdi.ContextSummarizedUntil = &until
+	log.Printf("[Context] Resumo do PDI %s atualizado até %s", pdi.ID, until.Format(time.RFC3339))
+	return nil
+}
+
+func toTurns(messages []*models.Message) []Turn {
+	turns := make([]Turn, 0, len(messages))
+	for _, msg := range messages {
+		turns = append(turns, Turn{Role: msg.Role, Content: msg.Content})
+	}
+	return turns
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+// recordingSummarizer registra as chamadas e devolve um resumo previsível.
+type recordingSummarizer struct {
+	calls [][]Turn
+}
+
+func (s *recordingSummarizer) Summarize(ctx context.Context, summary string, turns []Turn) (*SummaryResult, error) {
+	s.calls = append(s.calls, turns)
+	var contents []string
+	for _, turn := range turns {
+		contents = append(contents, turn.Content)
+	}
+	return &SummaryResult{
+		Summary: strings.TrimSpace(summary + " " + strings.Join(contents, ",")),
+		Model:   "gpt-4o-mini",
+		Usage:   Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+	}, nil
+}
+
+// recordingProvider guarda a última requisição recebida.
+type recordingProvider struct {
+	last ProviderRequest
+}
+
+func (p *recordingProvider) Name() string { return "recording" }
+
+func (p *recordingProvider) Run(ctx context.Context, req ProviderRequest) (*ProviderResponse, error) {
+	p.last = req
+	return &ProviderResponse{Content: "ok"}, nil
+}
+
+func createConversation(t *testing.T, chatService *ChatService, pdiID string, from, to int) {
+	base := time.Now().Add(-time.Hour)
+	for i := from; i < to; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		// Cada mensagem custa 4 + 40/4 = 14 tokens estimados
+		content := fmt.Sprintf("m%02d", i) + strings.Repeat(".", 37)
+		message := &models.Message{PDIID: pdiID, Role: role, Content: content, CreatedAt: base.Add(time.Duration(i) * time.Second)}
+		if _, err := chatService.CreateMessage(message); err != nil {
+			t.Fatalf("Erro ao criar mensagem: %v", err)
+		}
+	}
+}
+
+func TestContextManager_Window(t *testing.T) {
+	db, _, pdi := setupChatTestDB(t)
+	chatService := NewChatService(db)
+	createConversation(t, chatService, pdi.ID, 0, 10)
+
+	summarizer := &recordingSummarizer{}
+	// Orçamento para 4 mensagens de 14 tokens mais a entrada "oi" (4 tokens)
+	manager := NewContextManager(db, summarizer, ContextConfig{MaxTokens: 60, KeepRecent: 2})
+
+	messages, _ := chatService.GetMessagesByPDIID(pdi.ID)
+	turns, summary, err := manager.Window(context.Background(), pdi, messages, "oi")
+	if err != nil {
+		t.Fatalf("Window() error = %v", err)
+	}
+
+	if len(turns) != 4 || !strings.HasPrefix(turns[0].Content, "m06") {
+		t.Errorf("Window() turns = %d, primeira = %v", len(turns), turns[0].Content)
+	}
+	if len(summarizer.calls) != 1 || len(summarizer.calls[0]) != 6 {
+		t.Fatalf("Resumo esperado das 6 mensagens antigas, chamadas = %v", summarizer.calls)
+	}
+
+	var stored models.PDI
+	db.First(&stored, "id = ?", pdi.ID)
+	if stored.ContextSummary != summary || stored.ContextSummarizedUntil == nil {
+		t.Errorf("Resumo não salvo no PDI: %q", stored.ContextSummary)
+	}
+
+	// O consumo do resumo entra no consumo do usuário, sem contar como resposta
+	usage, err := NewUsageService(db).GetPDIUsage(pdi.UserID, pdi.ID, UsagePeriod{})
+	if err != nil {
+		t.Fatalf("GetPDIUsage() error = %v", err)
+	}
+	if usage.Messages != 5 || usage.TotalTokens != 120 || usage.CostUSD == 0 {
+		t.Errorf("Consumo com resumo = %+v", usage.UsageTotals)
+	}
+
+	// Novas mensagens: só o que saiu da janela desde o último resumo é resumido
+	createConversation(t, chatService, pdi.ID, 10, 12)
+	messages, _ = chatService.GetMessagesByPDIID(pdi.ID)
+	turns, _, err = manager.Window(context.Background(), &stored, messages, "oi")
+	if err != nil {
+		t.Fatalf("Window() error = %v", err)
+	}
+	if len(summarizer.calls) != 2 {
+		t.Fatalf("Esperadas 2 chamadas ao resumo, recebidas %d", len(summarizer.calls))
+	}
+	for _, turn := range summarizer.calls[1] {
+		if strings.HasPrefix(turn.Content, "m00") {
+			t.Errorf("Mensagem já resumida enviada de novo: %v", turn.Content)
+		}
+	}
+	if len(turns) < 2 || !strings.HasPrefix(turns[len(turns)-1].Content, "m11") {
+		t.Errorf("Window() deve manter as mensagens mais recentes: %v", turns)
+	}
+}
+
+func TestOpenAIService_ManagedContext(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	chatService := NewChatService(db)
+	createConversation(t, chatService, pdi.ID, 0, 10)
+
+	provider := &recordingProvider{}
+	manager := NewContextManager(db, &recordingSummarizer{}, ContextConfig{MaxTokens: 60, KeepRecent: 2})
+	service := NewOpenAIService(db, provider, manager)
+
+	message, err := chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Content: "oi", Role: "user"})
+	if err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+
+	if _, err := service.ProcessMessage(context.Background(), message, user.ID.String()); err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+
+	if provider.last.ThreadID != "" || len(provider.last.History) != 4 || provider.last.Summary == "" {
+		t.Errorf("Requisição inesperada: thread=%q history=%d summary=%q", provider.last.ThreadID, len(provider.last.History), provider.last.Summary)
+	}
+	// Sem persona, a configuração do provedor não é substituída
+	if provider.last.OverrideTools || provider.last.Instructions != "" || provider.last.Temperature != nil {
+		t.Errorf("Requisição sem persona substitui a configuração do provedor: %+v", provider.last)
+	}
+}
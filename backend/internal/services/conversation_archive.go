@@ -0,0 +1,248 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"meu-pdi-estrategico/backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrArchiveNotFound   = errors.New("conversa arquivada não encontrada")
+	ErrConversationEmpty = errors.New("a conversa do PDI não tem mensagens")
+)
+
+// emptyPDIContent é o conteúdo de um PDI sem plano, o mesmo da criação.
+const emptyPDIContent = "{}"
+
+// ResetConversation reinicia a conversa do PDI. As mensagens são excluídas
+// logicamente e ligadas a um arquivo, junto com a thread do provedor e o
+// resumo, que deixam de valer no PDI. Com keepContent falso, o conteúdo do
+// PDI também volta ao vazio e fica guardado no arquivo.
+func (s *ChatService) ResetConversation(pdi *models.PDI, keepContent bool) (*models.ConversationArchive, error) {
+	var archive *models.ConversationArchive
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// O arquivo guarda a thread, o resumo e o conteúdo lidos com a trava, não
+		// os da cópia do chamador, que pode ser anterior a outra alteração
+		locked, err := lockPDI(tx, pdi.ID)
+		if err != nil {
+			return err
+		}
+		*pdi = *locked
+		if err := conversationIdle(tx, pdi.ID); err != nil {
+			return err
+		}
+
+		archive, err = archiveConversation(tx, pdi, keepContent)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrConversationBusy) || errors.Is(err, ErrConversationEmpty) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("erro ao reiniciar conversa: %v", err)
+	}
+	return archive, nil
+}
+
+// ListArchives devolve as conversas arquivadas do PDI, da mais recente à mais
+// antiga.
+func (s *ChatService) ListArchives(pdiID string) ([]models.ConversationArchive, error) {
+	archives := []models.ConversationArchive{}
+	if err := s.db.Where("pdi_id = ?", pdiID).
+		Order("created_at DESC").
+		Find(&archives).Error; err != nil {
+		return nil, fmt.Errorf("erro ao buscar conversas arquivadas: %v", err)
+	}
+	return archives, nil
+}
+
+// GetArchive devolve a conversa arquivada com as suas mensagens, de todos os
+// ramos, em ordem de criação.
+func (s *ChatService) GetArchive(pdiID, archiveID string) (*models.ConversationArchive, []*models.Message, error) {
+	archive, err := findArchive(s.db, pdiID, archiveID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var messages []*models.Message
+	if err := s.db.Unscoped().Preload("Attachments").
+		Where("archive_id = ?", archive.ID).
+		Order("created_at ASC, id ASC").
+		Find(&messages).Error; err != nil {
+		return nil, nil, fmt.Errorf("erro ao buscar mensagens: %v", err)
+	}
+	return archive, messages, nil
+}
+
+// RestoreArchive traz de volta a conversa arquivada, com a thread, o ramo
+// ativo e, se ele tiver sido limpo, o conteúdo do PDI. A conversa atual, se
+// houver, é arquivada antes. Devolve esse novo arquivo ou nil.
+func (s *ChatService) RestoreArchive(pdi *models.PDI, archiveID string) (*models.ConversationArchive, error) {
+	var current *models.ConversationArchive
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockPDI(tx, pdi.ID)
+		if err != nil {
+			return err
+		}
+		*pdi = *locked
+		archive, err := findArchive(tx, pdi.ID, archiveID)
+		if err != nil {
+			return err
+		}
+		if err := conversationIdle(tx, pdi.ID); err != nil {
+			return err
+		}
+
+		// Nada da conversa atual se perde: as mensagens e, se for substituído,
+		// o conteúdo vão para um novo arquivo
+		current, err = archiveConversation(tx, pdi, archive.Content == nil)
+		if err != nil && !errors.Is(err, ErrConversationEmpty) {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&models.Message{}).
+			Where("archive_id = ?", archive.ID).
+			Updates(map[string]interface{}{"archive_id": nil, "deleted_at": nil}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(pdi).Where("id = ?", pdi.ID).Updates(map[string]interface{}{
+			"thread_id":                archive.ThreadID,
+			"active_branch_id":         archive.ActiveBranchID,
+			"context_summary":          archive.ContextSummary,
+			"context_summarized_until": archive.ContextSummarizedUntil,
+		}).Error; err != nil {
+			return err
+		}
+
+		pdi.ThreadID = archive.ThreadID
+		pdi.ActiveBranchID = archive.ActiveBranchID
+		pdi.ContextSummary = archive.ContextSummary
+		pdi.ContextSummarizedUntil = archive.ContextSummarizedUntil
+		if archive.Content != nil {
+			if err := restoreGoals(tx, pdi.ID, *archive.Content); err != nil {
+				return err
+			}
+			source := RevisionSource{Author: models.RevisionAuthorUser, Reason: RevisionReasonArchiveRestore}
+			if err := recordRevision(tx, pdi, *archive.Content, source); err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(archive).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrArchiveNotFound) || errors.Is(err, ErrConversationBusy) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("erro ao restaurar conversa: %v", err)
+	}
+	return current, nil
+}
+
+func findArchive(tx *gorm.DB, pdiID, archiveID string) (*models.ConversationArchive, error) {
+	if _, err := uuid.Parse(archiveID); err != nil {
+		return nil, ErrArchiveNotFound
+	}
+
+	var archive models.ConversationArchive
+	if err := tx.Where("id = ? AND pdi_id = ?", archiveID, pdiID).First(&archive).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrArchiveNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar conversa arquivada: %v", err)
+	}
+	return &archive, nil
+}
+
+// conversationIdle falha com ErrConversationBusy se houver mensagens do PDI
+// aguardando o provedor, em qualquer ramo.
+func conversationIdle(tx *gorm.DB, pdiID string) error {
+	var count int64
+	if err := tx.Model(&models.Message{}).
+		Where("pdi_id = ? AND role = ? AND status = ?", pdiID, "user", models.MessageStatusPending).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("erro ao buscar mensagens: %v", err)
+	}
+	if count > 0 {
+		return ErrConversationBusy
+	}
+	return nil
+}
+
+// archiveConversation move a conversa atual do PDI para um arquivo e deixa o
+// PDI pronto para uma conversa nova, no ramo principal e sem thread.
+func archiveConversation(tx *gorm.DB, pdi *models.PDI, keepContent bool) (*models.ConversationArchive, error) {
+	var messages []models.Message
+	if err := tx.Select("id", "role", "content").
+		Where("pdi_id = ?", pdi.ID).
+		Order("created_at ASC, id ASC").
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	resetContent := !keepContent && pdi.Content != "" && pdi.Content != emptyPDIContent
+	if len(messages) == 0 && !resetContent {
+		return nil, ErrConversationEmpty
+	}
+
+	archive := &models.ConversationArchive{
+		PDIID:                  pdi.ID,
+		ThreadID:               pdi.ThreadID,
+		ActiveBranchID:         pdi.ActiveBranchID,
+		ContextSummary:         pdi.ContextSummary,
+		ContextSummarizedUntil: pdi.ContextSummarizedUntil,
+		MessageCount:           len(messages),
+	}
+	for _, msg := range messages {
+		if msg.Role == "user" {
+			archive.FirstMessage = msg.Content
+			break
+		}
+	}
+	if resetContent {
+		content := pdi.Content
+		archive.Content = &content
+		archive.ContentReset = true
+	}
+	if err := tx.Create(archive).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(&models.Message{}).
+		Where("pdi_id = ?", pdi.ID).
+		Updates(map[string]interface{}{"archive_id": archive.ID, "deleted_at": time.Now()}).Error; err != nil {
+		return nil, err
+	}
+
+	if err := activateBranch(tx, pdi, models.MainBranchID(pdi.ID)); err != nil {
+		return nil, err
+	}
+	if resetContent {
+		if err := deleteGoals(tx, pdi.ID); err != nil {
+			return nil, err
+		}
+		source := RevisionSource{Author: models.RevisionAuthorUser, Reason: RevisionReasonConversationReset}
+		if err := recordRevision(tx, pdi, emptyPDIContent, source); err != nil {
+			return nil, err
+		}
+	}
+	return archive, nil
+}
+
+// restoreGoals refaz os objetivos do PDI a partir do conteúdo guardado no
+// arquivo.
+func restoreGoals(tx *gorm.DB, pdiID, data string) error {
+	content, err := decodeContent(data)
+	if err != nil {
+		return err
+	}
+	if err := deleteGoals(tx, pdiID); err != nil {
+		return err
+	}
+	return createGoals(tx, pdiID, content.Goals, nil)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+func TestChatService_ResetConversation(t *testing.T) {
+	db, _, pdi := setupChatTestDB(t)
+	chatService := NewChatService(db)
+
+	if _, err := chatService.ResetConversation(pdi, true); !errors.Is(err, ErrConversationEmpty) {
+		t.Errorf("ResetConversation() sem mensagens error = %v, esperado ErrConversationEmpty", err)
+	}
+
+	db.Model(pdi).Update("thread_id", "thread-antiga")
+	pdi.ThreadID = "thread-antiga"
+	if err := NewGoalService(db).SavePlan(pdi, []byte(`{"goals":[`+testGoal("Liderar o squad")+`],"self_assessment_questions":[]}`), userEdit); err != nil {
+		t.Fatalf("SavePlan() error = %v", err)
+	}
+	content := pdi.Content
+	question, _ := chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Role: "user", Content: "Quero liderar", Status: models.MessageStatusPending})
+
+	if _, err := chatService.ResetConversation(pdi, true); !errors.Is(err, ErrConversationBusy) {
+		t.Errorf("ResetConversation() com mensagem pendente error = %v, esperado ErrConversationBusy", err)
+	}
+	chatService.UpdateMessageStatus(question.ID.String(), models.MessageStatusCompleted)
+	chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Role: "assistant", Content: "Vamos planejar", Status: models.MessageStatusCompleted, ParentID: &question.ID})
+
+	archive, err := chatService.ResetConversation(pdi, false)
+	if err != nil {
+		t.Fatalf("ResetConversation() error = %v", err)
+	}
+	if archive.MessageCount != 2 || archive.FirstMessage != "Quero liderar" || archive.ThreadID != "thread-antiga" || !archive.ContentReset {
+		t.Errorf("Arquivo = %+v", archive)
+	}
+
+	var stored models.PDI
+	db.First(&stored, "id = ?", pdi.ID)
+	if stored.ThreadID != "" || stored.Content != emptyPDIContent {
+		t.Errorf("PDI após reinício: thread %q, conteúdo %q", stored.ThreadID, stored.Content)
+	}
+	var goals int64
+	db.Model(&models.Goal{}).Where("pdi_id = ?", pdi.ID).Count(&goals)
+	if goals != 0 {
+		t.Errorf("Objetivos após reinício = %d, esperado 0", goals)
+	}
+	if messages, _ := chatService.GetBranchMessages(pdi.ID, pdi.BranchID()); len(messages) != 0 {
+		t.Errorf("Mensagens após reinício = %d, esperado 0", len(messages))
+	}
+
+	// A conversa nova é arquivada ao restaurar a antiga
+	chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Role: "user", Content: "Recomeçando", Status: models.MessageStatusCompleted})
+
+	_, archived, err := chatService.GetArchive(pdi.ID, archive.ID.String())
+	if err != nil || len(archived) != 2 || archived[0].ID != question.ID {
+		t.Fatalf("GetArchive() = %d mensagens, error = %v", len(archived), err)
+	}
+
+	current, err := chatService.RestoreArchive(pdi, archive.ID.String())
+	if err != nil {
+		t.Fatalf("RestoreArchive() error = %v", err)
+	}
+	if current == nil || current.FirstMessage != "Recomeçando" || current.ContentReset {
+		t.Errorf("Conversa atual arquivada = %+v", current)
+	}
+
+	db.First(&stored, "id = ?", pdi.ID)
+	if stored.ThreadID != "thread-antiga" || stored.Content != content {
+		t.Errorf("PDI restaurado: thread %q, conteúdo %q", stored.ThreadID, stored.Content)
+	}
+	if restoredGoals, _ := NewGoalService(db).ListGoals(&stored); len(restoredGoals) != 1 || restoredGoals[0].Description != "Liderar o squad" {
+		t.Errorf("Objetivos restaurados = %+v", restoredGoals)
+	}
+	revisions, _ := NewRevisionService(db, NewGoalService(db)).ListRevisions(pdi.ID)
+	if len(revisions) != 3 || revisions[1].Reason != RevisionReasonConversationReset || revisions[0].Reason != RevisionReasonArchiveRestore {
+		t.Errorf("Revisões após reinício e restauração = %+v", revisions)
+	}
+	messages, _ := chatService.GetBranchMessages(pdi.ID, pdi.BranchID())
+	if len(messages) != 2 || messages[0].ID != question.ID {
+		t.Errorf("Mensagens restauradas = %d", len(messages))
+	}
+
+	archives, _ := chatService.ListArchives(pdi.ID)
+	if len(archives) != 1 || archives[0].ID != current.ID {
+		t.Errorf("Arquivos = %+v", archives)
+	}
+	if _, err := chatService.RestoreArchive(pdi, archive.ID.String()); !errors.Is(err, ErrArchiveNotFound) {
+		t.Errorf("RestoreArchive() repetido error = %v, esperado ErrArchiveNotFound", err)
+	}
+}
+
+func TestChatService_ResetConversationUsesLockedPDI(t *testing.T) {
+	db, _, pdi := setupChatTestDB(t)
+	chatService := NewChatService(db)
+	chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Role: "user", Content: "Quero liderar", Status: models.MessageStatusCompleted})
+
+	// A cópia do chamador é anterior à thread e ao plano salvos por outra
+	// requisição
+	stale := *pdi
+	db.Model(pdi).Update("thread_id", "thread-nova")
+	if err := NewGoalService(db).SavePlan(pdi, []byte(`{"goals":[`+testGoal("Liderar o squad")+`],"self_assessment_questions":[]}`), userEdit); err != nil {
+		t.Fatalf("SavePlan() error = %v", err)
+	}
+
+	archive, err := chatService.ResetConversation(&stale, false)
+	if err != nil {
+		t.Fatalf("ResetConversation() error = %v", err)
+	}
+	if archive.ThreadID != "thread-nova" || !archive.ContentReset || archive.Content == nil || *archive.Content != pdi.Content {
+		t.Errorf("Arquivo = %+v, esperado a thread e o conteúdo gravados", archive)
+	}
+	if stale.ThreadID != "" || stale.Content != emptyPDIContent {
+		t.Errorf("PDI do chamador após reinício: thread %q, conteúdo %q", stale.ThreadID, stale.Content)
+	}
+}
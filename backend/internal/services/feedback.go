@@ -0,0 +1,162 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"meu-pdi-estrategico/backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxFeedbackComment = 2000
+
+var (
+	ErrInvalidFeedback    = errors.New("avaliação inválida")
+	ErrFeedbackNotAllowed = errors.New("apenas respostas do assistente podem ser avaliadas")
+)
+
+type FeedbackRequest struct {
+	Rating  models.FeedbackRating `json:"rating"`
+	Comment string                `json:"comment"`
+}
+
+// FeedbackExportFilter limita a exportação; campos vazios não limitam.
+type FeedbackExportFilter struct {
+	Period UsagePeriod
+	Rating models.FeedbackRating
+}
+
+// FeedbackRecord é uma linha da exportação: a avaliação com a mensagem do
+// usuário, a resposta avaliada e a versão de persona que a gerou.
+type FeedbackRecord struct {
+	FeedbackID     uuid.UUID             `json:"feedback_id"`
+	Rating         models.FeedbackRating `json:"rating"`
+	Comment        string                `json:"comment,omitempty"`
+	RatedAt        time.Time             `json:"rated_at"`
+	PDIID          string                `json:"pdi_id"`
+	PromptID       *uuid.UUID            `json:"prompt_id,omitempty"`
+	Prompt         string                `json:"prompt"`
+	ReplyID        uuid.UUID             `json:"reply_id"`
+	Reply          string                `json:"reply"`
+	Model          string                `json:"model,omitempty"`
+	PersonaSlug    string                `json:"persona_slug,omitempty"`
+	PersonaVersion int                   `json:"persona_version,omitempty"`
+}
+
+type FeedbackService struct {
+	db      *gorm.DB
+	filters *FilterPipeline
+}
+
+func NewFeedbackService(db *gorm.DB) *FeedbackService {
+	return &FeedbackService{db: db}
+}
+
+// SetFilters ativa a remoção de dados pessoais dos textos exportados.
+func (s *FeedbackService) SetFilters(filters *FilterPipeline) {
+	s.filters = filters
+}
+
+// SaveFeedback grava a avaliação do usuário para a resposta. Uma nova
+// avaliação da mesma resposta substitui a anterior; devolve true quando a
+// avaliação é nova.
+func (s *FeedbackService) SaveFeedback(message *models.Message, userID string, req FeedbackRequest) (*models.MessageFeedback, bool, error) {
+	if message.Role != "assistant" || message.Status != models.MessageStatusCompleted {
+		return nil, false, ErrFeedbackNotAllowed
+	}
+	if req.Rating != models.FeedbackUp && req.Rating != models.FeedbackDown {
+		return nil, false, fmt.Errorf("%w: a nota deve ser up ou down", ErrInvalidFeedback)
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if len([]rune(comment)) > maxFeedbackComment {
+		return nil, false, fmt.Errorf("%w: o comentário deve ter no máximo %d caracteres", ErrInvalidFeedback, maxFeedbackComment)
+	}
+
+	user, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: usuário inválido", ErrInvalidFeedback)
+	}
+
+	var feedback models.MessageFeedback
+	err = s.db.Where("message_id = ? AND user_id = ?", message.ID, user).First(&feedback).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("erro ao buscar avaliação: %v", err)
+	}
+	created := errors.Is(err, gorm.ErrRecordNotFound)
+
+	feedback.MessageID = message.ID
+	feedback.UserID = user
+	feedback.Rating = req.Rating
+	feedback.Comment = comment
+	if err := s.db.Save(&feedback).Error; err != nil {
+		return nil, false, fmt.Errorf("erro ao salvar avaliação: %v", err)
+	}
+
+	return &feedback, created, nil
+}
+
+// Export escreve em w as avaliações no formato JSONL, uma por linha, das mais
+// antigas às mais recentes, à medida que são lidas do banco. Respostas
+// substituídas por regeneração também são exportadas. Os dados pessoais da
+// mensagem, da resposta e do comentário saem trocados por marcadores.
+// Devolve quantas linhas foram escritas.
+func (s *FeedbackService) Export(w io.Writer, filter FeedbackExportFilter) (int, error) {
+	query := s.db.Table("message_feedbacks").
+		Select(`message_feedbacks.id AS feedback_id,
+			message_feedbacks.rating,
+			message_feedbacks.comment,
+			message_feedbacks.created_at AS rated_at,
+			replies.pdi_id,
+			prompts.id AS prompt_id,
+			COALESCE(prompts.content, '') AS prompt,
+			replies.id AS reply_id,
+			replies.content AS reply,
+			COALESCE(replies.model, '') AS model,
+			COALESCE(personas.slug, '') AS persona_slug,
+			COALESCE(personas.version, 0) AS persona_version`).
+		Joins("JOIN messages AS replies ON replies.id = message_feedbacks.message_id").
+		Joins("LEFT JOIN messages AS prompts ON prompts.id = replies.parent_id").
+		Joins("LEFT JOIN personas ON personas.id = replies.persona_id").
+		Order("message_feedbacks.created_at ASC")
+	if !filter.Period.From.IsZero() {
+		query = query.Where("message_feedbacks.created_at >= ?", filter.Period.From)
+	}
+	if !filter.Period.To.IsZero() {
+		query = query.Where("message_feedbacks.created_at < ?", filter.Period.To)
+	}
+	if filter.Rating != "" {
+		query = query.Where("message_feedbacks.rating = ?", filter.Rating)
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		return 0, fmt.Errorf("erro ao buscar avaliações: %v", err)
+	}
+	defer rows.Close()
+
+	encoder := json.NewEncoder(w)
+	count := 0
+	for rows.Next() {
+		var record FeedbackRecord
+		if err := s.db.ScanRows(rows, &record); err != nil {
+			return count, fmt.Errorf("erro ao ler avaliação: %v", err)
+		}
+		record.Prompt = s.filters.Mask(record.Prompt)
+		record.Reply = s.filters.Mask(record.Reply)
+		record.Comment = s.filters.Mask(record.Comment)
+		if err := encoder.Encode(record); err != nil {
+			return count, fmt.Errorf("erro ao escrever avaliação: %v", err)
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, fmt.Errorf("erro ao buscar avaliações: %v", err)
+	}
+	return count, nil
+}
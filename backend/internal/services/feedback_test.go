@@ -0,0 +1,61 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+func TestFeedbackService_SaveAndExport(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	chatService := NewChatService(db)
+	service := NewFeedbackService(db)
+
+	persona := createPersona(t, NewPersonaService(db, "career-coach"), CreatePersonaRequest{Slug: "career-coach", Name: "Coach", Instructions: "v1"})
+	prompt, _ := chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Role: "user", Content: "Como peço promoção? Meu e-mail é ana@empresa.com", Status: models.MessageStatusCompleted})
+	reply, _ := chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Role: "assistant", Content: "Mostre seus resultados.", Status: models.MessageStatusCompleted, ParentID: &prompt.ID, Model: "gpt-4o-mini", PersonaID: &persona.ID})
+
+	if _, _, err := service.SaveFeedback(prompt, user.ID.String(), FeedbackRequest{Rating: models.FeedbackUp}); !errors.Is(err, ErrFeedbackNotAllowed) {
+		t.Errorf("SaveFeedback() em mensagem do usuário error = %v, esperado ErrFeedbackNotAllowed", err)
+	}
+	if _, _, err := service.SaveFeedback(reply, user.ID.String(), FeedbackRequest{Rating: "meh"}); !errors.Is(err, ErrInvalidFeedback) {
+		t.Errorf("SaveFeedback() com nota inválida error = %v, esperado ErrInvalidFeedback", err)
+	}
+
+	first, created, err := service.SaveFeedback(reply, user.ID.String(), FeedbackRequest{Rating: models.FeedbackDown})
+	if err != nil || !created {
+		t.Fatalf("SaveFeedback() = %v, %v", created, err)
+	}
+	// Avaliar de novo substitui a avaliação anterior
+	second, created, err := service.SaveFeedback(reply, user.ID.String(), FeedbackRequest{Rating: models.FeedbackUp, Comment: " Muito útil "})
+	if err != nil || created || second.ID != first.ID {
+		t.Fatalf("SaveFeedback() = %+v, %v, %v", second, created, err)
+	}
+
+	service.SetFilters(defaultTestPipeline(t))
+	var out bytes.Buffer
+	count, err := service.Export(&out, FeedbackExportFilter{})
+	if err != nil || count != 1 {
+		t.Fatalf("Export() = %d, %v", count, err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var record FeedbackRecord
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Linha inválida %q: %v", lines[0], err)
+	}
+	if record.Rating != models.FeedbackUp || record.Comment != "Muito útil" || record.Prompt != "Como peço promoção? Meu e-mail é [EMAIL]" || record.Reply != reply.Content {
+		t.Errorf("Registro exportado = %+v", record)
+	}
+	if record.PersonaSlug != "career-coach" || record.PersonaVersion != 1 || record.Model != "gpt-4o-mini" || record.PDIID != pdi.ID {
+		t.Errorf("Registro exportado = %+v", record)
+	}
+
+	out.Reset()
+	if count, _ := service.Export(&out, FeedbackExportFilter{Rating: models.FeedbackDown}); count != 0 {
+		t.Errorf("Export() com filtro down = %d linhas", count)
+	}
+}
@@ -0,0 +1,495 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"meu-pdi-estrategico/backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrKeyResultNotFound  = errors.New("resultado-chave não encontrado")
+	ErrActionItemNotFound = errors.New("ação não encontrada")
+)
+
+// PDIContent é o conteúdo do PDI no formato do schema versionado. As tabelas
+// goals, key_results, action_items e skills guardam os objetivos; o conteúdo
+// em pdis.content é gerado a partir delas a cada alteração.
+type PDIContent struct {
+	SchemaVersion           int              `json:"schema_version"`
+	Goals                   []PDIContentGoal `json:"goals"`
+	SelfAssessmentQuestions []string         `json:"self_assessment_questions"`
+}
+
+type PDIContentGoal struct {
+	Description  string           `json:"description"`
+	Skills       PDIContentSkills `json:"skills"`
+	Alignment    string           `json:"alignment"`
+	ActionPlan   []string         `json:"action_plan"`
+	KeyResults   []string         `json:"key_results"`
+	Progress     float64          `json:"progress"`
+	ProgressNote string           `json:"progress_note,omitempty"`
+}
+
+type PDIContentSkills struct {
+	HardSkills []string `json:"hard_skills"`
+	SoftSkills []string `json:"soft_skills"`
+}
+
+// GoalUpdate altera um objetivo; campos nulos não mudam.
+type GoalUpdate struct {
+	Description  *string  `json:"description"`
+	Alignment    *string  `json:"alignment"`
+	Progress     *float64 `json:"progress"`
+	ProgressNote *string  `json:"progress_note"`
+}
+
+type KeyResultUpdate struct {
+	Description *string `json:"description"`
+	Done        *bool   `json:"done"`
+}
+
+type ActionItemUpdate struct {
+	Description *string `json:"description"`
+	Done        *bool   `json:"done"`
+}
+
+type GoalService struct {
+	db *gorm.DB
+}
+
+func NewGoalService(db *gorm.DB) *GoalService {
+	return &GoalService{db: db}
+}
+
+// SavePlan valida o conteúdo e substitui os objetivos do PDI pelos dele. As
+// ações e os resultados-chave que continuam no mesmo objetivo, com a mesma
+// descrição, mantêm o estado de conclusão. source identifica a revisão gerada.
+func (s *GoalService) SavePlan(pdi *models.PDI, data []byte, source RevisionSource) error {
+	validated, err := ValidatePDIContent(data)
+	if err != nil {
+		return err
+	}
+	var content PDIContent
+	if err := json.Unmarshal(validated, &content); err != nil {
+		return &PDIValidationError{Errors: []string{fmt.Sprintf("JSON inválido: %v", err)}}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockPDI(tx, pdi.ID); err != nil {
+			return err
+		}
+		previous, err := loadGoals(tx, pdi.ID)
+		if err != nil {
+			return err
+		}
+		if err := deleteGoals(tx, pdi.ID); err != nil {
+			return err
+		}
+		if err := createGoals(tx, pdi.ID, content.Goals, previous); err != nil {
+			return err
+		}
+		return syncContent(tx, pdi, content.SelfAssessmentQuestions, source)
+	})
+	if err != nil {
+		var validation *PDIValidationError
+		if errors.As(err, &validation) {
+			return err
+		}
+		return fmt.Errorf("erro ao salvar objetivos do PDI: %v", err)
+	}
+	return nil
+}
+
+// ListGoals devolve os objetivos do PDI, na ordem do plano, com resultados-
+// chave, ações e competências.
+func (s *GoalService) ListGoals(pdi *models.PDI) ([]models.Goal, error) {
+	if err := s.ensurePlan(pdi); err != nil {
+		return nil, err
+	}
+	goals, err := loadGoals(s.db, pdi.ID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar objetivos: %v", err)
+	}
+	return goals, nil
+}
+
+// UpdateGoal altera um objetivo do PDI e atualiza o conteúdo do PDI.
+func (s *GoalService) UpdateGoal(pdi *models.PDI, goalID string, req GoalUpdate, source RevisionSource) (*models.Goal, error) {
+	if err := s.ensurePlan(pdi); err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(goalID); err != nil {
+		return nil, ErrGoalNotFound
+	}
+	return s.updateGoal(pdi, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("id = ? AND pdi_id = ?", goalID, pdi.ID)
+	}, req, source)
+}
+
+// UpdateGoalAt altera o objetivo pela sua posição no plano, como fazem as
+// ferramentas do assistente.
+func (s *GoalService) UpdateGoalAt(pdi *models.PDI, position int, req GoalUpdate, source RevisionSource) (*models.Goal, error) {
+	if err := s.ensurePlan(pdi); err != nil {
+		return nil, err
+	}
+	return s.updateGoal(pdi, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("pdi_id = ? AND position = ?", pdi.ID, position)
+	}, req, source)
+}
+
+func (s *GoalService) updateGoal(pdi *models.PDI, find func(tx *gorm.DB) *gorm.DB, req GoalUpdate, source RevisionSource) (*models.Goal, error) {
+	var goal models.Goal
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockPDI(tx, pdi.ID); err != nil {
+			return err
+		}
+		if err := find(tx).First(&goal).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGoalNotFound
+			}
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if req.Description != nil {
+			updates["description"] = strings.TrimSpace(*req.Description)
+		}
+		if req.Alignment != nil {
+			updates["alignment"] = *req.Alignment
+		}
+		if req.Progress != nil {
+			updates["progress"] = *req.Progress
+		}
+		if req.ProgressNote != nil {
+			updates["progress_note"] = *req.ProgressNote
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&goal).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return syncContent(tx, pdi, nil, source)
+	})
+	if err != nil {
+		return nil, goalError(err)
+	}
+	return &goal, nil
+}
+
+// UpdateKeyResult altera um resultado-chave de um objetivo do PDI. Ao marcar
+// o resultado como atingido, DoneAt registra o momento.
+func (s *GoalService) UpdateKeyResult(pdi *models.PDI, keyResultID string, req KeyResultUpdate, source RevisionSource) (*models.KeyResult, error) {
+	if err := s.ensurePlan(pdi); err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(keyResultID); err != nil {
+		return nil, ErrKeyResultNotFound
+	}
+
+	var keyResult models.KeyResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockPDI(tx, pdi.ID); err != nil {
+			return err
+		}
+		if err := tx.Joins("JOIN goals ON goals.id = key_results.goal_id").
+			Where("key_results.id = ? AND goals.pdi_id = ?", keyResultID, pdi.ID).
+			First(&keyResult).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrKeyResultNotFound
+			}
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if req.Description != nil {
+			updates["description"] = strings.TrimSpace(*req.Description)
+		}
+		if req.Done != nil && *req.Done != keyResult.Done {
+			updates["done"] = *req.Done
+			updates["done_at"] = doneAt(*req.Done)
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&keyResult).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return syncContent(tx, pdi, nil, source)
+	})
+	if err != nil {
+		return nil, goalError(err)
+	}
+	return &keyResult, nil
+}
+
+// UpdateActionItem altera uma ação de um objetivo do PDI. Ao marcar a ação
+// como concluída, DoneAt registra o momento.
+func (s *GoalService) UpdateActionItem(pdi *models.PDI, actionItemID string, req ActionItemUpdate, source RevisionSource) (*models.ActionItem, error) {
+	if err := s.ensurePlan(pdi); err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(actionItemID); err != nil {
+		return nil, ErrActionItemNotFound
+	}
+
+	var item models.ActionItem
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockPDI(tx, pdi.ID); err != nil {
+			return err
+		}
+		if err := tx.Joins("JOIN goals ON goals.id = action_items.goal_id").
+			Where("action_items.id = ? AND goals.pdi_id = ?", actionItemID, pdi.ID).
+			First(&item).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrActionItemNotFound
+			}
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if req.Description != nil {
+			updates["description"] = strings.TrimSpace(*req.Description)
+		}
+		if req.Done != nil && *req.Done != item.Done {
+			updates["done"] = *req.Done
+			updates["done_at"] = doneAt(*req.Done)
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&item).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return syncContent(tx, pdi, nil, source)
+	})
+	if err != nil {
+		return nil, goalError(err)
+	}
+	return &item, nil
+}
+
+// ensurePlan importa os objetivos do conteúdo quando o PDI ainda não os tem
+// nas tabelas, como faz a migração para os PDIs existentes.
+func (s *GoalService) ensurePlan(pdi *models.PDI) error {
+	var count int64
+	if err := s.db.Model(&models.Goal{}).Where("pdi_id = ?", pdi.ID).Count(&count).Error; err != nil {
+		return fmt.Errorf("erro ao buscar objetivos: %v", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	// Duas primeiras leituras simultâneas chegam aqui juntas: a contagem é
+	// refeita com o PDI travado para que só uma delas importe os objetivos
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockPDI(tx, pdi.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Goal{}).Where("pdi_id = ?", pdi.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		content, err := decodeContent(locked.Content)
+		if err != nil || len(content.Goals) == 0 {
+			return err
+		}
+		return createGoals(tx, pdi.ID, content.Goals, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao importar objetivos do PDI: %v", err)
+	}
+	return nil
+}
+
+// doneAt é o valor de done_at ao marcar ou desmarcar um item como concluído.
+func doneAt(done bool) *time.Time {
+	if !done {
+		return nil
+	}
+	now := time.Now()
+	return &now
+}
+
+func goalError(err error) error {
+	var validation *PDIValidationError
+	if errors.As(err, &validation) || errors.Is(err, ErrGoalNotFound) ||
+		errors.Is(err, ErrKeyResultNotFound) || errors.Is(err, ErrActionItemNotFound) {
+		return err
+	}
+	return fmt.Errorf("erro ao atualizar objetivo: %v", err)
+}
+
+func decodeContent(data string) (*PDIContent, error) {
+	content := &PDIContent{}
+	if data == "" {
+		return content, nil
+	}
+	if err := json.Unmarshal([]byte(data), content); err != nil {
+		return nil, fmt.Errorf("conteúdo do PDI inválido: %v", err)
+	}
+	return content, nil
+}
+
+// lockPDI trava a linha do PDI até o fim da transação e devolve o PDI lido com
+// a trava. Toda alteração dos objetivos começa por ela, de modo que quem
+// confere os objetivos com o PDI travado, como as transições de status, não vê
+// o plano mudar no meio.
+func lockPDI(tx *gorm.DB, pdiID string) (*models.PDI, error) {
+	var pdi models.PDI
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", pdiID).
+		Take(&pdi).Error; err != nil {
+		return nil, err
+	}
+	return &pdi, nil
+}
+
+func loadGoals(tx *gorm.DB, pdiID string) ([]models.Goal, error) {
+	goals := []models.Goal{}
+	err := tx.Where("pdi_id = ?", pdiID).
+		Order("position ASC").
+		Preload("KeyResults", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Preload("ActionItems", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Preload("Skills", func(db *gorm.DB) *gorm.DB { return db.Order("kind ASC, position ASC") }).
+		Find(&goals).Error
+	return goals, err
+}
+
+func deleteGoals(tx *gorm.DB, pdiID string) error {
+	goalIDs := tx.Model(&models.Goal{}).Select("id").Where("pdi_id = ?", pdiID)
+	for _, model := range []interface{}{&models.KeyResult{}, &models.ActionItem{}, &models.Skill{}} {
+		if err := tx.Where("goal_id IN (?)", goalIDs).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Where("pdi_id = ?", pdiID).Delete(&models.Goal{}).Error
+}
+
+// createGoals grava os objetivos do conteúdo. previous são os objetivos
+// substituídos, de onde vem o estado das ações e dos resultados-chave
+// mantidos.
+func createGoals(tx *gorm.DB, pdiID string, goals []PDIContentGoal, previous []models.Goal) error {
+	for i, item := range goals {
+		done := map[string]models.ActionItem{}
+		achieved := map[string]models.KeyResult{}
+		if i < len(previous) {
+			for _, action := range previous[i].ActionItems {
+				done[action.Description] = action
+			}
+			for _, keyResult := range previous[i].KeyResults {
+				achieved[keyResult.Description] = keyResult
+			}
+		}
+
+		goal := models.Goal{
+			PDIID:        pdiID,
+			Position:     i,
+			Description:  item.Description,
+			Alignment:    item.Alignment,
+			Progress:     item.Progress,
+			ProgressNote: item.ProgressNote,
+		}
+		for j, description := range item.KeyResults {
+			keyResult := models.KeyResult{Position: j, Description: description}
+			if kept, ok := achieved[description]; ok {
+				keyResult.Done, keyResult.DoneAt = kept.Done, kept.DoneAt
+			}
+			goal.KeyResults = append(goal.KeyResults, keyResult)
+		}
+		for j, description := range item.ActionPlan {
+			action := models.ActionItem{Position: j, Description: description}
+			if kept, ok := done[description]; ok {
+				action.Done, action.DoneAt = kept.Done, kept.DoneAt
+			}
+			goal.ActionItems = append(goal.ActionItems, action)
+		}
+		for j, name := range item.Skills.HardSkills {
+			goal.Skills = append(goal.Skills, models.Skill{Kind: models.SkillHard, Position: j, Name: name})
+		}
+		for j, name := range item.Skills.SoftSkills {
+			goal.Skills = append(goal.Skills, models.Skill{Kind: models.SkillSoft, Position: j, Name: name})
+		}
+
+		if err := tx.Create(&goal).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncContent gera o conteúdo do PDI a partir das tabelas e o grava com
+// recordRevision. Sem perguntas informadas, mantém as do
+// conteúdo atual.
+func syncContent(tx *gorm.DB, pdi *models.PDI, questions []string, source RevisionSource) error {
+	// As perguntas mantidas vêm do conteúdo lido com a trava, não da cópia do
+	// chamador, que pode ser anterior a outra alteração simultânea
+	locked, err := lockPDI(tx, pdi.ID)
+	if err != nil {
+		return err
+	}
+	if questions == nil {
+		current, err := decodeContent(locked.Content)
+		if err != nil {
+			return err
+		}
+		questions = current.SelfAssessmentQuestions
+	}
+
+	goals, err := loadGoals(tx, pdi.ID)
+	if err != nil {
+		return err
+	}
+	content := PDIContent{
+		SchemaVersion:           PDIContentSchemaVersion,
+		Goals:                   make([]PDIContentGoal, len(goals)),
+		SelfAssessmentQuestions: questions,
+	}
+	if content.SelfAssessmentQuestions == nil {
+		content.SelfAssessmentQuestions = []string{}
+	}
+	for i, goal := range goals {
+		item := PDIContentGoal{
+			Description:  goal.Description,
+			Alignment:    goal.Alignment,
+			Progress:     goal.Progress,
+			ProgressNote: goal.ProgressNote,
+			ActionPlan:   []string{},
+			KeyResults:   []string{},
+			Skills:       PDIContentSkills{HardSkills: []string{}, SoftSkills: []string{}},
+		}
+		for _, keyResult := range goal.KeyResults {
+			item.KeyResults = append(item.KeyResults, keyResult.Description)
+		}
+		for _, action := range goal.ActionItems {
+			item.ActionPlan = append(item.ActionPlan, action.Description)
+		}
+		for _, skill := range goal.Skills {
+			if skill.Kind == models.SkillHard {
+				item.Skills.HardSkills = append(item.Skills.HardSkills, skill.Name)
+			} else {
+				item.Skills.SoftSkills = append(item.Skills.SoftSkills, skill.Name)
+			}
+		}
+		content.Goals[i] = item
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	// O conteúdo gerado passa pela mesma validação do save_pdi
+	if data, err = ValidatePDIContent(data); err != nil {
+		return err
+	}
+	return recordRevision(tx, pdi, string(data), source)
+}
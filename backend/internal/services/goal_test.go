@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+func TestGoalService_SavePlan(t *testing.T) {
+	db, _, pdi := setupChatTestDB(t)
+	service := NewGoalService(db)
+
+	plan := `{"goals":[` + planGoal("Aprender Go") + `,` + planGoal("Liderar um projeto") + `],"self_assessment_questions":["Como foi o trimestre?"]}`
+	if err := service.SavePlan(pdi, []byte(plan), userEdit); err != nil {
+		t.Fatalf("SavePlan() error = %v", err)
+	}
+
+	goals, err := service.ListGoals(pdi)
+	if err != nil {
+		t.Fatalf("ListGoals() error = %v", err)
+	}
+	if len(goals) != 2 || goals[1].Description != "Liderar um projeto" || goals[1].Position != 1 {
+		t.Fatalf("Objetivos = %+v", goals)
+	}
+	goal := goals[0]
+	if len(goal.KeyResults) != 1 || len(goal.ActionItems) != 1 || len(goal.Skills) != 2 || goal.Skills[0].Kind != models.SkillHard || goal.Skills[0].Name != "Go" {
+		t.Fatalf("Itens do objetivo = %+v", goal)
+	}
+
+	// A ação concluída continua concluída quando o plano é salvo de novo
+	done := true
+	item, err := service.UpdateActionItem(pdi, goal.ActionItems[0].ID.String(), ActionItemUpdate{Done: &done}, userEdit)
+	if err != nil || !item.Done || item.DoneAt == nil {
+		t.Fatalf("UpdateActionItem() = %+v, error = %v", item, err)
+	}
+	if err := service.SavePlan(pdi, []byte(plan), userEdit); err != nil {
+		t.Fatalf("SavePlan() error = %v", err)
+	}
+	goals, _ = service.ListGoals(pdi)
+	if !goals[0].ActionItems[0].Done || goals[1].ActionItems[0].Done {
+		t.Errorf("Estado das ações após salvar o plano = %+v", goals)
+	}
+
+	progress := 60.0
+	description := "Aprender Go a fundo"
+	if _, err := service.UpdateGoal(pdi, goals[0].ID.String(), GoalUpdate{Description: &description, Progress: &progress}, userEdit); err != nil {
+		t.Fatalf("UpdateGoal() error = %v", err)
+	}
+	var stored models.PDI
+	db.First(&stored, "id = ?", pdi.ID)
+	if !strings.Contains(stored.Content, `"description":"Aprender Go a fundo"`) || !strings.Contains(stored.Content, `"progress":60`) || !strings.Contains(stored.Content, "Como foi o trimestre?") {
+		t.Errorf("Conteúdo do PDI não atualizado: %v", stored.Content)
+	}
+
+	empty := " "
+	var validation *PDIValidationError
+	if _, err := service.UpdateGoal(pdi, goals[0].ID.String(), GoalUpdate{Description: &empty}, userEdit); !errors.As(err, &validation) {
+		t.Errorf("UpdateGoal() com descrição vazia error = %v, esperado PDIValidationError", err)
+	}
+	goals, _ = service.ListGoals(pdi)
+	if goals[0].Description != "Aprender Go a fundo" {
+		t.Errorf("Alteração inválida não deveria ser salva: %v", goals[0].Description)
+	}
+
+	// Itens de outro PDI não são encontrados
+	other, _ := NewPDIService(db).CreatePDI(pdi.UserID, CreatePDIRequest{Name: "Outro PDI"})
+	if _, err := service.UpdateKeyResult(other, goals[0].KeyResults[0].ID.String(), KeyResultUpdate{Description: &description}, userEdit); !errors.Is(err, ErrKeyResultNotFound) {
+		t.Errorf("UpdateKeyResult() em outro PDI error = %v, esperado ErrKeyResultNotFound", err)
+	}
+}
+
+func TestGoalService_ImportsLegacyContent(t *testing.T) {
+	db, _, pdi := setupChatTestDB(t)
+
+	// PDIs salvos antes das tabelas só têm o conteúdo
+	content := `{"goals":[` + planGoal("Falar em público") + `],"self_assessment_questions":[]}`
+	db.Model(pdi).Update("content", content)
+	pdi.Content = content
+
+	goals, err := NewGoalService(db).ListGoals(pdi)
+	if err != nil {
+		t.Fatalf("ListGoals() error = %v", err)
+	}
+	if len(goals) != 1 || goals[0].Description != "Falar em público" || len(goals[0].ActionItems) != 1 {
+		t.Errorf("Objetivos importados = %+v", goals)
+	}
+}
+
+func TestGoalService_SavePlanKeepsAchievedKeyResults(t *testing.T) {
+	db, _, pdi := setupChatTestDB(t)
+	service := NewGoalService(db)
+
+	plan := []byte(`{"goals":[` + planGoal("Aprender Go") + `],"self_assessment_questions":[]}`)
+	if err := service.SavePlan(pdi, plan, userEdit); err != nil {
+		t.Fatalf("SavePlan() error = %v", err)
+	}
+	goals, _ := service.ListGoals(pdi)
+	done := true
+	if _, err := service.UpdateKeyResult(pdi, goals[0].KeyResults[0].ID.String(), KeyResultUpdate{Done: &done}, userEdit); err != nil {
+		t.Fatalf("UpdateKeyResult() error = %v", err)
+	}
+	if err := service.SavePlan(pdi, plan, userEdit); err != nil {
+		t.Fatalf("SavePlan() error = %v", err)
+	}
+
+	goals, _ = service.ListGoals(pdi)
+	if keyResult := goals[0].KeyResults[0]; !keyResult.Done || keyResult.DoneAt == nil {
+		t.Errorf("Resultado-chave após salvar o plano = %+v", keyResult)
+	}
+}
+
+func TestGoalService_UpdateGoalKeepsConcurrentQuestions(t *testing.T) {
+	db, _, pdi := setupChatTestDB(t)
+	service := NewGoalService(db)
+
+	plan := []byte(`{"goals":[` + planGoal("Aprender Go") + `],"self_assessment_questions":["Pergunta antiga"]}`)
+	if err := service.SavePlan(pdi, plan, userEdit); err != nil {
+		t.Fatalf("SavePlan() error = %v", err)
+	}
+
+	// Outra requisição salva o plano com novas perguntas enquanto esta ainda
+	// segura a cópia antiga do PDI
+	stale := *pdi
+	updated := []byte(`{"goals":[` + planGoal("Aprender Go") + `],"self_assessment_questions":["Pergunta nova"]}`)
+	if err := service.SavePlan(pdi, updated, userEdit); err != nil {
+		t.Fatalf("SavePlan() error = %v", err)
+	}
+
+	goals, _ := service.ListGoals(pdi)
+	progress := 30.0
+	if _, err := service.UpdateGoal(&stale, goals[0].ID.String(), GoalUpdate{Progress: &progress}, userEdit); err != nil {
+		t.Fatalf("UpdateGoal() error = %v", err)
+	}
+	var stored models.PDI
+	db.First(&stored, "id = ?", pdi.ID)
+	if !strings.Contains(stored.Content, "Pergunta nova") || strings.Contains(stored.Content, "Pergunta antiga") {
+		t.Errorf("Perguntas sobrescritas pela cópia antiga: %v", stored.Content)
+	}
+
+	var revision models.PDIRevision
+	db.Where("pdi_id = ?", pdi.ID).Order("number DESC").First(&revision)
+	var previous models.PDIRevision
+	db.Where("pdi_id = ? AND number = ?", pdi.ID, revision.Number-1).First(&previous)
+	if !strings.Contains(previous.Content, "Pergunta nova") {
+		t.Errorf("Revisão anterior = %v, esperado o conteúdo salvo pela outra requisição", previous.Content)
+	}
+}
+
+var userEdit = RevisionSource{Author: models.RevisionAuthorUser, Reason: RevisionReasonGoalUpdate}
+
+func planGoal(description string) string {
+	return `{"description":"` + description + `","skills":{"hard_skills":["Go"],"soft_skills":["Comunicação"]},"alignment":"Carreira","action_plan":["Estudar ` + description + `"],"key_results":["Concluir ` + description + `"]}`
+}
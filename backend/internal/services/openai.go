@@ -2,44 +2,93 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"meu-pdi-estrategico/backend/internal/models"
-	"os"
-	"time"
 
-	openai "github.com/sashabaranov/go-openai"
 	"gorm.io/gorm"
 )
 
+const (
+	StreamEventToken    = "token"
+	StreamEventToolCall = "tool_call"
+	StreamEventMessage  = "message"
+	StreamEventStatus   = "status"
+)
+
+// StreamEvent é um evento emitido enquanto a resposta do assistente é gerada.
+type StreamEvent struct {
+	Type    string               `json:"type"`
+	Content string               `json:"content,omitempty"`
+	Tool    *ToolCall            `json:"tool,omitempty"`
+	Status  models.MessageStatus `json:"status,omitempty"`
+	Error   string               `json:"error,omitempty"`
+	Message *models.Message      `json:"-"`
+}
+
+var (
+	ErrThreadsNotSupported = errors.New("o provedor configurado não usa threads")
+	ErrConversationBusy    = errors.New("há mensagens do PDI em processamento")
+)
+
+// Assistant gera a resposta do assistente para uma mensagem de um PDI.
+type Assistant interface {
+	ProcessMessage(ctx context.Context, message *models.Message, userID string) (*models.Message, error)
+	// StreamMessage funciona como ProcessMessage, repassando a onEvent os
+	// trechos da resposta e as chamadas de ferramentas.
+	StreamMessage(ctx context.Context, message *models.Message, userID string, onEvent func(StreamEvent)) (*models.Message, error)
+	// RehydrateThread recria a thread do PDI no provedor.
+	RehydrateThread(ctx context.Context, userID, pdiID string) (*RehydrateResult, error)
+}
+
+// OpenAIService conduz a conversa de um PDI com o provedor de LLM configurado.
 type OpenAIService struct {
-	client      *openai.Client
 	db          *gorm.DB
-	assistantID string
+	provider    Provider
 	pdiService  *PDIService
+	chatService *ChatService
+	tools       *ToolRegistry
+	personas    *PersonaService
+	context     *ContextManager
+	filters     *FilterPipeline
 }
 
-func NewOpenAIService(db *gorm.DB) *OpenAIService {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		panic("OPENAI_API_KEY não configurada")
-	}
-	assistantID := os.Getenv("OPENAI_ASSISTANT_ID")
-	if assistantID == "" {
-		panic("OPENAI_ASSISTANT_ID não configurada")
-	}
-
-	client := openai.NewClient(apiKey)
+// NewOpenAIService cria o serviço do chat. Com contextManager nil, o contexto
+// fica na thread do provedor (ou, sem thread, é o histórico completo).
+func NewOpenAIService(db *gorm.DB, provider Provider, contextManager *ContextManager) *OpenAIService {
 	return &OpenAIService{
-		assistantID: assistantID,
-		client:      client,
 		db:          db,
+		provider:    provider,
 		pdiService:  NewPDIService(db),
+		chatService: NewChatService(db),
+		tools:       NewDefaultToolRegistry(db),
+		personas:    NewPersonaService(db, PersonaDefaultFromEnv()),
+		context:     contextManager,
 	}
 }
 
+// SetFilters ativa a moderação e a remoção de dados pessoais do conteúdo
+// enviado ao provedor. Sem filtros, o conteúdo vai ao provedor como está.
+func (s *OpenAIService) SetFilters(filters *FilterPipeline) {
+	s.filters = filters
+}
+
+// Moderate aplica as regras de moderação ao texto de uma mensagem do usuário.
+func (s *OpenAIService) Moderate(text string) error {
+	return s.filters.Moderate(text)
+}
+
 func (s *OpenAIService) ProcessMessage(ctx context.Context, message *models.Message, userID string) (*models.Message, error) {
-	log.Printf("[OpenAI] Iniciando processamento de mensagem. UserID: %s, PDI ID: %s", userID, message.PDIID)
+	return s.StreamMessage(ctx, message, userID, nil)
+}
+
+func (s *OpenAIService) StreamMessage(ctx context.Context, message *models.Message, userID string, onEvent func(StreamEvent)) (*models.Message, error) {
+	if onEvent == nil {
+		onEvent = func(StreamEvent) {}
+	}
+
+	log.Printf("[OpenAI] Iniciando processamento de mensagem. UserID: %s, PDI ID: %s, Provedor: %s", userID, message.PDIID, s.provider.Name())
 
 	// Buscar o PDI
 	pdi, err := s.pdiService.GetPDIByID(userID, message.PDIID)
@@ -54,139 +103,142 @@ func (s *OpenAIService) ProcessMessage(ctx context.Context, message *models.Mess
 		return nil, fmt.Errorf("PDI não está ativo")
 	}
 
-	var threadID string
-	if pdi.ThreadID == "" {
-		log.Printf("[OpenAI] ThreadID não encontrado. Criando novo thread...")
-		// Criar novo thread se não existir
-		thread, err := s.client.CreateThread(ctx, openai.ThreadRequest{})
-		if err != nil {
-			log.Printf("[OpenAI] Erro ao criar thread: %v", err)
-			return nil, fmt.Errorf("erro ao criar thread: %v", err)
-		}
-		log.Printf("[OpenAI] Novo thread criado com ID: %s", thread.ID)
-
-		// Atualizar o PDI com o novo ThreadID usando Updates para evitar hooks
-    pdi.ThreadID = thread.ID
-
-		result := s.db.Model(&pdi).
-			Where("id = ?", pdi.ID).
-			Updates(&pdi)
-		
-		if result.Error != nil {
-			log.Printf("[OpenAI] Erro ao atualizar thread_id do PDI: %v", result.Error)
-			return nil, fmt.Errorf("erro ao atualizar thread_id do PDI: %v", result.Error)
-		}
+	// Uma resposta existente indica que ela está sendo regenerada
+	previous, err := s.chatService.GetReply(message.ID)
+	if err != nil && !errors.Is(err, ErrMessageNotFound) {
+		return nil, err
+	}
 
-		if result.RowsAffected == 0 {
-			log.Printf("[OpenAI] Nenhum PDI foi atualizado")
-			return nil, fmt.Errorf("PDI não encontrado para atualização")
-		}
+	persona, err := s.personas.Resolve(pdi)
+	if err != nil {
+		return nil, err
+	}
 
-		log.Printf("[OpenAI] PDI atualizado com novo ThreadID")
-		threadID = thread.ID
-	} else {
-		threadID = pdi.ThreadID
-		log.Printf("[OpenAI] Usando thread existente: %s", threadID)
+	tools := s.tools
+	if persona != nil {
+		log.Printf("[OpenAI] Usando persona %s v%d", persona.Slug, persona.Version)
+		tools = s.tools.Subset(persona.Tools)
 	}
 
-	// Adicionar a mensagem do usuário ao thread
-	log.Printf("[OpenAI] Adicionando mensagem do usuário ao thread...")
-	_, err = s.client.CreateMessage(ctx, threadID, openai.MessageRequest{
-		Role:    "user",
-		Content: message.Content,
-	})
+	history, err := s.history(message, previous)
 	if err != nil {
-		log.Printf("[OpenAI] Erro ao adicionar mensagem ao thread: %v", err)
-		return nil, fmt.Errorf("erro ao adicionar mensagem ao thread: %v", err)
+		return nil, err
 	}
-	log.Printf("[OpenAI] Mensagem do usuário adicionada com sucesso")
 
-	// Criar e executar o run
-	log.Printf("[OpenAI] Criando run com AssistantID: %s", s.assistantID)
-	run, err := s.client.CreateRun(ctx, threadID, openai.RunRequest{
-		AssistantID: s.assistantID,
-	})
+	attachments, err := s.chatService.GetAttachments(message.ID)
 	if err != nil {
-		log.Printf("[OpenAI] Erro ao criar run: %v", err)
-		return nil, fmt.Errorf("erro ao criar run: %v", err)
+		return nil, err
 	}
-	log.Printf("[OpenAI] Run criado com ID: %s", run.ID)
 
-	// Aguardar a conclusão do run
-	log.Printf("[OpenAI] Aguardando processamento do run...")
-	for run.Status == openai.RunStatusQueued || run.Status == openai.RunStatusInProgress || run.Status == openai.RunStatusRequiresAction {
-		run, err = s.client.RetrieveRun(ctx, threadID, run.ID)
-		if err != nil {
-			log.Printf("[OpenAI] Erro ao verificar status do run: %v", err)
-			return nil, fmt.Errorf("erro ao processar mensagem com OpenAI: %v", err)
+	// O texto dos anexos passa pela mesma moderação da mensagem
+	content := withAttachments(message.Content, attachments)
+	if err := s.filters.Moderate(content); err != nil {
+		return nil, err
+	}
+
+	// Dados pessoais saem de tudo o que vai ao provedor e voltam na resposta
+	vault := s.filters.NewVault()
+	redacted := s.redactMessages(history, vault)
+	input := s.filters.Redact(content, vault)
+	onToken, flushTokens := vault.RestoreStream(func(token string) {
+		onEvent(StreamEvent{Type: StreamEventToken, Content: token})
+	})
+	executeTool := s.executeTool(tools, ToolContext{UserID: userID, PDI: pdi, MessageID: &message.ID}, onEvent)
+
+	req := ProviderRequest{
+		Input: input,
+		Tools: tools.Definitions(),
+		ExecuteTool: func(ctx context.Context, call ToolCall) (string, error) {
+			call.Arguments = vault.Restore(call.Arguments)
+			output, err := executeTool(ctx, call)
+			return s.filters.Redact(output, vault), err
+		},
+		OnToken: onToken,
+	}
+	if persona != nil {
+		// O que a persona define substitui a configuração do provedor; campos
+		// vazios mantêm a do provedor (assistant/instructions.md e as
+		// ferramentas registradas)
+		req.Instructions = persona.Instructions
+		req.Model = persona.Model
+		req.OverrideTools = len(persona.Tools) > 0
+		if persona.Temperature != nil {
+			temperature := float32(*persona.Temperature)
+			req.Temperature = &temperature
 		}
-		log.Printf("[OpenAI] Status atual do run: %s", run.Status)
-
-		if run.Status == openai.RunStatusRequiresAction {
-			log.Printf("[OpenAI] Run requer ação. Respondendo automaticamente...")
-			
-			// Submeter resposta para todas as ferramentas requeridas
-			var toolOutputs []openai.ToolOutput
-			for _, tool := range run.RequiredAction.SubmitToolOutputs.ToolCalls {
-				if tool.Type == openai.ToolTypeFunction {
-					// Salvar os goals no PDI
-					if tool.Function.Name == "save_pdi" {
-						pdi.Content = tool.Function.Arguments
-						if err := s.db.Model(&pdi).Where("id = ?", pdi.ID).Update("content", pdi.Content).Error; err != nil {
-							log.Printf("[OpenAI] Erro ao salvar goals do PDI: %v", err)
-							return nil, fmt.Errorf("erro ao salvar goals do PDI: %v", err)
-						}
-						log.Printf("[OpenAI] Goals do PDI salvos com sucesso")
-					}
-
-					toolOutputs = append(toolOutputs, openai.ToolOutput{
-						ToolCallID: tool.ID,
-						Output: "ok", // Resposta automática para todas as ferramentas
-					})
-				}
-			}
+	}
 
-			// Submeter as respostas
-			run, err = s.client.SubmitToolOutputs(ctx, threadID, run.ID, openai.SubmitToolOutputsRequest{
-				ToolOutputs: toolOutputs,
-			})
-			if err != nil {
-				log.Printf("[OpenAI] Erro ao submeter respostas das ferramentas: %v", err)
-				return nil, fmt.Errorf("erro ao submeter respostas das ferramentas: %v", err)
+	if s.context != nil {
+		// Contexto gerenciado: a tabela messages é a fonte da conversa
+		req.History, req.Summary, err = s.context.Window(ctx, pdi, redacted, input)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		replayed, err := s.ensureThread(ctx, pdi, history, vault)
+		if err != nil {
+			return nil, err
+		}
+		req.ThreadID = pdi.ThreadID
+		req.History = toTurns(redacted)
+		// Numa thread recriada a mensagem e a resposta anterior ainda não existem
+		if !replayed {
+			req.InputMessageID = message.ProviderMessageID
+			if previous != nil {
+				req.ReplaceMessageID = previous.ProviderMessageID
 			}
-			log.Printf("[OpenAI] Respostas das ferramentas submetidas com sucesso")
 		}
-
-		time.Sleep(100 * time.Millisecond)
 	}
 
-	if run.Status != openai.RunStatusCompleted {
-		log.Printf("[OpenAI] Run falhou com status: %s %s", run.Status, run.LastError.Message)
-		return nil, fmt.Errorf("erro ao processar com status na OpenAI: %v", run.Status)
+	resp, err := s.provider.Run(ctx, req)
+	flushTokens()
+	if err != nil {
+		var runErr *RunError
+		if errors.As(err, &runErr) {
+			if err := s.chatService.RecordRun(message, runErr.RunID, runErr.InputMessageID); err != nil {
+				log.Printf("[OpenAI] %v", err)
+			}
+		}
+		return nil, err
 	}
-	log.Printf("[OpenAI] Run concluído com sucesso")
 
-	// Buscar a última mensagem do assistente
-	log.Printf("[OpenAI] Buscando resposta do assistente...")
-	numMessages := 1
-	iaMessages, err := s.client.ListMessage(ctx, threadID, &numMessages, nil, nil, nil, nil)
-	if err != nil {
-		log.Printf("[OpenAI] Erro ao buscar mensagem do assistente: %v", err)
-		return nil, fmt.Errorf("erro ao buscar mensagem do assistente: %v", err)
+	if err := s.chatService.RecordRun(message, resp.RunID, resp.InputMessageID); err != nil {
+		log.Printf("[OpenAI] %v", err)
 	}
-	log.Printf("[OpenAI] Resposta do assistente recebida")
 
 	// Criar resposta do assistente
 	assistantMessage := &models.Message{
-		PDIID:   message.PDIID,
-		Content: iaMessages.Messages[0].Content[0].Text.Value,
-		Role:    "assistant",
-		Status:  models.MessageStatusCompleted,
+		PDIID:             message.PDIID,
+		Content:           vault.Restore(resp.Content),
+		Role:              "assistant",
+		Status:            models.MessageStatusCompleted,
+		ParentID:          &message.ID,
+		BranchID:          message.BranchID,
+		RunID:             resp.RunID,
+		ProviderMessageID: resp.MessageID,
+		Model:             resp.Model,
+		PromptTokens:      resp.Usage.PromptTokens,
+		CompletionTokens:  resp.Usage.CompletionTokens,
+		TotalTokens:       resp.Usage.TotalTokens,
+		CostUSD:           EstimateCost(resp.Model, resp.Usage),
+	}
+	if persona != nil {
+		assistantMessage.PersonaID = &persona.ID
 	}
 
 	// Salvar a resposta no banco de dados
 	log.Printf("[OpenAI] Salvando resposta no banco de dados...")
-	if err := s.db.Create(assistantMessage).Error; err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(assistantMessage).Error; err != nil {
+			return err
+		}
+		// A resposta substituída é excluída logicamente e segue auditável pelo run_id
+		if previous != nil {
+			return tx.Delete(previous).Error
+		}
+		return nil
+	})
+	if err != nil {
 		log.Printf("[OpenAI] Erro ao salvar resposta: %v", err)
 		return nil, fmt.Errorf("erro ao salvar resposta: %v", err)
 	}
@@ -194,3 +246,226 @@ func (s *OpenAIService) ProcessMessage(ctx context.Context, message *models.Mess
 
 	return assistantMessage, nil
 }
+
+// ensureThread cria a thread remota do PDI quando o provedor trabalha com
+// threads. Se a conversa já tem mensagens, por exemplo depois de uma troca de
+// ramo, a thread nova é criada com elas e devolve true.
+func (s *OpenAIService) ensureThread(ctx context.Context, pdi *models.PDI, history []*models.Message, vault *RedactionVault) (bool, error) {
+	threads, ok := s.provider.(ThreadProvider)
+	if !ok {
+		return false, nil
+	}
+	if pdi.ThreadID != "" {
+		log.Printf("[OpenAI] Usando thread existente: %s", pdi.ThreadID)
+		return false, nil
+	}
+
+	if len(history) > 0 {
+		log.Printf("[OpenAI] ThreadID não encontrado. Recriando thread com %d mensagens...", len(history))
+		turns, replayed := s.threadTurns(pdi, history, vault)
+		threadID, messageIDs, err := threads.ReplayThread(ctx, turns)
+		if err != nil {
+			return false, err
+		}
+		return true, s.replaceThread(pdi, threadID, replayed, messageIDs)
+	}
+
+	log.Printf("[OpenAI] ThreadID não encontrado. Criando novo thread...")
+	threadID, err := threads.CreateThread(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	result := s.db.Model(pdi).
+		Where("id = ?", pdi.ID).
+		Update("thread_id", threadID)
+	if result.Error != nil {
+		log.Printf("[OpenAI] Erro ao atualizar thread_id do PDI: %v", result.Error)
+		return false, fmt.Errorf("erro ao atualizar thread_id do PDI: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		log.Printf("[OpenAI] Nenhum PDI foi atualizado")
+		return false, fmt.Errorf("PDI não encontrado para atualização")
+	}
+
+	pdi.ThreadID = threadID
+	log.Printf("[OpenAI] PDI atualizado com novo ThreadID")
+	return false, nil
+}
+
+// RehydrateResult descreve a thread criada por RehydrateThread.
+type RehydrateResult struct {
+	ThreadID         string `json:"thread_id"`
+	PreviousThreadID string `json:"previous_thread_id,omitempty"`
+	Messages         int    `json:"messages"`
+}
+
+// CheckRehydrate confere, antes de enfileirar a reconstrução, se o provedor
+// usa threads e se não há mensagens do PDI em processamento.
+// RehydrateThread repete a conferência ao rodar.
+func (s *OpenAIService) CheckRehydrate(pdiID string) error {
+	if _, ok := s.provider.(ThreadProvider); !ok {
+		return ErrThreadsNotSupported
+	}
+	return conversationIdle(s.db, pdiID)
+}
+
+// RehydrateThread cria uma thread nova com as mensagens salvas e o conteúdo
+// atual do PDI e passa a usá-la no lugar da anterior, que não é removida do
+// provedor. Serve para recuperar conversas cuja thread se perdeu ou para
+// levá-las a outro assistente.
+func (s *OpenAIService) RehydrateThread(ctx context.Context, userID, pdiID string) (*RehydrateResult, error) {
+	threads, ok := s.provider.(ThreadProvider)
+	if !ok {
+		return nil, ErrThreadsNotSupported
+	}
+
+	pdi, err := s.pdiService.GetPDIByID(userID, pdiID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar PDI: %v", err)
+	}
+
+	messages, err := s.chatService.GetBranchMessages(pdi.ID, pdi.BranchID())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, msg := range messages {
+		if msg.Role == "user" && msg.Status == models.MessageStatusPending {
+			return nil, ErrConversationBusy
+		}
+	}
+	turns, replayed := s.threadTurns(pdi, expandAttachments(messages), s.filters.NewVault())
+
+	log.Printf("[OpenAI] Reconstruindo thread do PDI %s com %d mensagens", pdi.ID, len(turns))
+	threadID, messageIDs, err := threads.ReplayThread(ctx, turns)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := pdi.ThreadID
+	if err := s.replaceThread(pdi, threadID, replayed, messageIDs); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[OpenAI] PDI %s passou do thread %q para %s", pdi.ID, previous, threadID)
+	return &RehydrateResult{
+		ThreadID:         threadID,
+		PreviousThreadID: previous,
+		Messages:         len(replayed),
+	}, nil
+}
+
+// threadTurns monta as mensagens que recriam a thread do PDI, sem dados
+// pessoais, e devolve as mensagens salvas correspondentes, na mesma ordem.
+// O conteúdo atual do PDI entra por último.
+func (s *OpenAIService) threadTurns(pdi *models.PDI, messages []*models.Message, vault *RedactionVault) ([]Turn, []*models.Message) {
+	turns := make([]Turn, 0, len(messages)+1)
+	replayed := make([]*models.Message, 0, len(messages))
+	for _, msg := range messages {
+		// Mensagens com falha ficam de fora e entram na thread se forem reenviadas
+		if msg.Status == models.MessageStatusFailed {
+			continue
+		}
+		turns = append(turns, Turn{Role: msg.Role, Content: s.filters.Redact(msg.Content, vault)})
+		replayed = append(replayed, msg)
+	}
+	if pdi.Content != "" && pdi.Content != "{}" {
+		turns = append(turns, Turn{Role: "user", Content: s.filters.Redact(pdiContentPrompt(pdi.Content), vault)})
+	}
+	return turns, replayed
+}
+
+// replaceThread passa o PDI para a thread recriada e guarda nas mensagens os
+// IDs que elas receberam nela.
+func (s *OpenAIService) replaceThread(pdi *models.PDI, threadID string, replayed []*models.Message, messageIDs []string) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// IDs da thread anterior não valem na nova
+		if err := tx.Model(&models.Message{}).
+			Where("pdi_id = ?", pdi.ID).
+			Update("provider_message_id", "").Error; err != nil {
+			return err
+		}
+		for i, msg := range replayed {
+			if err := tx.Model(&models.Message{}).
+				Where("id = ?", msg.ID).
+				Update("provider_message_id", messageIDs[i]).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(pdi).Where("id = ?", pdi.ID).Update("thread_id", threadID).Error
+	})
+	if err != nil {
+		log.Printf("[OpenAI] Erro ao trocar thread do PDI: %v", err)
+		return fmt.Errorf("erro ao trocar thread do PDI: %v", err)
+	}
+
+	pdi.ThreadID = threadID
+	return nil
+}
+
+// pdiContentPrompt apresenta ao modelo o PDI salvo ao reconstruir a thread.
+func pdiContentPrompt(content string) string {
+	return "Conteúdo atual do PDI salvo, para referência na continuação da conversa:\n" + content
+}
+
+// redactMessages devolve cópias das mensagens sem os dados pessoais.
+func (s *OpenAIService) redactMessages(messages []*models.Message, vault *RedactionVault) []*models.Message {
+	if s.filters == nil {
+		return messages
+	}
+	redacted := make([]*models.Message, 0, len(messages))
+	for _, msg := range messages {
+		msgCopy := *msg
+		msgCopy.Content = s.filters.Redact(msg.Content, vault)
+		redacted = append(redacted, &msgCopy)
+	}
+	return redacted
+}
+
+// history devolve as mensagens do ramo anteriores à mensagem em
+// processamento, sem a resposta que está sendo substituída.
+func (s *OpenAIService) history(current *models.Message, replaced *models.Message) ([]*models.Message, error) {
+	messages, err := s.chatService.GetBranchMessages(current.PDIID, current.BranchID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]*models.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.ID == current.ID {
+			break
+		}
+		if replaced != nil && msg.ID == replaced.ID {
+			continue
+		}
+		history = append(history, msg)
+	}
+	return expandAttachments(history), nil
+}
+
+// expandAttachments devolve cópias das mensagens com o texto dos anexos no
+// conteúdo, como o assistente as recebe.
+func expandAttachments(messages []*models.Message) []*models.Message {
+	expanded := make([]*models.Message, 0, len(messages))
+	for _, msg := range messages {
+		if len(msg.Attachments) == 0 {
+			expanded = append(expanded, msg)
+			continue
+		}
+		msgCopy := *msg
+		msgCopy.Content = withAttachments(msg.Content, msg.Attachments)
+		msgCopy.Attachments = nil
+		expanded = append(expanded, &msgCopy)
+	}
+	return expanded
+}
+
+// executeTool executa as chamadas de ferramentas do run, restritas às da
+// persona, no contexto da pessoa usuária dona do PDI.
+func (s *OpenAIService) executeTool(tools *ToolRegistry, tc ToolContext, onEvent func(StreamEvent)) ToolExecutor {
+	return func(ctx context.Context, call ToolCall) (string, error) {
+		onEvent(StreamEvent{Type: StreamEventToolCall, Tool: &call})
+		return tools.Execute(ctx, tc, call), nil
+	}
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"meu-pdi-estrategico/backend/internal/models"
+	"meu-pdi-estrategico/backend/internal/openaistub"
+)
+
+func setupChatTestDB(t *testing.T) (*gorm.DB, *models.User, *models.PDI) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Erro ao conectar com o banco de dados: %v", err)
+	}
+	// Cada conexão a ":memory:" abre um banco vazio: o worker precisa usar a mesma
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&models.User{}, &models.PDI{}, &models.Message{}, &models.QuotaPlan{}, &models.Persona{}, &models.MessageFeedback{}, &models.Attachment{}, &models.ConversationArchive{}, &models.Goal{}, &models.KeyResult{}, &models.ActionItem{}, &models.Skill{}, &models.PDIRevision{}, &models.PDITransition{}, &models.UsageRecord{}); err != nil {
+		t.Fatalf("Erro ao migrar banco de dados: %v", err)
+	}
+
+	user, err := NewUserService(db).CreateUser("teste@exemplo.com", "Senha@123", "teste")
+	if err != nil {
+		t.Fatalf("Erro ao criar usuário para teste: %v", err)
+	}
+
+	pdi, err := NewPDIService(db).CreatePDI(user.ID.String(), CreatePDIRequest{Name: "Meu PDI"})
+	if err != nil {
+		t.Fatalf("Erro ao criar PDI para teste: %v", err)
+	}
+
+	return db, user, pdi
+}
+
+func TestOpenAIService_ProcessMessage(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	service := NewOpenAIService(db, NewFakeProvider(), nil)
+
+	message, err := NewChatService(db).CreateMessage(&models.Message{PDIID: pdi.ID, Content: "Olá", Role: "user"})
+	if err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+
+	reply, err := service.ProcessMessage(context.Background(), message, user.ID.String())
+	if err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+
+	if reply.Content != "Resposta simulada: Olá" {
+		t.Errorf("ProcessMessage() content = %v", reply.Content)
+	}
+	if reply.Role != "assistant" || reply.Status != models.MessageStatusCompleted {
+		t.Errorf("ProcessMessage() role = %v, status = %v", reply.Role, reply.Status)
+	}
+
+	messages, err := NewChatService(db).GetMessagesByPDIID(pdi.ID)
+	if err != nil {
+		t.Fatalf("Erro ao buscar mensagens: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Errorf("Mensagens esperadas 2, recebidas %d", len(messages))
+	}
+}
+
+func TestOpenAIService_ProcessMessage_SavePDI(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	content := `{"goals":[],"self_assessment_questions":[]}`
+	provider := NewFakeProvider(FakeReply{
+		Content:   "PDI salvo!",
+		ToolCalls: []ToolCall{{Name: "save_pdi", Arguments: content}},
+	})
+	service := NewOpenAIService(db, provider, nil)
+
+	message, err := NewChatService(db).CreateMessage(&models.Message{PDIID: pdi.ID, Content: "Salve meu PDI", Role: "user"})
+	if err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+
+	if _, err := service.ProcessMessage(context.Background(), message, user.ID.String()); err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+
+	saved, err := NewPDIService(db).GetPDIByID(user.ID.String(), pdi.ID)
+	if err != nil {
+		t.Fatalf("Erro ao buscar PDI: %v", err)
+	}
+	expected := `{"goals":[],"schema_version":1,"self_assessment_questions":[]}`
+	if saved.Content != expected {
+		t.Errorf("Conteúdo esperado %v, recebido %v", expected, saved.Content)
+	}
+}
+
+// Fluxo completo com a API de Assistants reproduzida do cassete: cria a
+// thread, executa o run, responde à chamada de save_pdi e busca a resposta.
+func TestOpenAIService_ProcessMessage_AssistantsSavePDI(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	stub := openaistub.New(t, "testdata/cassettes/save_pdi.json")
+	provider := NewAssistantsProvider(stub.Client(), "asst_stub", RunPollConfig{InitialInterval: time.Millisecond})
+	service := NewOpenAIService(db, provider, nil)
+
+	message, err := NewChatService(db).CreateMessage(&models.Message{PDIID: pdi.ID, Content: "Quero virar tech lead em um ano. Pode salvar meu PDI?", Role: "user"})
+	if err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+
+	reply, err := service.ProcessMessage(context.Background(), message, user.ID.String())
+	if err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+	if reply.Content != "Salvei seu PDI com a meta de se tornar tech lead." || reply.ProviderMessageID != "msg_reply" {
+		t.Errorf("ProcessMessage() = %q (%s)", reply.Content, reply.ProviderMessageID)
+	}
+
+	saved, err := NewPDIService(db).GetPDIByID(user.ID.String(), pdi.ID)
+	if err != nil {
+		t.Fatalf("Erro ao buscar PDI: %v", err)
+	}
+	if saved.ThreadID != "thread_save" {
+		t.Errorf("thread_id = %q, esperado thread_save", saved.ThreadID)
+	}
+	if !strings.Contains(saved.Content, "Tornar-me tech lead") {
+		t.Errorf("Conteúdo do PDI não foi salvo: %s", saved.Content)
+	}
+}
+
+func TestOpenAIService_ProcessMessage_OtherUser(t *testing.T) {
+	db, _, pdi := setupChatTestDB(t)
+	service := NewOpenAIService(db, NewFakeProvider(), nil)
+
+	message := &models.Message{PDIID: pdi.ID, Content: "Olá", Role: "user"}
+	if _, err := service.ProcessMessage(context.Background(), message, "00000000-0000-0000-0000-000000000000"); err == nil {
+		t.Error("ProcessMessage() deveria falhar para PDI de outro usuário")
+	}
+}
+
+// threadRecorder simula um provedor com threads e guarda as mensagens recriadas.
+type threadRecorder struct {
+	recordingProvider
+	replayed []Turn
+}
+
+func (p *threadRecorder) CreateThread(ctx context.Context) (string, error) {
+	return "thread-nova", nil
+}
+
+func (p *threadRecorder) ReplayThread(ctx context.Context, turns []Turn) (string, []string, error) {
+	p.replayed = turns
+	ids := make([]string, len(turns))
+	for i := range turns {
+		ids[i] = fmt.Sprintf("msg-%d", i)
+	}
+	return "thread-nova", ids, nil
+}
+
+func TestOpenAIService_RehydrateThread(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	chatService := NewChatService(db)
+	db.Model(pdi).Where("id = ?", pdi.ID).Updates(map[string]interface{}{"thread_id": "thread-velha", "content": `{"goals":[]}`})
+
+	messages := []*models.Message{
+		{PDIID: pdi.ID, Role: "user", Content: "Olá", Status: models.MessageStatusCompleted, ProviderMessageID: "velha-1"},
+		{PDIID: pdi.ID, Role: "assistant", Content: "Oi!", Status: models.MessageStatusCompleted, ProviderMessageID: "velha-2"},
+		{PDIID: pdi.ID, Role: "user", Content: "Falhou", Status: models.MessageStatusFailed, ProviderMessageID: "velha-3"},
+	}
+	base := time.Now().Add(-time.Minute)
+	for i, msg := range messages {
+		msg.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		if _, err := chatService.CreateMessage(msg); err != nil {
+			t.Fatalf("Erro ao criar mensagem: %v", err)
+		}
+	}
+
+	provider := &threadRecorder{}
+	result, err := NewOpenAIService(db, provider, nil).RehydrateThread(context.Background(), user.ID.String(), pdi.ID)
+	if err != nil {
+		t.Fatalf("RehydrateThread() error = %v", err)
+	}
+
+	if result.ThreadID != "thread-nova" || result.PreviousThreadID != "thread-velha" || result.Messages != 2 {
+		t.Errorf("RehydrateThread() = %+v", result)
+	}
+	if len(provider.replayed) != 3 || !strings.Contains(provider.replayed[2].Content, `{"goals":[]}`) {
+		t.Errorf("Mensagens recriadas = %+v", provider.replayed)
+	}
+
+	var stored models.PDI
+	db.First(&stored, "id = ?", pdi.ID)
+	if stored.ThreadID != "thread-nova" {
+		t.Errorf("thread_id = %q, esperado thread-nova", stored.ThreadID)
+	}
+
+	saved, _ := chatService.GetMessagesByPDIID(pdi.ID)
+	if saved[0].ProviderMessageID != "msg-0" || saved[1].ProviderMessageID != "msg-1" || saved[2].ProviderMessageID != "" {
+		t.Errorf("provider_message_id = %q, %q, %q", saved[0].ProviderMessageID, saved[1].ProviderMessageID, saved[2].ProviderMessageID)
+	}
+
+	// Com mensagem em processamento a thread não é trocada
+	if _, err := chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Role: "user", Content: "Nova"}); err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+	if _, err := NewOpenAIService(db, provider, nil).RehydrateThread(context.Background(), user.ID.String(), pdi.ID); !errors.Is(err, ErrConversationBusy) {
+		t.Errorf("RehydrateThread() error = %v, esperado ErrConversationBusy", err)
+	}
+
+	if _, err := NewOpenAIService(db, NewFakeProvider(), nil).RehydrateThread(context.Background(), user.ID.String(), pdi.ID); !errors.Is(err, ErrThreadsNotSupported) {
+		t.Errorf("RehydrateThread() error = %v, esperado ErrThreadsNotSupported", err)
+	}
+}
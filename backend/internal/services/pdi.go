@@ -15,21 +15,30 @@ func NewPDIService(db *gorm.DB) *PDIService {
 	return &PDIService{db: db}
 }
 
+// CreatePDIRequest cria o PDI em DRAFT; o status só muda pelas transições do
+// PDIWorkflowService.
 type CreatePDIRequest struct {
-	Name   string      `json:"name" binding:"required"`
-	Status models.PDIStatus `json:"status" binding:"required"`
+	Name string `json:"name" binding:"required"`
+	// Persona é o slug da persona do assistente; vazio usa a persona padrão.
+	Persona string `json:"persona"`
 }
 
 type UpdatePDIRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name    string `json:"name" binding:"required"`
+	Persona string `json:"persona"`
 }
 
 func (s *PDIService) CreatePDI(userID string, req CreatePDIRequest) (*models.PDI, error) {
+	if err := s.checkPersona(req.Persona); err != nil {
+		return nil, err
+	}
+
 	pdi := &models.PDI{
 		Name:   req.Name,
 		UserID: userID,
-		Status: req.Status,
+		Status: models.PDIStatusDraft,
     Content: "{}",
+		Persona: req.Persona,
 	}
 
 	if err := s.db.Create(pdi).Error; err != nil {
@@ -56,8 +65,19 @@ func (s *PDIService) UpdatePDI(userID, pdiID string, req UpdatePDIRequest) (*mod
 		return nil, err
 	}
 
+	if err := s.checkPersona(req.Persona); err != nil {
+		return nil, err
+	}
+
+	// Só os campos do pedido são gravados: o resto da linha lida acima pode já
+	// ter mudado, como o status, o conteúdo ou a thread da conversa
 	pdi.Name = req.Name
-	if err := s.db.Save(&pdi).Error; err != nil {
+	updates := map[string]interface{}{"name": req.Name}
+	if req.Persona != "" {
+		pdi.Persona = req.Persona
+		updates["persona"] = req.Persona
+	}
+	if err := s.db.Model(&pdi).Updates(updates).Error; err != nil {
 		return nil, err
 	}
 
@@ -73,4 +93,19 @@ func (s *PDIService) GetPDIByID(userID, pdiID string) (*models.PDI, error) {
 		return nil, err
 	}
 	return &pdi, nil
-} 
+}
+
+// checkPersona confere se a persona escolhida para o PDI está disponível.
+func (s *PDIService) checkPersona(slug string) error {
+	if slug == "" {
+		return nil
+	}
+	exists, err := NewPersonaService(s.db, "").Exists(slug)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrPersonaNotFound
+	}
+	return nil
+}
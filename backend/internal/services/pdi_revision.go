@@ -0,0 +1,251 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"meu-pdi-estrategico/backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Motivos registrados nas revisões do conteúdo do PDI.
+const (
+	RevisionReasonBaseline          = "baseline"
+	RevisionReasonSavePDI           = "save_pdi"
+	RevisionReasonGoalProgress      = "update_goal_progress"
+	RevisionReasonGoalUpdate        = "goal_update"
+	RevisionReasonKeyResultUpdate   = "key_result_update"
+	RevisionReasonActionItemUpdate  = "action_item_update"
+	RevisionReasonRestore           = "restore"
+	RevisionReasonConversationReset = "conversation_reset"
+	RevisionReasonArchiveRestore    = "archive_restore"
+)
+
+var ErrRevisionNotFound = errors.New("revisão não encontrada")
+
+// RevisionSource identifica quem alterou o conteúdo do PDI e por quê.
+type RevisionSource struct {
+	Author       models.RevisionAuthor
+	MessageID    *uuid.UUID
+	Reason       string
+	RestoredFrom *int
+}
+
+// RevisionChange é uma diferença entre duas revisões. Path é um JSON Pointer
+// para o campo do conteúdo; Op é added, removed ou changed.
+type RevisionChange struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+type RevisionDiff struct {
+	From    int              `json:"from"`
+	To      int              `json:"to"`
+	Changes []RevisionChange `json:"changes"`
+}
+
+type RevisionService struct {
+	db    *gorm.DB
+	goals *GoalService
+}
+
+func NewRevisionService(db *gorm.DB, goals *GoalService) *RevisionService {
+	return &RevisionService{db: db, goals: goals}
+}
+
+// ListRevisions devolve as revisões do PDI, da mais recente à mais antiga,
+// sem o conteúdo.
+func (s *RevisionService) ListRevisions(pdiID string) ([]models.PDIRevision, error) {
+	revisions := []models.PDIRevision{}
+	if err := s.db.Omit("content").
+		Where("pdi_id = ?", pdiID).
+		Order("number DESC").
+		Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("erro ao buscar revisões: %v", err)
+	}
+	return revisions, nil
+}
+
+// GetRevision devolve a revisão com o conteúdo. Com number zero, devolve a
+// mais recente.
+func (s *RevisionService) GetRevision(pdiID string, number int) (*models.PDIRevision, error) {
+	query := s.db.Where("pdi_id = ?", pdiID)
+	if number > 0 {
+		query = query.Where("number = ?", number)
+	}
+
+	var revision models.PDIRevision
+	if err := query.Order("number DESC").First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar revisão: %v", err)
+	}
+	return &revision, nil
+}
+
+// Diff compara o conteúdo de duas revisões campo a campo. Com to zero, usa a
+// revisão mais recente; com from zero, a anterior a to.
+func (s *RevisionService) Diff(pdiID string, from, to int) (*RevisionDiff, error) {
+	target, err := s.GetRevision(pdiID, to)
+	if err != nil {
+		return nil, err
+	}
+	if from == 0 {
+		from = target.Number - 1
+	}
+
+	var before interface{} = map[string]interface{}{}
+	if from > 0 {
+		source, err := s.GetRevision(pdiID, from)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(source.Content), &before); err != nil {
+			return nil, fmt.Errorf("conteúdo da revisão %d inválido: %v", source.Number, err)
+		}
+	}
+	var after interface{}
+	if err := json.Unmarshal([]byte(target.Content), &after); err != nil {
+		return nil, fmt.Errorf("conteúdo da revisão %d inválido: %v", target.Number, err)
+	}
+
+	diff := &RevisionDiff{From: from, To: target.Number, Changes: []RevisionChange{}}
+	diffContent("", before, after, &diff.Changes)
+	return diff, nil
+}
+
+// Restore volta o conteúdo do PDI ao da revisão informada, pelo mesmo caminho
+// do save_pdi. A restauração vira uma nova revisão.
+func (s *RevisionService) Restore(pdi *models.PDI, number int) (*models.PDIRevision, error) {
+	revision, err := s.GetRevision(pdi.ID, number)
+	if err != nil {
+		return nil, err
+	}
+
+	source := RevisionSource{Author: models.RevisionAuthorUser, Reason: RevisionReasonRestore, RestoredFrom: &revision.Number}
+	if err := s.goals.SavePlan(pdi, []byte(revision.Content), source); err != nil {
+		return nil, err
+	}
+	return s.GetRevision(pdi.ID, 0)
+}
+
+// recordRevision grava o novo conteúdo do PDI e registra a revisão, se ele
+// mudou. O conteúdo anterior é o gravado no banco, lido com o PDI travado até
+// o fim da transação: duas alterações simultâneas não calculam o mesmo número
+// nem comparam com uma cópia desatualizada. Na primeira alteração de um PDI
+// sem histórico, o conteúdo anterior vira a revisão 1.
+func recordRevision(tx *gorm.DB, pdi *models.PDI, content string, source RevisionSource) error {
+	locked, err := lockPDI(tx, pdi.ID)
+	if err != nil {
+		return err
+	}
+	previous := locked.Content
+	if previous != content {
+		if err := tx.Model(locked).Update("content", content).Error; err != nil {
+			return err
+		}
+	}
+	pdi.Content = content
+
+	var latest models.PDIRevision
+	err = tx.Where("pdi_id = ?", pdi.ID).Order("number DESC").Take(&latest).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if latest.ID == uuid.Nil {
+		if previous != "" && previous != emptyPDIContent && !sameContent(previous, content) {
+			latest = models.PDIRevision{PDIID: pdi.ID, Number: 1, Content: previous, Author: models.RevisionAuthorSystem, Reason: RevisionReasonBaseline}
+			if err := tx.Create(&latest).Error; err != nil {
+				return err
+			}
+		}
+	} else if sameContent(latest.Content, content) {
+		return nil
+	}
+
+	return tx.Create(&models.PDIRevision{
+		PDIID:        pdi.ID,
+		Number:       latest.Number + 1,
+		Content:      content,
+		Author:       source.Author,
+		MessageID:    source.MessageID,
+		Reason:       source.Reason,
+		RestoredFrom: source.RestoredFrom,
+	}).Error
+}
+
+// sameContent compara dois documentos JSON ignorando espaços e a ordem das
+// chaves, que o banco pode alterar.
+func sameContent(a, b string) bool {
+	var left, right interface{}
+	if json.Unmarshal([]byte(a), &left) != nil || json.Unmarshal([]byte(b), &right) != nil {
+		return a == b
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func diffContent(path string, before, after interface{}, changes *[]RevisionChange) {
+	switch a := before.(type) {
+	case map[string]interface{}:
+		b, ok := after.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(a)+len(b))
+		for key := range a {
+			keys = append(keys, key)
+		}
+		for key := range b {
+			if _, ok := a[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := path + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+			old, inBefore := a[key]
+			new, inAfter := b[key]
+			switch {
+			case !inBefore:
+				*changes = append(*changes, RevisionChange{Path: child, Op: "added", New: new})
+			case !inAfter:
+				*changes = append(*changes, RevisionChange{Path: child, Op: "removed", Old: old})
+			default:
+				diffContent(child, old, new, changes)
+			}
+		}
+		return
+	case []interface{}:
+		b, ok := after.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(a) || i < len(b); i++ {
+			child := path + "/" + strconv.Itoa(i)
+			switch {
+			case i >= len(a):
+				*changes = append(*changes, RevisionChange{Path: child, Op: "added", New: b[i]})
+			case i >= len(b):
+				*changes = append(*changes, RevisionChange{Path: child, Op: "removed", Old: a[i]})
+			default:
+				diffContent(child, a[i], b[i], changes)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, RevisionChange{Path: path, Op: "changed", Old: before, New: after})
+	}
+}
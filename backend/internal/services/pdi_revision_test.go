@@ -0,0 +1,112 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+func TestRevisionService_History(t *testing.T) {
+	db, _, pdi := setupChatTestDB(t)
+	goals := NewGoalService(db)
+	service := NewRevisionService(db, goals)
+
+	// O conteúdo anterior ao histórico vira a revisão de base
+	legacy := `{"goals":[` + planGoal("Aprender Go") + `],"self_assessment_questions":[]}`
+	db.Model(pdi).Where("id = ?", pdi.ID).Update("content", legacy)
+	pdi.Content = legacy
+
+	message := &models.Message{PDIID: pdi.ID, Role: "user", Content: "Troque o objetivo", Status: models.MessageStatusCompleted}
+	NewChatService(db).CreateMessage(message)
+	tc := ToolContext{PDI: pdi, MessageID: &message.ID}
+
+	plan := []byte(`{"goals":[` + planGoal("Liderar um projeto") + `],"self_assessment_questions":[]}`)
+	if err := goals.SavePlan(pdi, plan, tc.revisionSource(RevisionReasonSavePDI)); err != nil {
+		t.Fatalf("SavePlan() error = %v", err)
+	}
+	// O mesmo conteúdo não gera nova revisão
+	if err := goals.SavePlan(pdi, plan, tc.revisionSource(RevisionReasonSavePDI)); err != nil {
+		t.Fatalf("SavePlan() repetido error = %v", err)
+	}
+	progress := 40.0
+	if _, err := goals.UpdateGoalAt(pdi, 0, GoalUpdate{Progress: &progress}, userEdit); err != nil {
+		t.Fatalf("UpdateGoalAt() error = %v", err)
+	}
+
+	revisions, err := service.ListRevisions(pdi.ID)
+	if err != nil {
+		t.Fatalf("ListRevisions() error = %v", err)
+	}
+	if len(revisions) != 3 {
+		t.Fatalf("ListRevisions() = %d revisões, esperado 3", len(revisions))
+	}
+	latest, saved, baseline := revisions[0], revisions[1], revisions[2]
+	if latest.Number != 3 || latest.Author != models.RevisionAuthorUser || latest.Content != "" {
+		t.Errorf("Revisão mais recente = %+v", latest)
+	}
+	if saved.Author != models.RevisionAuthorAssistant || saved.MessageID == nil || *saved.MessageID != message.ID || saved.Reason != RevisionReasonSavePDI {
+		t.Errorf("Revisão do assistente = %+v", saved)
+	}
+	if baseline.Number != 1 || baseline.Author != models.RevisionAuthorSystem {
+		t.Errorf("Revisão de base = %+v", baseline)
+	}
+
+	diff, err := service.Diff(pdi.ID, 1, 0)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	changes := map[string]RevisionChange{}
+	for _, change := range diff.Changes {
+		changes[change.Path] = change
+	}
+	if diff.To != 3 || changes["/goals/0/description"].New != "Liderar um projeto" || changes["/goals/0/progress"].New != 40.0 {
+		t.Errorf("Diff() = %+v", diff)
+	}
+	if _, ok := changes["/goals/0/alignment"]; ok {
+		t.Errorf("Diff() inclui campo sem alteração: %+v", diff.Changes)
+	}
+	if _, err := service.Diff(pdi.ID, 9, 0); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("Diff() com revisão inexistente error = %v, esperado ErrRevisionNotFound", err)
+	}
+
+	restored, err := service.Restore(pdi, 1)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if restored.Number != 4 || restored.RestoredFrom == nil || *restored.RestoredFrom != 1 || restored.Reason != RevisionReasonRestore {
+		t.Errorf("Restore() = %+v", restored)
+	}
+	current, _ := goals.ListGoals(pdi)
+	if len(current) != 1 || current[0].Description != "Aprender Go" {
+		t.Errorf("Objetivos após restaurar = %+v", current)
+	}
+}
+
+func TestDiffContent(t *testing.T) {
+	before := map[string]interface{}{
+		"a/b":  "x",
+		"list": []interface{}{"um", "dois"},
+	}
+	after := map[string]interface{}{
+		"list": []interface{}{"um"},
+		"novo": 1.0,
+	}
+
+	var changes []RevisionChange
+	diffContent("", before, after, &changes)
+
+	expected := []RevisionChange{
+		{Path: "/a~1b", Op: "removed", Old: "x"},
+		{Path: "/list/1", Op: "removed", Old: "dois"},
+		{Path: "/novo", Op: "added", New: 1.0},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("diffContent() = %+v", changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("diffContent()[%d] = %+v, esperado %+v", i, changes[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// PDIContentSchemaVersion é a versão do schema do conteúdo do PDI. Cada
+// documento salvo registra a versão em schema_version.
+const PDIContentSchemaVersion = 1
+
+const pdiContentSchemaURL = "https://meupdiestrategico.app/schemas/pdi_content.v1.json"
+
+//go:embed schemas/pdi_content.v1.json
+var pdiContentSchemaV1 []byte
+
+var pdiContentSchema = compilePDIContentSchema()
+
+func compilePDIContentSchema() *jsonschema.Schema {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(pdiContentSchemaURL, bytes.NewReader(pdiContentSchemaV1)); err != nil {
+		panic(fmt.Sprintf("schema do PDI inválido: %v", err))
+	}
+	return compiler.MustCompile(pdiContentSchemaURL)
+}
+
+// PDIValidationError lista os problemas encontrados no conteúdo do PDI.
+type PDIValidationError struct {
+	Errors []string `json:"validation_errors"`
+}
+
+func (e *PDIValidationError) Error() string {
+	return "conteúdo do PDI inválido: " + strings.Join(e.Errors, "; ")
+}
+
+// ValidatePDIContent valida o documento contra o schema atual e devolve o
+// documento com schema_version preenchido.
+func ValidatePDIContent(data []byte) ([]byte, error) {
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, &PDIValidationError{Errors: []string{fmt.Sprintf("JSON inválido: %v", err)}}
+	}
+
+	if err := pdiContentSchema.Validate(document); err != nil {
+		var validation *jsonschema.ValidationError
+		if !errors.As(err, &validation) {
+			return nil, err
+		}
+		return nil, &PDIValidationError{Errors: validationMessages(validation)}
+	}
+
+	content := document.(map[string]interface{})
+	content["schema_version"] = PDIContentSchemaVersion
+	return json.Marshal(content)
+}
+
+// validationMessages achata a árvore de erros do validador, mantendo só as
+// causas finais no formato "<caminho>: <mensagem>".
+func validationMessages(err *jsonschema.ValidationError) []string {
+	if len(err.Causes) == 0 {
+		location := err.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		return []string{location + ": " + err.Message}
+	}
+
+	var messages []string
+	for _, cause := range err.Causes {
+		messages = append(messages, validationMessages(cause)...)
+	}
+	return messages
+}
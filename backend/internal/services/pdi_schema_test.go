@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidatePDIContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		errors  []string
+	}{
+		{
+			name:    "documento válido",
+			content: `{"goals":[` + testGoal("Aprender Go") + `],"self_assessment_questions":["Como me saí?"]}`,
+		},
+		{
+			name:    "JSON inválido",
+			content: `{"goals":`,
+			errors:  []string{"JSON inválido"},
+		},
+		{
+			name:    "campos obrigatórios ausentes",
+			content: `{"goals":[{"description":"Aprender Go"}]}`,
+			errors:  []string{"self_assessment_questions", "/goals/0"},
+		},
+		{
+			name:    "tipo incorreto",
+			content: `{"goals":[{"description":"Aprender Go","skills":{"hard_skills":"Go","soft_skills":[]},"alignment":"","action_plan":[],"key_results":[]}],"self_assessment_questions":[]}`,
+			errors:  []string{"/goals/0/skills/hard_skills"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := ValidatePDIContent([]byte(tt.content))
+			if len(tt.errors) == 0 {
+				if err != nil {
+					t.Fatalf("ValidatePDIContent() error = %v", err)
+				}
+				if !strings.Contains(string(content), `"schema_version":1`) {
+					t.Errorf("ValidatePDIContent() content = %s", content)
+				}
+				return
+			}
+
+			var validation *PDIValidationError
+			if !errors.As(err, &validation) {
+				t.Fatalf("ValidatePDIContent() error = %v, esperado PDIValidationError", err)
+			}
+			joined := strings.Join(validation.Errors, "\n")
+			for _, expected := range tt.errors {
+				if !strings.Contains(joined, expected) {
+					t.Errorf("Erro esperado contendo %q, recebido %v", expected, validation.Errors)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+
+	"meu-pdi-estrategico/backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func TestPDIService_UpdatePDIKeepsOtherColumns(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+
+	// Outra requisição altera a thread e o conteúdo logo depois da leitura
+	// feita em UpdatePDI
+	concurrent := false
+	db.Callback().Query().After("gorm:query").Register("test:concurrent_update", func(tx *gorm.DB) {
+		if concurrent || tx.Statement.Table != "pdis" {
+			return
+		}
+		concurrent = true
+		tx.Session(&gorm.Session{NewDB: true}).Model(&models.PDI{}).Where("id = ?", pdi.ID).
+			UpdateColumns(map[string]interface{}{"thread_id": "thread-atual", "content": `{"goals":[]}`})
+	})
+
+	updated, err := NewPDIService(db).UpdatePDI(user.ID.String(), pdi.ID, UpdatePDIRequest{Name: "PDI renomeado"})
+	if err != nil {
+		t.Fatalf("UpdatePDI() error = %v", err)
+	}
+	if updated.Name != "PDI renomeado" {
+		t.Errorf("Nome = %q", updated.Name)
+	}
+
+	var stored models.PDI
+	db.First(&stored, "id = ?", pdi.ID)
+	if stored.Name != "PDI renomeado" || stored.ThreadID != "thread-atual" || stored.Content != `{"goals":[]}` || stored.Status != models.PDIStatusDraft {
+		t.Errorf("PDI após renomear = %+v", stored)
+	}
+}
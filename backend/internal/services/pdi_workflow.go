@@ -0,0 +1,202 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"meu-pdi-estrategico/backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidPDIStatus     = errors.New("status inválido: use DRAFT, PENDING, IN_PROGRESS ou DONE")
+	ErrTransitionNotAllowed = errors.New("transição de status não permitida")
+	ErrTransitionConflict   = errors.New("o status do PDI mudou durante a transição")
+)
+
+// TransitionBlockedError lista as condições do PDI que impedem a transição.
+type TransitionBlockedError struct {
+	Errors []string
+}
+
+func (e *TransitionBlockedError) Error() string {
+	return "transição bloqueada: " + strings.Join(e.Errors, "; ")
+}
+
+// pdiTransitions são os status de destino permitidos a partir de cada status.
+// O PDI nasce em DRAFT, vai para PENDING quando o plano está pronto para
+// revisão, IN_PROGRESS durante a execução e DONE ao final; um PDI concluído
+// pode ser reaberto.
+var pdiTransitions = map[models.PDIStatus][]models.PDIStatus{
+	models.PDIStatusDraft:      {models.PDIStatusPending},
+	models.PDIStatusPending:    {models.PDIStatusDraft, models.PDIStatusInProgress},
+	models.PDIStatusInProgress: {models.PDIStatusDone},
+	models.PDIStatusDone:       {models.PDIStatusInProgress},
+}
+
+// transitionGuards conferem o PDI antes de ele entrar no status e devolvem os
+// motivos que impedem a transição.
+var transitionGuards = map[models.PDIStatus]func(goals []models.Goal) []string{
+	models.PDIStatusPending:    requirePlan,
+	models.PDIStatusInProgress: requirePlan,
+	models.PDIStatusDone:       requireKeyResultsDone,
+}
+
+func requirePlan(goals []models.Goal) []string {
+	if len(goals) == 0 {
+		return []string{"o PDI ainda não tem objetivos"}
+	}
+	return nil
+}
+
+func requireKeyResultsDone(goals []models.Goal) []string {
+	var reasons []string
+	for _, goal := range goals {
+		for _, keyResult := range goal.KeyResults {
+			if !keyResult.Done {
+				reasons = append(reasons, fmt.Sprintf("resultado-chave em aberto no objetivo %d: %s", goal.Position+1, keyResult.Description))
+			}
+		}
+	}
+	return reasons
+}
+
+// AllowedTransitions devolve os status para os quais o PDI pode ir a partir
+// de from.
+func AllowedTransitions(from models.PDIStatus) []models.PDIStatus {
+	allowed := pdiTransitions[from]
+	if allowed == nil {
+		// Status antigos, fora do fluxo, só podem voltar ao rascunho
+		return []models.PDIStatus{models.PDIStatusDraft}
+	}
+	return allowed
+}
+
+type TransitionRequest struct {
+	To   models.PDIStatus `json:"to"`
+	Note string           `json:"note"`
+}
+
+// PDITransitionEvent é emitido a cada mudança de status do PDI, depois de
+// gravada.
+type PDITransitionEvent struct {
+	PDI        *models.PDI
+	Transition models.PDITransition
+}
+
+type PDITransitionListener func(event PDITransitionEvent)
+
+type PDIWorkflowService struct {
+	db    *gorm.DB
+	goals *GoalService
+
+	mu        sync.RWMutex
+	listeners []PDITransitionListener
+}
+
+func NewPDIWorkflowService(db *gorm.DB, goals *GoalService) *PDIWorkflowService {
+	return &PDIWorkflowService{db: db, goals: goals}
+}
+
+// OnTransition inscreve o ouvinte nas mudanças de status. Os ouvintes rodam
+// na goroutine da requisição, na ordem de inscrição; trabalho demorado deve
+// ir para outra goroutine.
+func (s *PDIWorkflowService) OnTransition(listener PDITransitionListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// Transition move o PDI para o status pedido, se a transição for permitida e
+// o PDI cumprir as condições do novo status, e registra a mudança no
+// histórico.
+func (s *PDIWorkflowService) Transition(pdi *models.PDI, userID string, req TransitionRequest) (*models.PDITransition, error) {
+	if !req.To.Valid() {
+		return nil, ErrInvalidPDIStatus
+	}
+
+	from := pdi.Status
+	allowed := false
+	for _, status := range AllowedTransitions(from) {
+		if status == req.To {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, ErrTransitionNotAllowed
+	}
+
+	// Objetivos importados do conteúdo antigo, se ainda não estiverem nas
+	// tabelas, antes de a condição conferi-los
+	if err := s.goals.ensurePlan(pdi); err != nil {
+		return nil, err
+	}
+
+	transition := models.PDITransition{
+		PDIID:      pdi.ID,
+		FromStatus: from,
+		ToStatus:   req.To,
+		UserID:     userID,
+		Note:       strings.TrimSpace(req.Note),
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Com o PDI travado, o plano não muda entre a condição e a troca de
+		// status, e duas transições simultâneas não passam as duas
+		if _, err := lockPDI(tx, pdi.ID); err != nil {
+			return err
+		}
+
+		if guard := transitionGuards[req.To]; guard != nil {
+			goals, err := loadGoals(tx, pdi.ID)
+			if err != nil {
+				return err
+			}
+			if reasons := guard(goals); len(reasons) > 0 {
+				return &TransitionBlockedError{Errors: reasons}
+			}
+		}
+
+		result := tx.Model(pdi).Where("status = ?", from).Update("status", req.To)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTransitionConflict
+		}
+		return tx.Create(&transition).Error
+	})
+	if err != nil {
+		pdi.Status = from
+		var blocked *TransitionBlockedError
+		if errors.Is(err, ErrTransitionConflict) || errors.As(err, &blocked) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("erro ao alterar status do PDI: %v", err)
+	}
+	pdi.Status = req.To
+
+	s.mu.RLock()
+	listeners := s.listeners
+	s.mu.RUnlock()
+	for _, listener := range listeners {
+		listener(PDITransitionEvent{PDI: pdi, Transition: transition})
+	}
+
+	return &transition, nil
+}
+
+// ListTransitions devolve o histórico de status do PDI, da mudança mais antiga
+// à mais recente.
+func (s *PDIWorkflowService) ListTransitions(pdiID string) ([]models.PDITransition, error) {
+	transitions := []models.PDITransition{}
+	if err := s.db.Where("pdi_id = ?", pdiID).
+		Order("created_at ASC").
+		Find(&transitions).Error; err != nil {
+		return nil, fmt.Errorf("erro ao buscar histórico de status: %v", err)
+	}
+	return transitions, nil
+}
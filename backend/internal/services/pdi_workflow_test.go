@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+func TestPDIWorkflowService_Transition(t *testing.T) {
+	db, _, pdi := setupChatTestDB(t)
+	goals := NewGoalService(db)
+	service := NewPDIWorkflowService(db, goals)
+
+	var events []PDITransitionEvent
+	service.OnTransition(func(event PDITransitionEvent) {
+		events = append(events, event)
+	})
+
+	if _, err := service.Transition(pdi, pdi.UserID, TransitionRequest{To: "ARCHIVED"}); !errors.Is(err, ErrInvalidPDIStatus) {
+		t.Errorf("Transition() para status desconhecido error = %v, esperado ErrInvalidPDIStatus", err)
+	}
+	if _, err := service.Transition(pdi, pdi.UserID, TransitionRequest{To: models.PDIStatusInProgress}); !errors.Is(err, ErrTransitionNotAllowed) {
+		t.Errorf("Transition() DRAFT -> IN_PROGRESS error = %v, esperado ErrTransitionNotAllowed", err)
+	}
+
+	// Sem objetivos, o PDI não sai do rascunho
+	var blocked *TransitionBlockedError
+	if _, err := service.Transition(pdi, pdi.UserID, TransitionRequest{To: models.PDIStatusPending}); !errors.As(err, &blocked) {
+		t.Fatalf("Transition() sem conteúdo error = %v, esperado TransitionBlockedError", err)
+	}
+
+	if err := goals.SavePlan(pdi, []byte(`{"goals":[`+planGoal("Aprender Go")+`],"self_assessment_questions":[]}`), userEdit); err != nil {
+		t.Fatalf("SavePlan() error = %v", err)
+	}
+	for _, status := range []models.PDIStatus{models.PDIStatusPending, models.PDIStatusInProgress} {
+		if _, err := service.Transition(pdi, pdi.UserID, TransitionRequest{To: status}); err != nil {
+			t.Fatalf("Transition() para %s error = %v", status, err)
+		}
+	}
+
+	// DONE exige todos os resultados-chave atingidos
+	if _, err := service.Transition(pdi, pdi.UserID, TransitionRequest{To: models.PDIStatusDone}); !errors.As(err, &blocked) || len(blocked.Errors) != 1 {
+		t.Fatalf("Transition() com resultado-chave em aberto error = %v", err)
+	}
+	current, _ := goals.ListGoals(pdi)
+	done := true
+	if _, err := goals.UpdateKeyResult(pdi, current[0].KeyResults[0].ID.String(), KeyResultUpdate{Done: &done}, userEdit); err != nil {
+		t.Fatalf("UpdateKeyResult() error = %v", err)
+	}
+	transition, err := service.Transition(pdi, pdi.UserID, TransitionRequest{To: models.PDIStatusDone, Note: " Concluído no prazo "})
+	if err != nil {
+		t.Fatalf("Transition() para DONE error = %v", err)
+	}
+	if transition.FromStatus != models.PDIStatusInProgress || transition.Note != "Concluído no prazo" || pdi.Status != models.PDIStatusDone {
+		t.Errorf("Transition() = %+v, status do PDI %s", transition, pdi.Status)
+	}
+
+	var stored models.PDI
+	db.First(&stored, "id = ?", pdi.ID)
+	if stored.Status != models.PDIStatusDone {
+		t.Errorf("Status gravado = %s, esperado DONE", stored.Status)
+	}
+
+	history, err := service.ListTransitions(pdi.ID)
+	if err != nil {
+		t.Fatalf("ListTransitions() error = %v", err)
+	}
+	if len(history) != 3 || history[0].FromStatus != models.PDIStatusDraft || history[2].ToStatus != models.PDIStatusDone {
+		t.Errorf("ListTransitions() = %+v", history)
+	}
+	if len(events) != 3 || events[2].Transition.ID != transition.ID || events[2].PDI.ID != pdi.ID {
+		t.Errorf("Eventos = %+v", events)
+	}
+
+	// Com o status alterado por fora, a transição não sobrescreve a mudança
+	stale := stored
+	db.Model(&stored).Update("status", models.PDIStatusInProgress)
+	if _, err := service.Transition(&stale, pdi.UserID, TransitionRequest{To: models.PDIStatusInProgress}); !errors.Is(err, ErrTransitionConflict) {
+		t.Errorf("Transition() com status desatualizado error = %v, esperado ErrTransitionConflict", err)
+	}
+	if len(events) != 3 {
+		t.Errorf("Eventos após falha = %d, esperado 3", len(events))
+	}
+}
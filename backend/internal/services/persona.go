@@ -0,0 +1,266 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"meu-pdi-estrategico/backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPersonaNotFound = errors.New("persona não encontrada")
+	ErrInvalidPersona  = errors.New("persona inválida")
+)
+
+var personaSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// CreatePersonaRequest descreve uma nova versão de persona. Sem Weight, a
+// versão nova passa a ser a única servida; com Weight, as demais versões
+// mantêm seus pesos, o que permite testes A/B. Instruções, modelo,
+// temperatura e ferramentas vazios mantêm a configuração do provedor.
+type CreatePersonaRequest struct {
+	Slug         string   `json:"slug"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	Instructions string   `json:"instructions"`
+	Model        string   `json:"model"`
+	Temperature  *float64 `json:"temperature"`
+	Tools        []string `json:"tools"`
+	Weight       *int     `json:"weight"`
+}
+
+// PersonaDefaultFromEnv lê LLM_DEFAULT_PERSONA, a persona dos PDIs que não
+// escolheram uma. Sem valor, esses PDIs usam a configuração do provedor.
+func PersonaDefaultFromEnv() string {
+	return os.Getenv("LLM_DEFAULT_PERSONA")
+}
+
+type PersonaService struct {
+	db          *gorm.DB
+	defaultSlug string
+}
+
+func NewPersonaService(db *gorm.DB, defaultSlug string) *PersonaService {
+	return &PersonaService{db: db, defaultSlug: defaultSlug}
+}
+
+// ListAvailable devolve, para cada slug servido, a versão mais recente com
+// peso maior que zero. É a lista de personas que a pessoa usuária pode escolher.
+func (s *PersonaService) ListAvailable() ([]models.Persona, error) {
+	var versions []models.Persona
+	if err := s.db.Where("weight > 0").
+		Order("slug ASC, version DESC").
+		Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("erro ao buscar personas: %v", err)
+	}
+
+	personas := make([]models.Persona, 0, len(versions))
+	for _, version := range versions {
+		if len(personas) == 0 || personas[len(personas)-1].Slug != version.Slug {
+			personas = append(personas, version)
+		}
+	}
+	return personas, nil
+}
+
+// ListVersions devolve todas as versões do slug, ou de todos os slugs quando
+// ele é vazio.
+func (s *PersonaService) ListVersions(slug string) ([]models.Persona, error) {
+	query := s.db.Order("slug ASC, version ASC")
+	if slug != "" {
+		query = query.Where("slug = ?", slug)
+	}
+
+	var versions []models.Persona
+	if err := query.Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("erro ao buscar personas: %v", err)
+	}
+	return versions, nil
+}
+
+// Exists indica se o slug tem alguma versão servida.
+func (s *PersonaService) Exists(slug string) (bool, error) {
+	var count int64
+	if err := s.db.Model(&models.Persona{}).
+		Where("slug = ? AND weight > 0", slug).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("erro ao buscar persona: %v", err)
+	}
+	return count > 0, nil
+}
+
+// CreateVersion salva uma nova versão do slug. Versões existentes não são
+// alteradas, apenas seus pesos.
+func (s *PersonaService) CreateVersion(req CreatePersonaRequest) (*models.Persona, error) {
+	if err := s.validate(req); err != nil {
+		return nil, err
+	}
+
+	persona := &models.Persona{
+		Slug:         req.Slug,
+		Name:         strings.TrimSpace(req.Name),
+		Description:  req.Description,
+		Instructions: req.Instructions,
+		Model:        req.Model,
+		Temperature:  req.Temperature,
+		Tools:        req.Tools,
+		Weight:       1,
+	}
+	if req.Weight != nil {
+		persona.Weight = *req.Weight
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&models.Persona{}).
+			Where("slug = ?", req.Slug).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+		persona.Version = latest + 1
+
+		if req.Weight == nil {
+			if err := tx.Model(&models.Persona{}).
+				Where("slug = ?", req.Slug).
+				Update("weight", 0).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(persona).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar persona: %v", err)
+	}
+
+	log.Printf("[Persona] %s v%d criada com peso %d", persona.Slug, persona.Version, persona.Weight)
+	return persona, nil
+}
+
+// SetWeight altera o peso de uma versão, para testes A/B.
+func (s *PersonaService) SetWeight(slug string, version, weight int) (*models.Persona, error) {
+	if weight < 0 {
+		return nil, fmt.Errorf("%w: o peso não pode ser negativo", ErrInvalidPersona)
+	}
+
+	persona, err := s.getVersion(s.db, slug, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(persona).Update("weight", weight).Error; err != nil {
+		return nil, fmt.Errorf("erro ao atualizar persona: %v", err)
+	}
+	return persona, nil
+}
+
+// Activate torna a versão a única servida do slug. Serve para promover uma
+// versão depois de um teste A/B ou voltar a uma versão anterior.
+func (s *PersonaService) Activate(slug string, version int) (*models.Persona, error) {
+	var persona *models.Persona
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		persona, err = s.getVersion(tx, slug, version)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Persona{}).
+			Where("slug = ? AND version <> ?", slug, version).
+			Update("weight", 0).Error; err != nil {
+			return err
+		}
+		return tx.Model(persona).Update("weight", 1).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrPersonaNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("erro ao ativar persona: %v", err)
+	}
+
+	log.Printf("[Persona] %s v%d ativada", slug, version)
+	return persona, nil
+}
+
+// Resolve escolhe a versão de persona que atende o PDI. Entre as versões
+// servidas, a escolha é proporcional aos pesos e estável para cada PDI. Sem
+// persona cadastrada, devolve nil e o provedor usa sua própria configuração.
+func (s *PersonaService) Resolve(pdi *models.PDI) (*models.Persona, error) {
+	slug := pdi.Persona
+	if slug == "" {
+		slug = s.defaultSlug
+	}
+	if slug == "" {
+		return nil, nil
+	}
+
+	var versions []models.Persona
+	if err := s.db.Where("slug = ? AND weight > 0", slug).
+		Order("version ASC").
+		Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("erro ao buscar persona: %v", err)
+	}
+	if len(versions) == 0 {
+		if pdi.Persona != "" {
+			log.Printf("[Persona] Persona %s do PDI %s sem versão ativa", slug, pdi.ID)
+		}
+		return nil, nil
+	}
+
+	total := 0
+	for _, version := range versions {
+		total += version.Weight
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(pdi.ID))
+	n := int(hash.Sum32() % uint32(total))
+	for i := range versions {
+		if n < versions[i].Weight {
+			return &versions[i], nil
+		}
+		n -= versions[i].Weight
+	}
+	return &versions[len(versions)-1], nil
+}
+
+func (s *PersonaService) getVersion(db *gorm.DB, slug string, version int) (*models.Persona, error) {
+	var persona models.Persona
+	if err := db.Where("slug = ? AND version = ?", slug, version).First(&persona).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPersonaNotFound
+		}
+		return nil, fmt.Errorf("erro ao buscar persona: %v", err)
+	}
+	return &persona, nil
+}
+
+func (s *PersonaService) validate(req CreatePersonaRequest) error {
+	if !personaSlugPattern.MatchString(req.Slug) {
+		return fmt.Errorf("%w: o slug deve ter letras minúsculas, números e hífens", ErrInvalidPersona)
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return fmt.Errorf("%w: nome é obrigatório", ErrInvalidPersona)
+	}
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
+		return fmt.Errorf("%w: a temperatura deve estar entre 0 e 2", ErrInvalidPersona)
+	}
+	if req.Weight != nil && *req.Weight < 0 {
+		return fmt.Errorf("%w: o peso não pode ser negativo", ErrInvalidPersona)
+	}
+
+	tools := NewDefaultToolRegistry(s.db)
+	for _, name := range req.Tools {
+		if !tools.Has(name) {
+			return fmt.Errorf("%w: ferramenta desconhecida %s", ErrInvalidPersona, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+func createPersona(t *testing.T, service *PersonaService, req CreatePersonaRequest) *models.Persona {
+	persona, err := service.CreateVersion(req)
+	if err != nil {
+		t.Fatalf("CreateVersion() error = %v", err)
+	}
+	return persona
+}
+
+func TestPersonaService_Versions(t *testing.T) {
+	db, _, pdi := setupChatTestDB(t)
+	service := NewPersonaService(db, "career-coach")
+
+	v1 := createPersona(t, service, CreatePersonaRequest{Slug: "career-coach", Name: "Coach", Instructions: "v1"})
+	v2 := createPersona(t, service, CreatePersonaRequest{Slug: "career-coach", Name: "Coach", Instructions: "v2"})
+	if v1.Version != 1 || v2.Version != 2 {
+		t.Fatalf("Versões = %d, %d", v1.Version, v2.Version)
+	}
+
+	// A versão nova passa a ser a única servida
+	persona, err := service.Resolve(pdi)
+	if err != nil || persona == nil || persona.ID != v2.ID {
+		t.Fatalf("Resolve() = %+v, %v", persona, err)
+	}
+
+	// Voltar para a versão anterior
+	if _, err := service.Activate("career-coach", 1); err != nil {
+		t.Fatalf("Activate() error = %v", err)
+	}
+	persona, _ = service.Resolve(pdi)
+	if persona == nil || persona.ID != v1.ID {
+		t.Errorf("Resolve() após rollback = %+v", persona)
+	}
+
+	if _, err := service.Activate("career-coach", 9); !errors.Is(err, ErrPersonaNotFound) {
+		t.Errorf("Activate() error = %v, esperado ErrPersonaNotFound", err)
+	}
+
+	available, _ := service.ListAvailable()
+	if len(available) != 1 || available[0].Version != 1 {
+		t.Errorf("ListAvailable() = %+v", available)
+	}
+}
+
+func TestPersonaService_ResolveWeights(t *testing.T) {
+	db, _, _ := setupChatTestDB(t)
+	service := NewPersonaService(db, "career-coach")
+
+	createPersona(t, service, CreatePersonaRequest{Slug: "career-coach", Name: "Coach", Instructions: "A"})
+	weight := 1
+	createPersona(t, service, CreatePersonaRequest{Slug: "career-coach", Name: "Coach", Instructions: "B", Weight: &weight})
+
+	served := map[int]int{}
+	for i := 0; i < 100; i++ {
+		pdi := &models.PDI{ID: fmt.Sprintf("pdi-%d", i)}
+		first, _ := service.Resolve(pdi)
+		again, _ := service.Resolve(pdi)
+		if first.ID != again.ID {
+			t.Fatalf("Resolve() deve ser estável para o mesmo PDI")
+		}
+		served[first.Version]++
+	}
+	if served[1] == 0 || served[2] == 0 {
+		t.Errorf("Teste A/B deve servir as duas versões: %v", served)
+	}
+
+	// Sem persona cadastrada, o provedor usa a própria configuração
+	if persona, err := service.Resolve(&models.PDI{ID: "x", Persona: "outra"}); err != nil || persona != nil {
+		t.Errorf("Resolve() = %+v, %v, esperado nil", persona, err)
+	}
+
+	// Sem persona padrão, o PDI que não escolheu uma usa a configuração do provedor
+	if persona, err := NewPersonaService(db, "").Resolve(&models.PDI{ID: "x"}); err != nil || persona != nil {
+		t.Errorf("Resolve() sem persona padrão = %+v, %v, esperado nil", persona, err)
+	}
+}
+
+func TestPersonaService_Invalid(t *testing.T) {
+	db, _, _ := setupChatTestDB(t)
+	service := NewPersonaService(db, "career-coach")
+
+	temperature := 3.0
+	for _, req := range []CreatePersonaRequest{
+		{Slug: "Coach Sênior", Name: "Coach", Instructions: "x"},
+		{Slug: "coach", Instructions: "x"},
+		{Slug: "coach", Name: "Coach", Instructions: "x", Temperature: &temperature},
+		{Slug: "coach", Name: "Coach", Instructions: "x", Tools: []string{"delete_everything"}},
+	} {
+		if _, err := service.CreateVersion(req); !errors.Is(err, ErrInvalidPersona) {
+			t.Errorf("CreateVersion(%+v) error = %v, esperado ErrInvalidPersona", req, err)
+		}
+	}
+}
+
+func TestOpenAIService_Persona(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	temperature := 0.2
+	persona := createPersona(t, NewPersonaService(db, ""), CreatePersonaRequest{
+		Slug:         "tech-lead-mentor",
+		Name:         "Mentor",
+		Instructions: "Seja um tech lead",
+		Model:        "gpt-4o",
+		Temperature:  &temperature,
+		Tools:        []string{"get_pdi", "list_goals"},
+	})
+	db.Model(pdi).Where("id = ?", pdi.ID).Update("persona", "tech-lead-mentor")
+
+	provider := &recordingProvider{}
+	service := NewOpenAIService(db, provider, nil)
+
+	message, err := NewChatService(db).CreateMessage(&models.Message{PDIID: pdi.ID, Content: "Olá", Role: "user"})
+	if err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+	reply, err := service.ProcessMessage(context.Background(), message, user.ID.String())
+	if err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+
+	req := provider.last
+	if req.Instructions != "Seja um tech lead" || req.Model != "gpt-4o" || req.Temperature == nil || *req.Temperature != 0.2 {
+		t.Errorf("Requisição sem a configuração da persona: %+v", req)
+	}
+	if len(req.Tools) != 2 || !req.OverrideTools {
+		t.Errorf("Ferramentas da persona = %+v", req.Tools)
+	}
+	// Ferramentas fora da persona não são executadas
+	if output := service.tools.Subset(persona.Tools).Execute(context.Background(), ToolContext{}, ToolCall{Name: "save_pdi"}); !strings.Contains(output, ErrToolNotFound.Error()) {
+		t.Errorf("Execute(save_pdi) = %v", output)
+	}
+	if reply.PersonaID == nil || *reply.PersonaID != persona.ID {
+		t.Errorf("Resposta sem a versão da persona: %v", reply.PersonaID)
+	}
+}
+
+func TestOpenAIService_PersonaOverrides(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	db.Model(pdi).Update("persona", "career-coach")
+	personas := NewPersonaService(db, "")
+	provider := &recordingProvider{}
+	service := NewOpenAIService(db, provider, nil)
+
+	send := func() ProviderRequest {
+		message, err := NewChatService(db).CreateMessage(&models.Message{PDIID: pdi.ID, Content: "oi", Role: "user"})
+		if err != nil {
+			t.Fatalf("Erro ao criar mensagem: %v", err)
+		}
+		if _, err := service.ProcessMessage(context.Background(), message, user.ID.String()); err != nil {
+			t.Fatalf("ProcessMessage() error = %v", err)
+		}
+		return provider.last
+	}
+
+	// Sem instruções nem ferramentas próprias, valem as do provedor
+	createPersona(t, personas, CreatePersonaRequest{Slug: "career-coach", Name: "Coach"})
+	if req := send(); req.Instructions != "" || req.OverrideTools || req.Temperature != nil {
+		t.Errorf("Persona sem configuração própria substitui a do provedor: %+v", req)
+	}
+
+	temperature := 0.0
+	createPersona(t, personas, CreatePersonaRequest{Slug: "career-coach", Name: "Coach", Instructions: "v2", Temperature: &temperature, Tools: []string{"save_pdi"}})
+	req := send()
+	if req.Instructions != "v2" || !req.OverrideTools || len(req.Tools) != 1 || req.Temperature == nil || *req.Temperature != 0 {
+		t.Errorf("Persona com configuração própria = %+v", req)
+	}
+}
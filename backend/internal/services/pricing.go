@@ -0,0 +1,48 @@
+package services
+
+import (
+	"math"
+	"strings"
+)
+
+// ModelPrice é o preço em dólares por milhão de tokens.
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// modelPrices segue a tabela pública da OpenAI. Modelos com data no nome
+// (por exemplo gpt-4o-mini-2024-07-18) usam o preço do prefixo mais longo.
+var modelPrices = map[string]ModelPrice{
+	"gpt-4o":        {Input: 2.50, Output: 10.00},
+	"gpt-4o-mini":   {Input: 0.15, Output: 0.60},
+	"gpt-4.1":       {Input: 2.00, Output: 8.00},
+	"gpt-4.1-mini":  {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano":  {Input: 0.10, Output: 0.40},
+	"gpt-4-turbo":   {Input: 10.00, Output: 30.00},
+	"gpt-4":         {Input: 30.00, Output: 60.00},
+	"gpt-3.5-turbo": {Input: 0.50, Output: 1.50},
+	"o3-mini":       {Input: 1.10, Output: 4.40},
+}
+
+// PriceForModel devolve o preço do modelo; modelos desconhecidos custam zero.
+func PriceForModel(model string) (ModelPrice, bool) {
+	var price ModelPrice
+	match := ""
+	for name, candidate := range modelPrices {
+		if strings.HasPrefix(model, name) && len(name) > len(match) {
+			match, price = name, candidate
+		}
+	}
+	return price, match != ""
+}
+
+// EstimateCost calcula o custo em dólares do consumo informado.
+func EstimateCost(model string, usage Usage) float64 {
+	price, ok := PriceForModel(model)
+	if !ok {
+		return 0
+	}
+	cost := float64(usage.PromptTokens)*price.Input/1e6 + float64(usage.CompletionTokens)*price.Output/1e6
+	return math.Round(cost*1e6) / 1e6
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	ProviderAssistants = "assistants"
+	ProviderChat       = "chat"
+	ProviderFake       = "fake"
+)
+
+var (
+	ErrMissingAPIKey      = errors.New("OPENAI_API_KEY não configurada")
+	ErrMissingAssistantID = errors.New("OPENAI_ASSISTANT_ID não configurada")
+)
+
+// Turn é uma mensagem do histórico da conversa enviada ao provedor.
+type Turn struct {
+	Role    string
+	Content string
+}
+
+// ToolCall é uma chamada de função solicitada pelo modelo.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ToolDefinition descreve uma função que o modelo pode chamar.
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage
+}
+
+// ToolExecutor executa uma chamada de função e devolve a saída enviada ao modelo.
+type ToolExecutor func(ctx context.Context, call ToolCall) (string, error)
+
+// TokenHandler recebe os trechos da resposta à medida que são gerados.
+type TokenHandler func(token string)
+
+type ProviderRequest struct {
+	// ThreadID é a thread remota da conversa. Vazio, o provedor trabalha só
+	// com History e Summary.
+	ThreadID string
+	Input    string
+	// InputMessageID indica que a entrada já está na thread (por exemplo ao
+	// reenviar uma mensagem) e não deve ser adicionada de novo.
+	InputMessageID string
+	// ReplaceMessageID é a resposta anterior a remover da thread antes de
+	// gerar outra.
+	ReplaceMessageID string
+	History          []Turn
+	// Summary resume as mensagens que ficaram fora de History.
+	Summary      string
+	Instructions string
+	// Model e Temperature, quando informados, substituem os do provedor.
+	Model       string
+	Temperature *float32
+	Tools       []ToolDefinition
+	// OverrideTools faz Tools substituir as ferramentas configuradas no
+	// assistente remoto.
+	OverrideTools bool
+	ExecuteTool   ToolExecutor
+	OnToken       TokenHandler
+}
+
+type ProviderResponse struct {
+	RunID          string
+	Content        string
+	InputMessageID string
+	MessageID      string
+	Model          string
+	Usage          Usage
+}
+
+// Usage é o consumo de tokens de uma execução no provedor.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// RunError é a falha de uma execução já iniciada no provedor. Guarda os
+// identificadores necessários para auditar e reenviar a mensagem.
+type RunError struct {
+	RunID          string
+	InputMessageID string
+	Err            error
+}
+
+func (e *RunError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RunError) Unwrap() error {
+	return e.Err
+}
+
+// Provider é um backend de LLM capaz de gerar a resposta do assistente.
+type Provider interface {
+	Name() string
+	Run(ctx context.Context, req ProviderRequest) (*ProviderResponse, error)
+}
+
+// ThreadProvider é implementado pelos provedores que guardam o estado da
+// conversa em threads remotas.
+type ThreadProvider interface {
+	CreateThread(ctx context.Context) (string, error)
+	// ReplayThread cria uma thread com as mensagens informadas, na ordem, e
+	// devolve o ID da thread e os IDs das mensagens criadas nela.
+	ReplayThread(ctx context.Context, turns []Turn) (string, []string, error)
+}
+
+// NewProviderFromEnv escolhe o provedor a partir de LLM_PROVIDER.
+func NewProviderFromEnv() (Provider, error) {
+	switch name := os.Getenv("LLM_PROVIDER"); name {
+	case "", ProviderAssistants:
+		client, err := newOpenAIClientFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		assistantID := os.Getenv("OPENAI_ASSISTANT_ID")
+		// Com OPENAI_PROVISION_ASSISTANT, o assistente é criado ou atualizado
+		// a partir da definição do repositório
+		if envBool("OPENAI_PROVISION_ASSISTANT", false) {
+			result, err := ProvisionAssistantFromEnv()
+			if err != nil {
+				return nil, fmt.Errorf("erro ao provisionar assistente: %v", err)
+			}
+			assistantID = result.AssistantID
+		}
+		if assistantID == "" {
+			return nil, ErrMissingAssistantID
+		}
+		return NewAssistantsProvider(client, assistantID, RunPollConfigFromEnv()), nil
+	case ProviderChat:
+		client, err := newOpenAIClientFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return NewChatCompletionsProvider(client, os.Getenv("OPENAI_MODEL")), nil
+	case ProviderFake:
+		return NewFakeProvider(), nil
+	default:
+		return nil, fmt.Errorf("LLM_PROVIDER desconhecido: %s", name)
+	}
+}
+
+func newOpenAIClientFromEnv() (*openai.Client, error) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return nil, ErrMissingAPIKey
+	}
+	return openai.NewClient(apiKey), nil
+}
@@ -0,0 +1,411 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+var (
+	ErrRunTimeout   = errors.New("tempo limite do run excedido")
+	ErrRunCancelled = errors.New("run cancelado")
+	ErrRunStuck     = errors.New("run preso aguardando ferramentas")
+)
+
+// RunPollConfig controla a espera pelo término de um run: prazo total,
+// intervalo inicial entre consultas, crescimento exponencial até MaxInterval
+// e quantas rodadas de ferramentas o run pode pedir.
+type RunPollConfig struct {
+	Timeout         time.Duration
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	MaxToolRounds   int
+}
+
+// RunPollConfigFromEnv lê OPENAI_RUN_TIMEOUT, OPENAI_POLL_INTERVAL,
+// OPENAI_POLL_MAX_INTERVAL e OPENAI_MAX_TOOL_ROUNDS.
+func RunPollConfigFromEnv() RunPollConfig {
+	return RunPollConfig{
+		Timeout:         envDuration("OPENAI_RUN_TIMEOUT", 2*time.Minute),
+		InitialInterval: envDuration("OPENAI_POLL_INTERVAL", 200*time.Millisecond),
+		MaxInterval:     envDuration("OPENAI_POLL_MAX_INTERVAL", 2*time.Second),
+		Multiplier:      2,
+		MaxToolRounds:   envInt("OPENAI_MAX_TOOL_ROUNDS", maxToolRounds),
+	}
+}
+
+func (c RunPollConfig) withDefaults() RunPollConfig {
+	if c.Timeout <= 0 {
+		c.Timeout = 2 * time.Minute
+	}
+	if c.InitialInterval <= 0 {
+		c.InitialInterval = 200 * time.Millisecond
+	}
+	if c.MaxInterval < c.InitialInterval {
+		c.MaxInterval = c.InitialInterval
+	}
+	if c.Multiplier < 1 {
+		c.Multiplier = 1
+	}
+	if c.MaxToolRounds <= 0 {
+		c.MaxToolRounds = maxToolRounds
+	}
+	return c
+}
+
+// AssistantsProvider usa a API de Assistants da OpenAI, com o histórico
+// guardado em threads. O run é acompanhado por consulta (waitRun), não pela
+// API de streaming de runs, que o cliente go-openai usado aqui não oferece: a
+// resposta chega a OnToken de uma vez, quando o run termina.
+type AssistantsProvider struct {
+	client      *openai.Client
+	assistantID string
+	poll        RunPollConfig
+}
+
+func NewAssistantsProvider(client *openai.Client, assistantID string, poll RunPollConfig) *AssistantsProvider {
+	return &AssistantsProvider{
+		client:      client,
+		assistantID: assistantID,
+		poll:        poll.withDefaults(),
+	}
+}
+
+func (p *AssistantsProvider) Name() string {
+	return ProviderAssistants
+}
+
+func (p *AssistantsProvider) CreateThread(ctx context.Context) (string, error) {
+	thread, err := p.client.CreateThread(ctx, openai.ThreadRequest{})
+	if err != nil {
+		log.Printf("[OpenAI] Erro ao criar thread: %v", err)
+		return "", fmt.Errorf("erro ao criar thread: %v", err)
+	}
+	log.Printf("[OpenAI] Novo thread criado com ID: %s", thread.ID)
+	return thread.ID, nil
+}
+
+// ReplayThread adiciona as mensagens uma a uma, já que a criação da thread
+// aceita poucas mensagens e não devolve os IDs de cada uma. Se alguma falhar,
+// a thread incompleta é removida.
+func (p *AssistantsProvider) ReplayThread(ctx context.Context, turns []Turn) (string, []string, error) {
+	threadID, err := p.CreateThread(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	messageIDs := make([]string, 0, len(turns))
+	for _, turn := range turns {
+		role := openai.ChatMessageRoleUser
+		if turn.Role == openai.ChatMessageRoleAssistant {
+			role = openai.ChatMessageRoleAssistant
+		}
+		message, err := p.client.CreateMessage(ctx, threadID, openai.MessageRequest{
+			Role:    role,
+			Content: turn.Content,
+		})
+		if err != nil {
+			log.Printf("[OpenAI] Erro ao reconstruir thread %s: %v", threadID, err)
+			p.deleteThread(threadID)
+			return "", nil, fmt.Errorf("erro ao adicionar mensagem ao thread: %v", err)
+		}
+		messageIDs = append(messageIDs, message.ID)
+	}
+
+	log.Printf("[OpenAI] Thread %s reconstruído com %d mensagens", threadID, len(turns))
+	return threadID, messageIDs, nil
+}
+
+// createEphemeralThread cria uma thread com o histórico informado, usada em
+// uma única execução quando o contexto é gerenciado pela aplicação.
+func (p *AssistantsProvider) createEphemeralThread(ctx context.Context, history []Turn) (string, error) {
+	messages := make([]openai.ThreadMessage, 0, len(history))
+	for _, turn := range history {
+		role := openai.ThreadMessageRoleUser
+		if turn.Role == openai.ChatMessageRoleAssistant {
+			role = openai.ThreadMessageRoleAssistant
+		}
+		messages = append(messages, openai.ThreadMessage{Role: role, Content: turn.Content})
+	}
+
+	thread, err := p.client.CreateThread(ctx, openai.ThreadRequest{Messages: messages})
+	if err != nil {
+		log.Printf("[OpenAI] Erro ao criar thread: %v", err)
+		return "", fmt.Errorf("erro ao criar thread: %v", err)
+	}
+	return thread.ID, nil
+}
+
+func (p *AssistantsProvider) deleteThread(threadID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := p.client.DeleteThread(ctx, threadID); err != nil {
+		log.Printf("[OpenAI] Erro ao remover thread %s: %v", threadID, err)
+	}
+}
+
+func (p *AssistantsProvider) Run(ctx context.Context, req ProviderRequest) (*ProviderResponse, error) {
+	threadID := req.ThreadID
+	if threadID == "" {
+		// Sem thread persistente: o contexto vem inteiro de History
+		ephemeral, err := p.createEphemeralThread(ctx, req.History)
+		if err != nil {
+			return nil, err
+		}
+		defer p.deleteThread(ephemeral)
+		threadID = ephemeral
+	}
+	// IDs de uma thread descartável não servem para reenviar nem auditar
+	persistent := req.ThreadID != ""
+
+	if req.ReplaceMessageID != "" {
+		log.Printf("[OpenAI] Removendo resposta anterior %s do thread...", req.ReplaceMessageID)
+		if _, err := p.client.DeleteMessage(ctx, threadID, req.ReplaceMessageID); err != nil {
+			log.Printf("[OpenAI] Erro ao remover resposta anterior: %v", err)
+		}
+	}
+
+	inputID := req.InputMessageID
+	if inputID == "" {
+		// Adicionar a mensagem do usuário ao thread
+		log.Printf("[OpenAI] Adicionando mensagem do usuário ao thread...")
+		input, err := p.client.CreateMessage(ctx, threadID, openai.MessageRequest{
+			Role:    openai.ChatMessageRoleUser,
+			Content: req.Input,
+		})
+		if err != nil {
+			log.Printf("[OpenAI] Erro ao adicionar mensagem ao thread: %v", err)
+			return nil, fmt.Errorf("erro ao adicionar mensagem ao thread: %v", err)
+		}
+		inputID = input.ID
+		log.Printf("[OpenAI] Mensagem do usuário adicionada com sucesso")
+	} else {
+		log.Printf("[OpenAI] Mensagem do usuário já está no thread: %s", inputID)
+	}
+
+	// Criar e executar o run
+	log.Printf("[OpenAI] Criando run com AssistantID: %s", p.assistantID)
+	runRequest := openai.RunRequest{
+		AssistantID:  p.assistantID,
+		Instructions: req.Instructions,
+	}
+	if req.Summary != "" {
+		runRequest.AdditionalInstructions = summaryPrompt(req.Summary)
+	}
+	if req.Model != "" {
+		runRequest.Model = req.Model
+	}
+	runRequest.Temperature = req.Temperature
+	if req.OverrideTools {
+		runRequest.Tools = openAITools(req.Tools)
+	}
+	run, err := p.client.CreateRun(ctx, threadID, runRequest)
+	if err != nil {
+		log.Printf("[OpenAI] Erro ao criar run: %v", err)
+		if !persistent {
+			return nil, fmt.Errorf("erro ao criar run: %v", err)
+		}
+		return nil, &RunError{InputMessageID: inputID, Err: fmt.Errorf("erro ao criar run: %v", err)}
+	}
+	log.Printf("[OpenAI] Run criado com ID: %s", run.ID)
+
+	runErr := func(err error) error {
+		if !persistent {
+			return &RunError{RunID: run.ID, Err: err}
+		}
+		return &RunError{RunID: run.ID, InputMessageID: inputID, Err: err}
+	}
+
+	// Aguardar a conclusão do run
+	log.Printf("[OpenAI] Aguardando processamento do run...")
+	run, err = p.waitRun(ctx, threadID, run, req.ExecuteTool)
+	if err != nil {
+		return nil, runErr(err)
+	}
+
+	if run.Status != openai.RunStatusCompleted {
+		lastError := ""
+		if run.LastError != nil {
+			lastError = run.LastError.Message
+		}
+		log.Printf("[OpenAI] Run falhou com status: %s %s", run.Status, lastError)
+		return nil, runErr(fmt.Errorf("erro ao processar com status na OpenAI: %v", run.Status))
+	}
+	log.Printf("[OpenAI] Run concluído com sucesso")
+
+	// Buscar a última mensagem do assistente
+	log.Printf("[OpenAI] Buscando resposta do assistente...")
+	numMessages := 1
+	iaMessages, err := p.client.ListMessage(ctx, threadID, &numMessages, nil, nil, nil, nil)
+	if err != nil {
+		log.Printf("[OpenAI] Erro ao buscar mensagem do assistente: %v", err)
+		return nil, runErr(fmt.Errorf("erro ao buscar mensagem do assistente: %v", err))
+	}
+	if len(iaMessages.Messages) == 0 || len(iaMessages.Messages[0].Content) == 0 || iaMessages.Messages[0].Content[0].Text == nil {
+		return nil, runErr(fmt.Errorf("resposta do assistente vazia"))
+	}
+	log.Printf("[OpenAI] Resposta do assistente recebida")
+
+	content := iaMessages.Messages[0].Content[0].Text.Value
+	// Sem streaming de runs, o texto inteiro vai como um único trecho
+	if req.OnToken != nil {
+		req.OnToken(content)
+	}
+
+	response := &ProviderResponse{
+		RunID:   run.ID,
+		Content: content,
+		Model:   run.Model,
+		Usage: Usage{
+			PromptTokens:     run.Usage.PromptTokens,
+			CompletionTokens: run.Usage.CompletionTokens,
+			TotalTokens:      run.Usage.TotalTokens,
+		},
+	}
+	if persistent {
+		response.InputMessageID = inputID
+		response.MessageID = iaMessages.Messages[0].ID
+	}
+	return response, nil
+}
+
+// waitRun consulta o run com backoff exponencial até ele sair dos estados
+// ativos, executando as ferramentas pedidas. Se o prazo acabar, o contexto
+// for cancelado, as respostas das ferramentas não puderem ser enviadas ou o
+// run repetir chamadas já respondidas, o run é cancelado no provedor.
+func (p *AssistantsProvider) waitRun(ctx context.Context, threadID string, run openai.Run, execute ToolExecutor) (openai.Run, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.poll.Timeout)
+	defer cancel()
+
+	interval := p.poll.InitialInterval
+	answered := make(map[string]bool)
+	rounds := 0
+
+	for {
+		switch run.Status {
+		case openai.RunStatusQueued, openai.RunStatusInProgress, openai.RunStatusCancelling:
+		case openai.RunStatusRequiresAction:
+			if run.RequiredAction == nil {
+				return run, p.abortRun(threadID, run.ID, ErrRunStuck)
+			}
+			calls := run.RequiredAction.SubmitToolOutputs.ToolCalls
+			for _, call := range calls {
+				if answered[call.ID] {
+					log.Printf("[OpenAI] Run %s pediu novamente a ferramenta %s já respondida", run.ID, call.ID)
+					return run, p.abortRun(threadID, run.ID, ErrRunStuck)
+				}
+			}
+			rounds++
+			if rounds > p.poll.MaxToolRounds {
+				log.Printf("[OpenAI] Run %s excedeu %d rodadas de ferramentas", run.ID, p.poll.MaxToolRounds)
+				return run, p.abortRun(threadID, run.ID, ErrRunStuck)
+			}
+
+			log.Printf("[OpenAI] Run requer ação. Executando ferramentas...")
+			submitted, err := p.submitTools(ctx, threadID, run, execute)
+			if err != nil {
+				if ctx.Err() != nil {
+					return run, p.abortRun(threadID, run.ID, contextError(ctx))
+				}
+				// Sem as respostas, o run ficaria parado em requires_action até expirar
+				return run, p.abortRun(threadID, run.ID, err)
+			}
+			for _, call := range calls {
+				answered[call.ID] = true
+			}
+			run = submitted
+			interval = p.poll.InitialInterval
+			continue
+		default:
+			return run, nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return run, p.abortRun(threadID, run.ID, contextError(ctx))
+		case <-timer.C:
+		}
+
+		retrieved, err := p.client.RetrieveRun(ctx, threadID, run.ID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return run, p.abortRun(threadID, run.ID, contextError(ctx))
+			}
+			log.Printf("[OpenAI] Erro ao verificar status do run: %v", err)
+			return run, fmt.Errorf("erro ao processar mensagem com OpenAI: %v", err)
+		}
+		run = retrieved
+		log.Printf("[OpenAI] Status atual do run: %s", run.Status)
+
+		interval = time.Duration(float64(interval) * p.poll.Multiplier)
+		if interval > p.poll.MaxInterval {
+			interval = p.poll.MaxInterval
+		}
+	}
+}
+
+func (p *AssistantsProvider) submitTools(ctx context.Context, threadID string, run openai.Run, execute ToolExecutor) (openai.Run, error) {
+	var toolOutputs []openai.ToolOutput
+	for _, tool := range run.RequiredAction.SubmitToolOutputs.ToolCalls {
+		if tool.Type != openai.ToolTypeFunction {
+			continue
+		}
+
+		output := "ok"
+		if execute != nil {
+			var err error
+			output, err = execute(ctx, ToolCall{
+				ID:        tool.ID,
+				Name:      tool.Function.Name,
+				Arguments: tool.Function.Arguments,
+			})
+			if err != nil {
+				return run, err
+			}
+		}
+
+		toolOutputs = append(toolOutputs, openai.ToolOutput{
+			ToolCallID: tool.ID,
+			Output:     output,
+		})
+	}
+
+	// Submeter as respostas
+	submitted, err := p.client.SubmitToolOutputs(ctx, threadID, run.ID, openai.SubmitToolOutputsRequest{
+		ToolOutputs: toolOutputs,
+	})
+	if err != nil {
+		log.Printf("[OpenAI] Erro ao submeter respostas das ferramentas: %v", err)
+		return run, fmt.Errorf("erro ao submeter respostas das ferramentas: %v", err)
+	}
+	log.Printf("[OpenAI] Respostas das ferramentas submetidas com sucesso")
+	return submitted, nil
+}
+
+// abortRun cancela o run no provedor e devolve reason. O cancelamento usa um
+// contexto próprio, já que o da requisição pode ter terminado.
+func (p *AssistantsProvider) abortRun(threadID, runID string, reason error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	log.Printf("[OpenAI] Cancelando run %s: %v", runID, reason)
+	if _, err := p.client.CancelRun(ctx, threadID, runID); err != nil {
+		log.Printf("[OpenAI] Erro ao cancelar run: %v", err)
+	}
+	return reason
+}
+
+// contextError traduz o término do contexto em ErrRunTimeout ou ErrRunCancelled.
+func contextError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrRunTimeout
+	}
+	return ErrRunCancelled
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// fakeAssistantsAPI simula os endpoints de threads e runs usados pelo
+// AssistantsProvider. status define o estado devolvido a cada consulta do run.
+type fakeAssistantsAPI struct {
+	mu        sync.Mutex
+	status    func(retrieves int) openai.Run
+	retrieves int
+	submits   int
+	messages  int
+	cancelled bool
+	// failSubmit faz o envio das respostas das ferramentas falhar
+	failSubmit bool
+}
+
+func (f *fakeAssistantsAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+	switch {
+	case r.Method == http.MethodPost && path == "/threads":
+		json.NewEncoder(w).Encode(openai.Thread{ID: "thread-1"})
+	case r.Method == http.MethodPost && path == "/threads/thread-1/messages":
+		f.messages++
+		json.NewEncoder(w).Encode(openai.Message{ID: fmt.Sprintf("msg-%d", f.messages)})
+	case r.Method == http.MethodPost && path == "/threads/thread-1/runs":
+		json.NewEncoder(w).Encode(openai.Run{ID: "run-1", Status: openai.RunStatusQueued})
+	case r.Method == http.MethodGet && path == "/threads/thread-1/runs/run-1":
+		f.retrieves++
+		json.NewEncoder(w).Encode(f.status(f.retrieves))
+	case r.Method == http.MethodPost && path == "/threads/thread-1/runs/run-1/submit_tool_outputs":
+		f.submits++
+		if f.failSubmit {
+			http.Error(w, `{"error":{"message":"falha no envio"}}`, http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(openai.Run{ID: "run-1", Status: openai.RunStatusQueued})
+	case r.Method == http.MethodPost && path == "/threads/thread-1/runs/run-1/cancel":
+		f.cancelled = true
+		json.NewEncoder(w).Encode(openai.Run{ID: "run-1", Status: openai.RunStatusCancelling})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// calls devolve os contadores sob o mutex, já que o servidor roda em outra goroutine.
+func (f *fakeAssistantsAPI) calls() (retrieves, submits int, cancelled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.retrieves, f.submits, f.cancelled
+}
+
+func newTestAssistantsProvider(t *testing.T, api *fakeAssistantsAPI, poll RunPollConfig) *AssistantsProvider {
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	config := openai.DefaultConfig("test")
+	config.BaseURL = server.URL + "/v1"
+	return NewAssistantsProvider(openai.NewClientWithConfig(config), "asst-1", poll)
+}
+
+func TestAssistantsProvider_RunTimeout(t *testing.T) {
+	api := &fakeAssistantsAPI{status: func(int) openai.Run {
+		return openai.Run{ID: "run-1", Status: openai.RunStatusInProgress}
+	}}
+	provider := newTestAssistantsProvider(t, api, RunPollConfig{
+		Timeout:         100 * time.Millisecond,
+		InitialInterval: 5 * time.Millisecond,
+		MaxInterval:     20 * time.Millisecond,
+		Multiplier:      2,
+	})
+
+	_, err := provider.Run(context.Background(), ProviderRequest{ThreadID: "thread-1", Input: "Olá"})
+
+	var runErr *RunError
+	if !errors.As(err, &runErr) || runErr.RunID != "run-1" || !errors.Is(err, ErrRunTimeout) {
+		t.Fatalf("Run() error = %v, esperado ErrRunTimeout do run-1", err)
+	}
+	retrieves, _, cancelled := api.calls()
+	if !cancelled {
+		t.Error("Run não foi cancelado no provedor")
+	}
+	// Com backoff, o número de consultas fica bem abaixo de timeout/intervalo inicial
+	if retrieves >= 20 {
+		t.Errorf("Consultas demais ao run: %d", retrieves)
+	}
+}
+
+func TestAssistantsProvider_RunCancelled(t *testing.T) {
+	api := &fakeAssistantsAPI{status: func(int) openai.Run {
+		return openai.Run{ID: "run-1", Status: openai.RunStatusInProgress}
+	}}
+	provider := newTestAssistantsProvider(t, api, RunPollConfig{InitialInterval: 5 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(30*time.Millisecond, cancel)
+
+	_, err := provider.Run(ctx, ProviderRequest{ThreadID: "thread-1", Input: "Olá"})
+	if !errors.Is(err, ErrRunCancelled) {
+		t.Fatalf("Run() error = %v, esperado ErrRunCancelled", err)
+	}
+	if _, _, cancelled := api.calls(); !cancelled {
+		t.Error("Run não foi cancelado no provedor")
+	}
+}
+
+func TestAssistantsProvider_RunStuckRequiresAction(t *testing.T) {
+	api := &fakeAssistantsAPI{status: func(int) openai.Run {
+		return openai.Run{
+			ID:     "run-1",
+			Status: openai.RunStatusRequiresAction,
+			RequiredAction: &openai.RunRequiredAction{
+				Type: openai.RequiredActionTypeSubmitToolOutputs,
+				SubmitToolOutputs: &openai.SubmitToolOutputs{ToolCalls: []openai.ToolCall{{
+					ID:       "call-1",
+					Type:     openai.ToolTypeFunction,
+					Function: openai.FunctionCall{Name: "list_goals", Arguments: "{}"},
+				}}},
+			},
+		}
+	}}
+	provider := newTestAssistantsProvider(t, api, RunPollConfig{InitialInterval: time.Millisecond})
+
+	_, err := provider.Run(context.Background(), ProviderRequest{
+		ThreadID: "thread-1",
+		Input:    "Olá",
+		ExecuteTool: func(ctx context.Context, call ToolCall) (string, error) {
+			return `{"goals":[]}`, nil
+		},
+	})
+	if !errors.Is(err, ErrRunStuck) {
+		t.Fatalf("Run() error = %v, esperado ErrRunStuck", err)
+	}
+	if _, submits, cancelled := api.calls(); submits != 1 || !cancelled {
+		t.Errorf("submits = %d, cancelled = %v", submits, cancelled)
+	}
+}
+
+func TestAssistantsProvider_SubmitToolOutputsFails(t *testing.T) {
+	api := &fakeAssistantsAPI{failSubmit: true, status: func(int) openai.Run {
+		return openai.Run{
+			ID:     "run-1",
+			Status: openai.RunStatusRequiresAction,
+			RequiredAction: &openai.RunRequiredAction{
+				Type: openai.RequiredActionTypeSubmitToolOutputs,
+				SubmitToolOutputs: &openai.SubmitToolOutputs{ToolCalls: []openai.ToolCall{{
+					ID:       "call-1",
+					Type:     openai.ToolTypeFunction,
+					Function: openai.FunctionCall{Name: "list_goals", Arguments: "{}"},
+				}}},
+			},
+		}
+	}}
+	provider := newTestAssistantsProvider(t, api, RunPollConfig{InitialInterval: time.Millisecond})
+
+	_, err := provider.Run(context.Background(), ProviderRequest{
+		ThreadID: "thread-1",
+		Input:    "Olá",
+		ExecuteTool: func(ctx context.Context, call ToolCall) (string, error) {
+			return `{"goals":[]}`, nil
+		},
+	})
+	var runErr *RunError
+	if !errors.As(err, &runErr) || !strings.Contains(err.Error(), "submeter respostas") {
+		t.Fatalf("Run() error = %v, esperado erro ao submeter respostas", err)
+	}
+	if _, submits, cancelled := api.calls(); submits != 1 || !cancelled {
+		t.Errorf("submits = %d, cancelled = %v", submits, cancelled)
+	}
+}
+
+func TestAssistantsProvider_ReplayThread(t *testing.T) {
+	provider := newTestAssistantsProvider(t, &fakeAssistantsAPI{}, RunPollConfig{})
+
+	threadID, ids, err := provider.ReplayThread(context.Background(), []Turn{
+		{Role: "user", Content: "Olá"},
+		{Role: "assistant", Content: "Oi!"},
+	})
+	if err != nil {
+		t.Fatalf("ReplayThread() error = %v", err)
+	}
+	if threadID != "thread-1" || len(ids) != 2 || ids[0] != "msg-1" || ids[1] != "msg-2" {
+		t.Errorf("ReplayThread() = %q, %v", threadID, ids)
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultChatModel = openai.GPT4oMini
+	maxToolRounds    = 5
+)
+
+// ChatCompletionsProvider usa a API de Chat Completions da OpenAI. Não há
+// estado remoto: o histórico é enviado em toda requisição.
+type ChatCompletionsProvider struct {
+	client       *openai.Client
+	model        string
+	instructions string
+}
+
+func NewChatCompletionsProvider(client *openai.Client, model string) *ChatCompletionsProvider {
+	if model == "" {
+		model = defaultChatModel
+	}
+	return &ChatCompletionsProvider{
+		client:       client,
+		model:        model,
+		instructions: defaultCoachInstructions,
+	}
+}
+
+func (p *ChatCompletionsProvider) Name() string {
+	return ProviderChat
+}
+
+func (p *ChatCompletionsProvider) Run(ctx context.Context, req ProviderRequest) (*ProviderResponse, error) {
+	instructions := req.Instructions
+	if instructions == "" {
+		instructions = p.instructions
+	}
+
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: instructions},
+	}
+	if req.Summary != "" {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: summaryPrompt(req.Summary)})
+	}
+	for _, turn := range req.History {
+		messages = append(messages, openai.ChatCompletionMessage{Role: turn.Role, Content: turn.Content})
+	}
+	if req.Input != "" {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: req.Input})
+	}
+
+	request := openai.ChatCompletionRequest{
+		Model:         p.model,
+		Tools:         openAITools(req.Tools),
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	}
+	if req.Model != "" {
+		request.Model = req.Model
+	}
+	if req.Temperature != nil {
+		request.Temperature = chatTemperature(*req.Temperature)
+	}
+
+	// O consumo soma todas as rodadas de chamadas de ferramentas
+	var usage Usage
+	for round := 0; round < maxToolRounds; round++ {
+		request.Messages = messages
+		result, err := p.complete(ctx, request, req.OnToken)
+		if err != nil {
+			log.Printf("[OpenAI] Erro ao gerar resposta: %v", err)
+			return nil, fmt.Errorf("erro ao processar mensagem com OpenAI: %v", err)
+		}
+		usage.Add(result.usage)
+
+		reply := result.reply
+		if len(reply.ToolCalls) == 0 {
+			return &ProviderResponse{
+				RunID:   result.id,
+				Content: reply.Content,
+				Model:   result.model,
+				Usage:   usage,
+			}, nil
+		}
+
+		messages = append(messages, reply)
+		for _, tool := range reply.ToolCalls {
+			output := "ok"
+			if req.ExecuteTool != nil {
+				output, err = req.ExecuteTool(ctx, ToolCall{
+					ID:        tool.ID,
+					Name:      tool.Function.Name,
+					Arguments: tool.Function.Arguments,
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    output,
+				ToolCallID: tool.ID,
+			})
+		}
+	}
+
+	return nil, fmt.Errorf("limite de chamadas de ferramentas excedido")
+}
+
+// openAITools converte as definições de ferramentas para o formato da OpenAI.
+func openAITools(definitions []ToolDefinition) []openai.Tool {
+	var tools []openai.Tool
+	for _, def := range definitions {
+		tools = append(tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        def.Name,
+				Description: def.Description,
+				Parameters:  def.Parameters,
+			},
+		})
+	}
+	return tools
+}
+
+type completion struct {
+	id    string
+	model string
+	reply openai.ChatCompletionMessage
+	usage Usage
+}
+
+// complete executa uma rodada de Chat Completions em modo streaming,
+// repassando o texto a onToken e remontando as chamadas de função.
+func (p *ChatCompletionsProvider) complete(ctx context.Context, request openai.ChatCompletionRequest, onToken TokenHandler) (*completion, error) {
+	result := &completion{model: request.Model}
+	reply := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+
+	stream, err := p.client.CreateChatCompletionStream(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result.id = chunk.ID
+		if chunk.Model != "" {
+			result.model = chunk.Model
+		}
+		// Com include_usage o último chunk traz o consumo e nenhuma escolha
+		if chunk.Usage != nil {
+			result.usage = Usage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			}
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta
+		if delta.Content != "" {
+			content.WriteString(delta.Content)
+			if onToken != nil {
+				onToken(delta.Content)
+			}
+		}
+
+		for _, call := range delta.ToolCalls {
+			index := len(reply.ToolCalls)
+			if call.Index != nil {
+				index = *call.Index
+			}
+			for len(reply.ToolCalls) <= index {
+				reply.ToolCalls = append(reply.ToolCalls, openai.ToolCall{Type: openai.ToolTypeFunction})
+			}
+			if call.ID != "" {
+				reply.ToolCalls[index].ID = call.ID
+			}
+			reply.ToolCalls[index].Function.Name += call.Function.Name
+			reply.ToolCalls[index].Function.Arguments += call.Function.Arguments
+		}
+	}
+
+	reply.Content = content.String()
+	result.reply = reply
+	return result, nil
+}
+
+// chatTemperature ajusta a temperatura para o Chat Completions. O campo é
+// omitido do JSON quando zero, e o servidor aplicaria a temperatura padrão;
+// o menor valor positivo tem o mesmo efeito de zero.
+func chatTemperature(temperature float32) float32 {
+	if temperature == 0 {
+		return math.SmallestNonzeroFloat32
+	}
+	return temperature
+}
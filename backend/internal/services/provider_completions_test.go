@@ -0,0 +1,26 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestChatTemperature(t *testing.T) {
+	if temperature := chatTemperature(0.7); temperature != 0.7 {
+		t.Errorf("chatTemperature(0.7) = %v", temperature)
+	}
+
+	// Temperatura zero precisa chegar ao JSON da requisição
+	request := openai.ChatCompletionRequest{Model: defaultChatModel, Temperature: chatTemperature(0)}
+	if request.Temperature != math.SmallestNonzeroFloat32 {
+		t.Errorf("chatTemperature(0) = %v", request.Temperature)
+	}
+	body, _ := json.Marshal(request)
+	if !strings.Contains(string(body), `"temperature"`) {
+		t.Errorf("Temperatura zero omitida da requisição: %s", body)
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FakeReply é uma resposta roteirizada do FakeProvider.
+type FakeReply struct {
+	Content   string
+	ToolCalls []ToolCall
+}
+
+// FakeProvider é um provedor determinístico para testes e desenvolvimento
+// local sem chave da OpenAI. Devolve as respostas roteirizadas em ordem e,
+// depois delas, ecoa a mensagem recebida.
+type FakeProvider struct {
+	mu      sync.Mutex
+	replies []FakeReply
+	runs    int
+}
+
+func NewFakeProvider(replies ...FakeReply) *FakeProvider {
+	return &FakeProvider{replies: replies}
+}
+
+func (p *FakeProvider) Name() string {
+	return ProviderFake
+}
+
+func (p *FakeProvider) Run(ctx context.Context, req ProviderRequest) (*ProviderResponse, error) {
+	p.mu.Lock()
+	p.runs++
+	run := p.runs
+	reply := FakeReply{Content: fmt.Sprintf("Resposta simulada: %s", req.Input)}
+	if len(p.replies) > 0 {
+		reply = p.replies[0]
+		p.replies = p.replies[1:]
+	}
+	p.mu.Unlock()
+
+	for i, call := range reply.ToolCalls {
+		if call.ID == "" {
+			call.ID = fmt.Sprintf("fake-call-%d-%d", run, i+1)
+		}
+		if req.ExecuteTool == nil {
+			continue
+		}
+		if _, err := req.ExecuteTool(ctx, call); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.OnToken != nil {
+		for _, token := range strings.SplitAfter(reply.Content, " ") {
+			req.OnToken(token)
+		}
+	}
+
+	// Contagem aproximada: uma palavra equivale a um token
+	prompt := len(strings.Fields(req.Input))
+	for _, turn := range req.History {
+		prompt += len(strings.Fields(turn.Content))
+	}
+	completion := len(strings.Fields(reply.Content))
+
+	return &ProviderResponse{
+		RunID:   fmt.Sprintf("fake-run-%d", run),
+		Content: reply.Content,
+		Model:   ProviderFake,
+		Usage: Usage{
+			PromptTokens:     prompt,
+			CompletionTokens: completion,
+			TotalTokens:      prompt + completion,
+		},
+	}, nil
+}
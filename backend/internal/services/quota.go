@@ -0,0 +1,178 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"meu-pdi-estrategico/backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	QuotaMessagesPerDay  = "messages_per_day"
+	QuotaTokensPerMonth  = "tokens_per_month"
+	QuotaMonthlySpendUSD = "max_monthly_spend_usd"
+)
+
+// QuotaExceededError indica que o usuário atingiu um limite do plano.
+type QuotaExceededError struct {
+	Limit   string
+	Used    float64
+	Max     float64
+	ResetAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	switch e.Limit {
+	case QuotaMessagesPerDay:
+		return fmt.Sprintf("limite diário de %.0f mensagens atingido", e.Max)
+	case QuotaTokensPerMonth:
+		return fmt.Sprintf("limite mensal de %.0f tokens atingido", e.Max)
+	default:
+		return fmt.Sprintf("limite mensal de gastos de US$ %.2f atingido", e.Max)
+	}
+}
+
+// QuotaStatus é o uso atual do usuário frente aos limites do plano.
+type QuotaStatus struct {
+	Plan               string    `json:"plan"`
+	MessagesToday      int64     `json:"messages_today"`
+	MessagesPerDay     int       `json:"messages_per_day"`
+	TokensThisMonth    int64     `json:"tokens_this_month"`
+	TokensPerMonth     int64     `json:"tokens_per_month"`
+	SpendThisMonthUSD  float64   `json:"spend_this_month_usd"`
+	MaxMonthlySpendUSD float64   `json:"max_monthly_spend_usd"`
+	DailyResetAt       time.Time `json:"daily_reset_at"`
+	MonthlyResetAt     time.Time `json:"monthly_reset_at"`
+}
+
+// QuotaDefaultsFromEnv lê os limites usados quando o plano do usuário não
+// está cadastrado em quota_plans: QUOTA_MESSAGES_PER_DAY,
+// QUOTA_TOKENS_PER_MONTH e QUOTA_MAX_MONTHLY_SPEND_USD. Zero não limita.
+func QuotaDefaultsFromEnv() models.QuotaPlan {
+	return models.QuotaPlan{
+		MessagesPerDay:     envInt("QUOTA_MESSAGES_PER_DAY", 0),
+		TokensPerMonth:     int64(envInt("QUOTA_TOKENS_PER_MONTH", 0)),
+		MaxMonthlySpendUSD: envFloat("QUOTA_MAX_MONTHLY_SPEND_USD", 0),
+	}
+}
+
+// QuotaService aplica os limites diários e mensais de uso do LLM. Os dias e
+// meses são contados em UTC.
+type QuotaService struct {
+	db       *gorm.DB
+	usage    *UsageService
+	defaults models.QuotaPlan
+	now      func() time.Time
+}
+
+func NewQuotaService(db *gorm.DB, defaults models.QuotaPlan) *QuotaService {
+	return &QuotaService{
+		db:       db,
+		usage:    NewUsageService(db),
+		defaults: defaults,
+		now:      time.Now,
+	}
+}
+
+// Check devolve um *QuotaExceededError se o usuário não puder enviar mais
+// mensagens ao assistente.
+func (s *QuotaService) Check(userID string) error {
+	status, err := s.Status(userID)
+	if err != nil {
+		return err
+	}
+
+	if status.MessagesPerDay > 0 && status.MessagesToday >= int64(status.MessagesPerDay) {
+		return &QuotaExceededError{
+			Limit:   QuotaMessagesPerDay,
+			Used:    float64(status.MessagesToday),
+			Max:     float64(status.MessagesPerDay),
+			ResetAt: status.DailyResetAt,
+		}
+	}
+	if status.TokensPerMonth > 0 && status.TokensThisMonth >= status.TokensPerMonth {
+		return &QuotaExceededError{
+			Limit:   QuotaTokensPerMonth,
+			Used:    float64(status.TokensThisMonth),
+			Max:     float64(status.TokensPerMonth),
+			ResetAt: status.MonthlyResetAt,
+		}
+	}
+	if status.MaxMonthlySpendUSD > 0 && status.SpendThisMonthUSD >= status.MaxMonthlySpendUSD {
+		return &QuotaExceededError{
+			Limit:   QuotaMonthlySpendUSD,
+			Used:    status.SpendThisMonthUSD,
+			Max:     status.MaxMonthlySpendUSD,
+			ResetAt: status.MonthlyResetAt,
+		}
+	}
+
+	return nil
+}
+
+// Status calcula o uso do dia e do mês corrente do usuário.
+func (s *QuotaService) Status(userID string) (*QuotaStatus, error) {
+	plan, err := s.planFor(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	status := &QuotaStatus{
+		Plan:               plan.Name,
+		MessagesPerDay:     plan.MessagesPerDay,
+		TokensPerMonth:     plan.TokensPerMonth,
+		MaxMonthlySpendUSD: plan.MaxMonthlySpendUSD,
+		DailyResetAt:       dayStart.AddDate(0, 0, 1),
+		MonthlyResetAt:     monthStart.AddDate(0, 1, 0),
+	}
+
+	if err := s.db.Table("messages").
+		Joins("JOIN pdis ON pdis.id = messages.pdi_id").
+		Where("pdis.user_id = ? AND messages.role = ? AND messages.created_at >= ?", userID, "user", dayStart).
+		Count(&status.MessagesToday).Error; err != nil {
+		return nil, fmt.Errorf("erro ao contar mensagens do dia: %v", err)
+	}
+
+	usage, err := s.usage.GetUserUsage(userID, UsagePeriod{From: monthStart})
+	if err != nil {
+		return nil, err
+	}
+	status.TokensThisMonth = usage.TotalTokens
+	status.SpendThisMonthUSD = usage.CostUSD
+
+	return status, nil
+}
+
+// planFor devolve o plano do usuário; planos não cadastrados usam os limites
+// padrão.
+func (s *QuotaService) planFor(userID string) (models.QuotaPlan, error) {
+	var user models.User
+	if err := s.db.Select("plan").Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.QuotaPlan{}, errors.New("usuário não encontrado")
+		}
+		return models.QuotaPlan{}, fmt.Errorf("erro ao buscar usuário: %v", err)
+	}
+	if user.Plan == "" {
+		user.Plan = models.DefaultPlan
+	}
+
+	var plan models.QuotaPlan
+	err := s.db.Where("name = ?", user.Plan).First(&plan).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		plan = s.defaults
+		plan.Name = user.Plan
+		return plan, nil
+	}
+	if err != nil {
+		return models.QuotaPlan{}, fmt.Errorf("erro ao buscar plano: %v", err)
+	}
+	return plan, nil
+}
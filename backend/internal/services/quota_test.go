@@ -0,0 +1,84 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+func TestQuotaService_Check(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	chatService := NewChatService(db)
+
+	service := NewQuotaService(db, models.QuotaPlan{MessagesPerDay: 2})
+
+	if err := service.Check(user.ID.String()); err != nil {
+		t.Fatalf("Check() sem uso error = %v", err)
+	}
+
+	for _, content := range []string{"um", "dois"} {
+		if _, err := chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Role: "user", Content: content}); err != nil {
+			t.Fatalf("Erro ao criar mensagem: %v", err)
+		}
+	}
+
+	var exceeded *QuotaExceededError
+	if err := service.Check(user.ID.String()); !errors.As(err, &exceeded) || exceeded.Limit != QuotaMessagesPerDay {
+		t.Fatalf("Check() error = %v, esperado limite diário", err)
+	}
+	now := time.Now().UTC()
+	if expected := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC); !exceeded.ResetAt.Equal(expected) {
+		t.Errorf("ResetAt = %v, esperado %v", exceeded.ResetAt, expected)
+	}
+
+	// Um plano cadastrado substitui os limites padrão
+	if err := db.Create(&models.QuotaPlan{Name: "pro", TokensPerMonth: 100}).Error; err != nil {
+		t.Fatalf("Erro ao criar plano: %v", err)
+	}
+	if err := db.Model(user).Update("plan", "pro").Error; err != nil {
+		t.Fatalf("Erro ao atualizar plano: %v", err)
+	}
+	if err := service.Check(user.ID.String()); err != nil {
+		t.Fatalf("Check() no plano pro error = %v", err)
+	}
+
+	if _, err := chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Role: "assistant", Content: "resposta", TotalTokens: 100}); err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+	if err := service.Check(user.ID.String()); !errors.As(err, &exceeded) || exceeded.Limit != QuotaTokensPerMonth {
+		t.Fatalf("Check() error = %v, esperado limite mensal de tokens", err)
+	}
+	if exceeded.ResetAt.Day() != 1 || !exceeded.ResetAt.After(now) {
+		t.Errorf("ResetAt mensal inesperado: %v", exceeded.ResetAt)
+	}
+
+	// No mês seguinte o consumo anterior não conta mais
+	service.now = func() time.Time { return exceeded.ResetAt.Add(time.Hour) }
+	if err := service.Check(user.ID.String()); err != nil {
+		t.Errorf("Check() no mês seguinte error = %v", err)
+	}
+}
+
+func TestQuotaService_MonthlySpend(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+
+	if _, err := NewChatService(db).CreateMessage(&models.Message{PDIID: pdi.ID, Role: "assistant", Content: "resposta", CostUSD: 5.5}); err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+
+	status, err := NewQuotaService(db, models.QuotaPlan{MaxMonthlySpendUSD: 10}).Status(user.ID.String())
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.Plan != models.DefaultPlan || status.SpendThisMonthUSD != 5.5 {
+		t.Errorf("Status() = %+v", status)
+	}
+
+	var exceeded *QuotaExceededError
+	err = NewQuotaService(db, models.QuotaPlan{MaxMonthlySpendUSD: 5}).Check(user.ID.String())
+	if !errors.As(err, &exceeded) || exceeded.Limit != QuotaMonthlySpendUSD {
+		t.Errorf("Check() error = %v, esperado limite de gastos", err)
+	}
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"meu-pdi-estrategico/backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Tipos de resultado da busca: o nome do PDI, o conteúdo do PDI (metas, ações
+// e demais campos de texto) e as mensagens do chat.
+const (
+	SearchKindPDI     = "pdi"
+	SearchKindContent = "content"
+	SearchKindMessage = "message"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 50
+	maxSearchQuery     = 200
+)
+
+var (
+	ErrEmptySearchQuery   = errors.New("informe o termo de busca em q")
+	ErrInvalidSearchLimit = errors.New("limit deve estar entre 1 e 50")
+)
+
+// Os trechos saem do banco com marcadores de controle em volta dos termos
+// encontrados; o texto é escapado antes de os marcadores virarem <mark>.
+const (
+	highlightStart = "\x01"
+	highlightStop  = "\x02"
+)
+
+type SearchRequest struct {
+	Query string
+	Limit int
+}
+
+// SearchResult é um item da busca. Snippet é HTML escapado, com os termos
+// encontrados entre <mark> e </mark>.
+type SearchResult struct {
+	Kind      string     `json:"kind"`
+	PDIID     string     `json:"pdi_id"`
+	PDIName   string     `json:"pdi_name"`
+	MessageID *uuid.UUID `json:"message_id,omitempty"`
+	Role      string     `json:"role,omitempty"`
+	Snippet   string     `json:"snippet"`
+	Rank      float64    `json:"rank"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type SearchService struct {
+	db *gorm.DB
+}
+
+func NewSearchService(db *gorm.DB) *SearchService {
+	return &SearchService{db: db}
+}
+
+// Search procura o termo nos PDIs ativos do usuário e nas mensagens deles,
+// do resultado mais relevante ao menos relevante. No PostgreSQL usa os
+// índices de texto (português e inglês); nos demais bancos, como o SQLite
+// dos testes, compara os termos diretamente.
+func (s *SearchService) Search(userID string, req SearchRequest) ([]SearchResult, error) {
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		return nil, ErrEmptySearchQuery
+	}
+	if runes := []rune(req.Query); len(runes) > maxSearchQuery {
+		req.Query = string(runes[:maxSearchQuery])
+	}
+	if req.Limit < 0 || req.Limit > MaxSearchLimit {
+		return nil, ErrInvalidSearchLimit
+	}
+	if req.Limit == 0 {
+		req.Limit = DefaultSearchLimit
+	}
+
+	var (
+		results []SearchResult
+		err     error
+	)
+	if s.db.Dialector.Name() == "postgres" {
+		results, err = s.searchPostgres(userID, req)
+	} else {
+		results, err = s.searchFallback(userID, req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar: %v", err)
+	}
+
+	for i := range results {
+		results[i].Snippet = highlight(results[i].Snippet)
+	}
+	return results, nil
+}
+
+const searchPostgresQuery = `
+WITH q AS (
+	SELECT websearch_to_tsquery('portuguese', @query) || websearch_to_tsquery('english', @query) AS query
+)
+SELECT * FROM (
+	SELECT 'pdi' AS kind, p.id AS pdi_id, p.name AS pdi_name, NULL::uuid AS message_id, '' AS role,
+		ts_headline('portuguese', p.name, q.query, @options) AS snippet,
+		ts_rank(p.name_search, q.query) AS rank, p.created_at
+	FROM pdis p, q
+	WHERE p.user_id = @user AND p.activated AND p.name_search @@ q.query
+	UNION ALL
+	SELECT 'content', p.id, p.name, NULL::uuid, '',
+		ts_headline('portuguese', pdi_content_text(p.content), q.query, @options),
+		ts_rank(p.content_search, q.query), p.updated_at
+	FROM pdis p, q
+	WHERE p.user_id = @user AND p.activated AND p.content_search @@ q.query
+	UNION ALL
+	SELECT 'message', p.id, p.name, m.id, m.role,
+		ts_headline('portuguese', m.content, q.query, @options),
+		ts_rank(m.content_search, q.query), m.created_at
+	FROM messages m JOIN pdis p ON p.id = m.pdi_id, q
+	WHERE p.user_id = @user AND p.activated AND m.deleted_at IS NULL AND m.content_search @@ q.query
+) AS results
+ORDER BY rank DESC, created_at DESC
+LIMIT @limit`
+
+func (s *SearchService) searchPostgres(userID string, req SearchRequest) ([]SearchResult, error) {
+	options := fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=" ... "`, highlightStart, highlightStop)
+
+	var results []SearchResult
+	err := s.db.Raw(searchPostgresQuery,
+		sql.Named("query", req.Query),
+		sql.Named("options", options),
+		sql.Named("user", userID),
+		sql.Named("limit", req.Limit),
+	).Scan(&results).Error
+	return results, err
+}
+
+// Pesos de cada tipo na busca sem índice, na mesma ordem dos pesos A, B e C
+// usados nos índices do PostgreSQL.
+var searchFallbackWeights = map[string]float64{
+	SearchKindPDI:     1.0,
+	SearchKindContent: 0.4,
+	SearchKindMessage: 0.2,
+}
+
+func (s *SearchService) searchFallback(userID string, req SearchRequest) ([]SearchResult, error) {
+	terms := searchTerms(req.Query)
+	if len(terms) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	var pdis []models.PDI
+	if err := s.db.Where("user_id = ? AND activated = ?", userID, true).Find(&pdis).Error; err != nil {
+		return nil, err
+	}
+
+	results := []SearchResult{}
+	add := func(kind string, pdi models.PDI, text string, build func(*SearchResult)) {
+		matches := countTerms(text, terms)
+		if matches == 0 {
+			return
+		}
+		result := SearchResult{
+			Kind:    kind,
+			PDIID:   pdi.ID,
+			PDIName: pdi.Name,
+			Snippet: fallbackSnippet(text, terms),
+			Rank:    searchFallbackWeights[kind] * float64(matches),
+		}
+		build(&result)
+		results = append(results, result)
+	}
+
+	names := make(map[string]models.PDI, len(pdis))
+	ids := make([]string, 0, len(pdis))
+	for _, pdi := range pdis {
+		pdi := pdi
+		names[pdi.ID] = pdi
+		ids = append(ids, pdi.ID)
+		add(SearchKindPDI, pdi, pdi.Name, func(r *SearchResult) { r.CreatedAt = pdi.CreatedAt })
+		add(SearchKindContent, pdi, contentText(pdi.Content), func(r *SearchResult) { r.CreatedAt = pdi.UpdatedAt })
+	}
+
+	if len(ids) > 0 {
+		// O LIKE só pré-seleciona; a contagem dos termos decide o resultado
+		query := s.db.Where("pdi_id IN ?", ids)
+		likes := s.db
+		for _, term := range terms {
+			likes = likes.Or("LOWER(content) LIKE ?", "%"+term+"%")
+		}
+		var messages []models.Message
+		if err := query.Where(likes).Find(&messages).Error; err != nil {
+			return nil, err
+		}
+		for _, msg := range messages {
+			msg := msg
+			add(SearchKindMessage, names[msg.PDIID], msg.Content, func(r *SearchResult) {
+				r.MessageID = &msg.ID
+				r.Role = msg.Role
+				r.CreatedAt = msg.CreatedAt
+			})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].CreatedAt.After(results[j].CreatedAt)
+	})
+	if len(results) > req.Limit {
+		results = results[:req.Limit]
+	}
+	return results, nil
+}
+
+// searchTerms separa o termo de busca em palavras minúsculas.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// countTerms conta as ocorrências dos termos no texto. Como na busca do
+// PostgreSQL, todos os termos precisam aparecer.
+func countTerms(text string, terms []string) int {
+	lower := strings.ToLower(text)
+	total := 0
+	for _, term := range terms {
+		count := strings.Count(lower, term)
+		if count == 0 {
+			return 0
+		}
+		total += count
+	}
+	return total
+}
+
+// contentText junta os valores de texto do conteúdo do PDI, como a função
+// pdi_content_text do banco.
+func contentText(content string) string {
+	var document interface{}
+	if err := json.Unmarshal([]byte(content), &document); err != nil {
+		return ""
+	}
+
+	var parts []string
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			parts = append(parts, v)
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				walk(v[key])
+			}
+		}
+	}
+	walk(document)
+	return strings.Join(parts, " ")
+}
+
+// fallbackSnippet recorta o texto em volta da primeira ocorrência e marca os
+// termos encontrados no recorte.
+func fallbackSnippet(text string, terms []string) string {
+	const before, size = 60, 200
+
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		lower = runes
+	}
+
+	first := -1
+	for _, term := range terms {
+		if i := strings.Index(string(lower), term); i >= 0 {
+			if position := len([]rune(string(lower)[:i])); first < 0 || position < first {
+				first = position
+			}
+		}
+	}
+
+	start := 0
+	if first > before {
+		start = first - before
+	}
+	end := start + size
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var snippet strings.Builder
+	if start > 0 {
+		snippet.WriteString("... ")
+	}
+	for i := start; i < end; {
+		matched := ""
+		for _, term := range terms {
+			length := len([]rune(term))
+			if i+length <= end && string(lower[i:i+length]) == term && len(term) > len(matched) {
+				matched = term
+			}
+		}
+		if matched == "" {
+			snippet.WriteRune(runes[i])
+			i++
+			continue
+		}
+		length := len([]rune(matched))
+		snippet.WriteString(highlightStart + string(runes[i:i+length]) + highlightStop)
+		i += length
+	}
+	if end < len(runes) {
+		snippet.WriteString(" ...")
+	}
+	return snippet.String()
+}
+
+// highlight escapa o trecho e troca os marcadores de controle por <mark>.
+func highlight(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightStop, "</mark>")
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+func TestSearchService_Search(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	chatService := NewChatService(db)
+
+	db.Model(pdi).Updates(map[string]interface{}{
+		"name":    "Liderança técnica",
+		"content": `{"goals":[{"title":"Assumir a liderança do squad","actions":["Conduzir as reuniões de planejamento"]}]}`,
+	})
+	chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Role: "user", Content: "Como desenvolver <b>liderança</b> sem cargo formal?"})
+	chatService.CreateMessage(&models.Message{PDIID: pdi.ID, Role: "assistant", Content: "Comece pelas reuniões técnicas."})
+
+	// PDIs de outros usuários ficam fora da busca
+	other, _ := NewUserService(db).CreateUser("outro@exemplo.com", "Senha@123", "outro")
+	NewPDIService(db).CreatePDI(other.ID.String(), CreatePDIRequest{Name: "Liderança de vendas"})
+
+	service := NewSearchService(db)
+	results, err := service.Search(user.ID.String(), SearchRequest{Query: "Liderança"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Resultados = %+v, esperado 3", results)
+	}
+
+	// O nome pesa mais que o conteúdo, que pesa mais que as mensagens
+	kinds := []string{SearchKindPDI, SearchKindContent, SearchKindMessage}
+	for i, result := range results {
+		if result.Kind != kinds[i] || result.PDIID != pdi.ID || result.PDIName != "Liderança técnica" {
+			t.Errorf("Resultado %d = %+v, esperado %s", i, result, kinds[i])
+		}
+	}
+	if results[0].Snippet != "<mark>Liderança</mark> técnica" {
+		t.Errorf("Trecho do nome = %q", results[0].Snippet)
+	}
+	message := results[2]
+	if message.MessageID == nil || message.Role != "user" || !strings.Contains(message.Snippet, "&lt;b&gt;<mark>liderança</mark>&lt;/b&gt;") {
+		t.Errorf("Resultado da mensagem = %+v", message)
+	}
+
+	// Todos os termos precisam aparecer
+	results, _ = service.Search(user.ID.String(), SearchRequest{Query: "reuniões planejamento"})
+	if len(results) != 1 || results[0].Kind != SearchKindContent || !strings.Contains(results[0].Snippet, "<mark>reuniões</mark> de <mark>planejamento</mark>") {
+		t.Errorf("Resultados com dois termos = %+v", results)
+	}
+
+	results, _ = service.Search(user.ID.String(), SearchRequest{Query: "liderança", Limit: 1})
+	if len(results) != 1 {
+		t.Errorf("Resultados com limite = %d, esperado 1", len(results))
+	}
+
+	if _, err := service.Search(user.ID.String(), SearchRequest{Query: "  "}); !errors.Is(err, ErrEmptySearchQuery) {
+		t.Errorf("Search() sem termo error = %v, esperado ErrEmptySearchQuery", err)
+	}
+	if _, err := service.Search(user.ID.String(), SearchRequest{Query: "x", Limit: MaxSearchLimit + 1}); !errors.Is(err, ErrInvalidSearchLimit) {
+		t.Errorf("Search() com limite alto error = %v, esperado ErrInvalidSearchLimit", err)
+	}
+}
@@ -0,0 +1,374 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"meu-pdi-estrategico/backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrToolNotFound   = errors.New("ferramenta não encontrada")
+	ErrGoalNotFound   = errors.New("objetivo não encontrado")
+	ErrInvalidToolArg = errors.New("argumentos inválidos")
+)
+
+// ToolContext identifica em nome de quem a ferramenta é executada. Toda
+// ferramenta só enxerga dados da pessoa usuária dona da conversa. MessageID é
+// a mensagem do usuário que originou a execução.
+type ToolContext struct {
+	UserID    string
+	PDI       *models.PDI
+	MessageID *uuid.UUID
+}
+
+// revisionSource identifica as alterações feitas pelo assistente no conteúdo
+// do PDI.
+func (tc ToolContext) revisionSource(reason string) RevisionSource {
+	return RevisionSource{Author: models.RevisionAuthorAssistant, MessageID: tc.MessageID, Reason: reason}
+}
+
+// ToolHandler executa a ferramenta com os argumentos recebidos do modelo. O
+// resultado é serializado em JSON e devolvido ao modelo.
+type ToolHandler func(ctx context.Context, tc ToolContext, args json.RawMessage) (interface{}, error)
+
+type Tool struct {
+	Definition ToolDefinition
+	Handler    ToolHandler
+}
+
+// ToolRegistry reúne as ferramentas que o assistente pode chamar.
+type ToolRegistry struct {
+	tools map[string]Tool
+	order []string
+}
+
+func NewToolRegistry(tools ...Tool) *ToolRegistry {
+	r := &ToolRegistry{tools: make(map[string]Tool)}
+	for _, tool := range tools {
+		r.Register(tool)
+	}
+	return r
+}
+
+// Register adiciona a ferramenta, substituindo outra com o mesmo nome.
+func (r *ToolRegistry) Register(tool Tool) {
+	name := tool.Definition.Name
+	if _, ok := r.tools[name]; !ok {
+		r.order = append(r.order, name)
+	}
+	r.tools[name] = tool
+}
+
+// Definitions devolve as definições na ordem de registro.
+func (r *ToolRegistry) Definitions() []ToolDefinition {
+	definitions := make([]ToolDefinition, 0, len(r.order))
+	for _, name := range r.order {
+		definitions = append(definitions, r.tools[name].Definition)
+	}
+	return definitions
+}
+
+// Has indica se há uma ferramenta registrada com o nome informado.
+func (r *ToolRegistry) Has(name string) bool {
+	_, ok := r.tools[name]
+	return ok
+}
+
+// Subset devolve um registro só com as ferramentas informadas, na ordem de
+// registro. Sem nomes, devolve o próprio registro.
+func (r *ToolRegistry) Subset(names []string) *ToolRegistry {
+	if len(names) == 0 {
+		return r
+	}
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+
+	subset := NewToolRegistry()
+	for _, name := range r.order {
+		if allowed[name] {
+			subset.Register(r.tools[name])
+		}
+	}
+	return subset
+}
+
+// Execute roda a ferramenta chamada pelo modelo. Erros da ferramenta não
+// interrompem o run: são devolvidos ao modelo como {"error": "..."} para que
+// ele possa corrigir a chamada ou explicar o problema.
+func (r *ToolRegistry) Execute(ctx context.Context, tc ToolContext, call ToolCall) string {
+	tool, ok := r.tools[call.Name]
+	if !ok {
+		return toolError(fmt.Errorf("%w: %s", ErrToolNotFound, call.Name))
+	}
+
+	args := json.RawMessage(call.Arguments)
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+
+	result, err := tool.Handler(ctx, tc, args)
+	if err != nil {
+		log.Printf("[Tools] Erro ao executar %s: %v", call.Name, err)
+		return toolError(err)
+	}
+
+	output, err := json.Marshal(result)
+	if err != nil {
+		return toolError(fmt.Errorf("erro ao serializar resultado: %v", err))
+	}
+	return string(output)
+}
+
+func toolError(err error) string {
+	result := map[string]interface{}{"error": err.Error()}
+
+	// Erros de validação vão detalhados para que o modelo corrija a chamada
+	var validation *PDIValidationError
+	if errors.As(err, &validation) {
+		result["error"] = "conteúdo do PDI inválido"
+		result["validation_errors"] = validation.Errors
+		result["schema_version"] = PDIContentSchemaVersion
+	}
+
+	output, _ := json.Marshal(result)
+	return string(output)
+}
+
+// decodeToolArgs lê os argumentos da ferramenta em v.
+func decodeToolArgs(args json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(args, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToolArg, err)
+	}
+	return nil
+}
+
+// NewDefaultToolRegistry registra as ferramentas de leitura e edição de PDI
+// usadas pelo assistente.
+func NewDefaultToolRegistry(db *gorm.DB) *ToolRegistry {
+	tools := &pdiTools{db: db, pdiService: NewPDIService(db), userService: NewUserService(db), goalService: NewGoalService(db)}
+	return NewToolRegistry(
+		Tool{Definition: savePDITool, Handler: tools.savePDI},
+		Tool{Definition: getPDITool, Handler: tools.getPDI},
+		Tool{Definition: listGoalsTool, Handler: tools.listGoals},
+		Tool{Definition: updateGoalProgressTool, Handler: tools.updateGoalProgress},
+		Tool{Definition: getUserProfileTool, Handler: tools.getUserProfile},
+		Tool{Definition: listPreviousPDIsTool, Handler: tools.listPreviousPDIs},
+	)
+}
+
+// savePDITool usa como parâmetros o schema versionado do conteúdo do PDI.
+var savePDITool = ToolDefinition{
+	Name:        "save_pdi",
+	Description: "Salva o conteúdo estruturado do PDI da pessoa usuária.",
+	Parameters:  json.RawMessage(pdiContentSchemaV1),
+}
+
+var getPDITool = ToolDefinition{
+	Name:        "get_pdi",
+	Description: "Retorna o PDI atual ou outro PDI da pessoa usuária, com o conteúdo estruturado.",
+	Parameters: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"pdi_id": {"type": "string", "description": "ID do PDI. Quando omitido, usa o PDI da conversa."}
+		}
+	}`),
+}
+
+var listGoalsTool = ToolDefinition{
+	Name:        "list_goals",
+	Description: "Lista os objetivos do PDI atual com o índice e o progresso de cada um.",
+	Parameters:  json.RawMessage(`{"type": "object", "properties": {}}`),
+}
+
+var updateGoalProgressTool = ToolDefinition{
+	Name:        "update_goal_progress",
+	Description: "Atualiza o progresso (0 a 100) de um objetivo do PDI atual.",
+	Parameters: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"goal_index": {"type": "integer", "minimum": 0, "description": "Índice do objetivo retornado por list_goals."},
+			"progress": {"type": "integer", "minimum": 0, "maximum": 100},
+			"note": {"type": "string", "description": "Observação sobre o avanço."}
+		},
+		"required": ["goal_index", "progress"]
+	}`),
+}
+
+var getUserProfileTool = ToolDefinition{
+	Name:        "get_user_profile",
+	Description: "Retorna o perfil da pessoa usuária.",
+	Parameters:  json.RawMessage(`{"type": "object", "properties": {}}`),
+}
+
+var listPreviousPDIsTool = ToolDefinition{
+	Name:        "list_previous_pdis",
+	Description: "Lista os outros PDIs da pessoa usuária, do mais recente ao mais antigo.",
+	Parameters: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"limit": {"type": "integer", "minimum": 1, "maximum": 20}
+		}
+	}`),
+}
+
+type pdiTools struct {
+	db          *gorm.DB
+	pdiService  *PDIService
+	userService *UserService
+	goalService *GoalService
+}
+
+type toolPDI struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Status    models.PDIStatus `json:"status"`
+	Content   json.RawMessage  `json:"content,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+type toolGoal struct {
+	Index       int     `json:"index"`
+	Description string  `json:"description"`
+	Progress    float64 `json:"progress"`
+}
+
+func (t *pdiTools) savePDI(ctx context.Context, tc ToolContext, args json.RawMessage) (interface{}, error) {
+	if err := t.goalService.SavePlan(tc.PDI, args, tc.revisionSource(RevisionReasonSavePDI)); err != nil {
+		return nil, err
+	}
+	log.Printf("[Tools] Goals do PDI salvos com sucesso")
+	return map[string]string{"status": "ok"}, nil
+}
+
+func (t *pdiTools) getPDI(ctx context.Context, tc ToolContext, args json.RawMessage) (interface{}, error) {
+	var input struct {
+		PDIID string `json:"pdi_id"`
+	}
+	if err := decodeToolArgs(args, &input); err != nil {
+		return nil, err
+	}
+
+	pdi := tc.PDI
+	if input.PDIID != "" && input.PDIID != tc.PDI.ID {
+		if _, err := uuid.Parse(input.PDIID); err != nil {
+			return nil, errors.New("PDI não encontrado")
+		}
+		other, err := t.pdiService.GetPDIByID(tc.UserID, input.PDIID)
+		if err != nil {
+			return nil, err
+		}
+		pdi = other
+	}
+
+	return newToolPDI(pdi, true), nil
+}
+
+func (t *pdiTools) listGoals(ctx context.Context, tc ToolContext, args json.RawMessage) (interface{}, error) {
+	goals, err := t.goalService.ListGoals(tc.PDI)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]toolGoal, 0, len(goals))
+	for _, goal := range goals {
+		result = append(result, toolGoal{Index: goal.Position, Description: goal.Description, Progress: goal.Progress})
+	}
+
+	return map[string]interface{}{"goals": result}, nil
+}
+
+func (t *pdiTools) updateGoalProgress(ctx context.Context, tc ToolContext, args json.RawMessage) (interface{}, error) {
+	var input struct {
+		GoalIndex *int   `json:"goal_index"`
+		Progress  *int   `json:"progress"`
+		Note      string `json:"note"`
+	}
+	if err := decodeToolArgs(args, &input); err != nil {
+		return nil, err
+	}
+	if input.GoalIndex == nil || input.Progress == nil {
+		return nil, fmt.Errorf("%w: goal_index e progress são obrigatórios", ErrInvalidToolArg)
+	}
+	if *input.Progress < 0 || *input.Progress > 100 {
+		return nil, fmt.Errorf("%w: progress deve estar entre 0 e 100", ErrInvalidToolArg)
+	}
+
+	progress := float64(*input.Progress)
+	update := GoalUpdate{Progress: &progress}
+	if input.Note != "" {
+		update.ProgressNote = &input.Note
+	}
+	goal, err := t.goalService.UpdateGoalAt(tc.PDI, *input.GoalIndex, update, tc.revisionSource(RevisionReasonGoalProgress))
+	if err != nil {
+		return nil, err
+	}
+
+	return toolGoal{Index: goal.Position, Description: goal.Description, Progress: progress}, nil
+}
+
+func (t *pdiTools) getUserProfile(ctx context.Context, tc ToolContext, args json.RawMessage) (interface{}, error) {
+	userID, err := uuid.Parse(tc.UserID)
+	if err != nil {
+		return nil, errors.New("usuário não encontrado")
+	}
+
+	user, err := t.userService.GetUserById(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var pdis int64
+	if err := t.db.Model(&models.PDI{}).Where("user_id = ? AND activated = ?", tc.UserID, true).Count(&pdis).Error; err != nil {
+		return nil, fmt.Errorf("erro ao contar PDIs: %v", err)
+	}
+
+	return map[string]interface{}{
+		"nickname":     user.Nickname,
+		"member_since": user.CreatedAt,
+		"pdi_count":    pdis,
+	}, nil
+}
+
+func (t *pdiTools) listPreviousPDIs(ctx context.Context, tc ToolContext, args json.RawMessage) (interface{}, error) {
+	var input struct {
+		Limit int `json:"limit"`
+	}
+	if err := decodeToolArgs(args, &input); err != nil {
+		return nil, err
+	}
+	if input.Limit <= 0 || input.Limit > 20 {
+		input.Limit = 20
+	}
+
+	var pdis []models.PDI
+	if err := t.db.Where("user_id = ? AND id != ? AND activated = ?", tc.UserID, tc.PDI.ID, true).
+		Order("created_at DESC").
+		Limit(input.Limit).
+		Find(&pdis).Error; err != nil {
+		return nil, fmt.Errorf("erro ao buscar PDIs: %v", err)
+	}
+
+	result := make([]toolPDI, 0, len(pdis))
+	for i := range pdis {
+		result = append(result, newToolPDI(&pdis[i], false))
+	}
+	return map[string]interface{}{"pdis": result}, nil
+}
+
+func newToolPDI(pdi *models.PDI, withContent bool) toolPDI {
+	result := toolPDI{ID: pdi.ID, Name: pdi.Name, Status: pdi.Status, CreatedAt: pdi.CreatedAt}
+	if withContent && json.Valid([]byte(pdi.Content)) {
+		result.Content = json.RawMessage(pdi.Content)
+	}
+	return result
+}
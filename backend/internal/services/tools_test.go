@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+func TestToolRegistry_GoalProgress(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	registry := NewDefaultToolRegistry(db)
+	tc := ToolContext{UserID: user.ID.String(), PDI: pdi}
+
+	output := registry.Execute(context.Background(), tc, ToolCall{
+		Name:      "save_pdi",
+		Arguments: `{"goals":[` + testGoal("Aprender Go") + `,` + testGoal("Liderar um projeto") + `],"self_assessment_questions":[]}`,
+	})
+	if output != `{"status":"ok"}` {
+		t.Fatalf("save_pdi output = %v", output)
+	}
+
+	output = registry.Execute(context.Background(), tc, ToolCall{
+		Name:      "update_goal_progress",
+		Arguments: `{"goal_index":1,"progress":40,"note":"Kickoff feito"}`,
+	})
+	if strings.Contains(output, "error") {
+		t.Fatalf("update_goal_progress output = %v", output)
+	}
+
+	var stored models.PDI
+	if err := db.First(&stored, "id = ?", pdi.ID).Error; err != nil {
+		t.Fatalf("Erro ao buscar PDI: %v", err)
+	}
+	if !strings.Contains(stored.Content, `"progress":40`) || !strings.Contains(stored.Content, `"progress_note":"Kickoff feito"`) {
+		t.Errorf("Conteúdo do PDI não atualizado: %v", stored.Content)
+	}
+
+	var goals struct {
+		Goals []toolGoal `json:"goals"`
+	}
+	output = registry.Execute(context.Background(), tc, ToolCall{Name: "list_goals"})
+	if err := json.Unmarshal([]byte(output), &goals); err != nil {
+		t.Fatalf("list_goals output = %v", output)
+	}
+	if len(goals.Goals) != 2 || goals.Goals[1].Progress != 40 || goals.Goals[0].Description != "Aprender Go" {
+		t.Errorf("list_goals goals = %+v", goals.Goals)
+	}
+
+	output = registry.Execute(context.Background(), tc, ToolCall{
+		Name:      "update_goal_progress",
+		Arguments: `{"goal_index":5,"progress":10}`,
+	})
+	if output != `{"error":"objetivo não encontrado"}` {
+		t.Errorf("update_goal_progress output = %v", output)
+	}
+}
+
+func TestToolRegistry_SavePDI_Invalid(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	registry := NewDefaultToolRegistry(db)
+	tc := ToolContext{UserID: user.ID.String(), PDI: pdi}
+
+	output := registry.Execute(context.Background(), tc, ToolCall{
+		Name:      "save_pdi",
+		Arguments: `{"goals":[{"description":"Aprender Go","skills":{"hard_skills":"Go"}}]}`,
+	})
+
+	var result struct {
+		Error            string   `json:"error"`
+		ValidationErrors []string `json:"validation_errors"`
+		SchemaVersion    int      `json:"schema_version"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("save_pdi output = %v", output)
+	}
+	if result.Error == "" || len(result.ValidationErrors) == 0 || result.SchemaVersion != PDIContentSchemaVersion {
+		t.Errorf("save_pdi output = %v", output)
+	}
+
+	var stored models.PDI
+	if err := db.First(&stored, "id = ?", pdi.ID).Error; err != nil {
+		t.Fatalf("Erro ao buscar PDI: %v", err)
+	}
+	if stored.Content != "{}" {
+		t.Errorf("Conteúdo inválido não deveria ser salvo: %v", stored.Content)
+	}
+}
+
+func testGoal(description string) string {
+	return `{"description":"` + description + `","skills":{"hard_skills":[],"soft_skills":[]},"alignment":"","action_plan":[],"key_results":[]}`
+}
+
+func TestToolRegistry_Authorization(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	registry := NewDefaultToolRegistry(db)
+
+	other, err := NewUserService(db).CreateUser("outro@exemplo.com", "Senha@123", "outro")
+	if err != nil {
+		t.Fatalf("Erro ao criar usuário: %v", err)
+	}
+	otherPDI, err := NewPDIService(db).CreatePDI(other.ID.String(), CreatePDIRequest{Name: "PDI alheio"})
+	if err != nil {
+		t.Fatalf("Erro ao criar PDI: %v", err)
+	}
+	previous, err := NewPDIService(db).CreatePDI(user.ID.String(), CreatePDIRequest{Name: "PDI anterior"})
+	if err != nil {
+		t.Fatalf("Erro ao criar PDI: %v", err)
+	}
+	db.Model(previous).Update("status", models.PDIStatusDone)
+	inactive, err := NewPDIService(db).CreatePDI(user.ID.String(), CreatePDIRequest{Name: "PDI desativado"})
+	if err != nil {
+		t.Fatalf("Erro ao criar PDI: %v", err)
+	}
+	db.Model(inactive).Update("activated", false)
+
+	tc := ToolContext{UserID: user.ID.String(), PDI: pdi}
+
+	output := registry.Execute(context.Background(), tc, ToolCall{Name: "get_pdi", Arguments: `{"pdi_id":"` + otherPDI.ID + `"}`})
+	if output != `{"error":"PDI não encontrado"}` {
+		t.Errorf("get_pdi de outro usuário output = %v", output)
+	}
+
+	output = registry.Execute(context.Background(), tc, ToolCall{Name: "get_pdi", Arguments: `{"pdi_id":"` + previous.ID + `"}`})
+	if !strings.Contains(output, `"name":"PDI anterior"`) {
+		t.Errorf("get_pdi output = %v", output)
+	}
+
+	output = registry.Execute(context.Background(), tc, ToolCall{Name: "list_previous_pdis"})
+	if !strings.Contains(output, "PDI anterior") || strings.Contains(output, "PDI alheio") || strings.Contains(output, "PDI desativado") || strings.Contains(output, `"name":"Meu PDI"`) {
+		t.Errorf("list_previous_pdis output = %v", output)
+	}
+
+	output = registry.Execute(context.Background(), tc, ToolCall{Name: "get_user_profile"})
+	if !strings.Contains(output, `"nickname":"teste"`) || !strings.Contains(output, `"pdi_count":2`) {
+		t.Errorf("get_user_profile output = %v", output)
+	}
+
+	output = registry.Execute(context.Background(), tc, ToolCall{Name: "delete_everything"})
+	if output != `{"error":"ferramenta não encontrada: delete_everything"}` {
+		t.Errorf("Ferramenta desconhecida output = %v", output)
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UsagePeriod limita a consulta de consumo; datas zeradas não limitam.
+type UsagePeriod struct {
+	From time.Time
+	To   time.Time
+}
+
+type UsageTotals struct {
+	Messages         int64   `json:"messages"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+type ModelUsage struct {
+	Model string `json:"model"`
+	UsageTotals
+}
+
+type PDIUsage struct {
+	PDIID string `json:"pdi_id"`
+	Name  string `json:"name"`
+	UsageTotals
+}
+
+// UsageReport agrega o consumo das respostas do assistente e das chamadas que
+// não geram mensagem, como os resumos da conversa. Messages conta só as
+// respostas.
+type UsageReport struct {
+	UsageTotals
+	From    *time.Time   `json:"from,omitempty"`
+	To      *time.Time   `json:"to,omitempty"`
+	ByModel []ModelUsage `json:"by_model"`
+	ByPDI   []PDIUsage   `json:"by_pdi,omitempty"`
+}
+
+const usageTotalsSelect = `COALESCE(SUM(usage_rows.replies), 0) AS messages,
+	COALESCE(SUM(usage_rows.prompt_tokens), 0) AS prompt_tokens,
+	COALESCE(SUM(usage_rows.completion_tokens), 0) AS completion_tokens,
+	COALESCE(SUM(usage_rows.total_tokens), 0) AS total_tokens,
+	COALESCE(SUM(usage_rows.cost_usd), 0) AS cost_usd`
+
+// usageRowsQuery junta as respostas do assistente e os registros de consumo
+// sem mensagem. Respostas substituídas ao regenerar continuam contando, pois
+// foram cobradas.
+const usageRowsQuery = `SELECT pdi_id, model, prompt_tokens, completion_tokens, total_tokens, cost_usd, created_at, 1 AS replies
+	FROM messages WHERE role = 'assistant'
+	UNION ALL
+	SELECT pdi_id, model, prompt_tokens, completion_tokens, total_tokens, cost_usd, created_at, 0 AS replies
+	FROM usage_records`
+
+type UsageService struct {
+	db *gorm.DB
+}
+
+func NewUsageService(db *gorm.DB) *UsageService {
+	return &UsageService{db: db}
+}
+
+// GetUserUsage agrega o consumo de todos os PDIs do usuário.
+func (s *UsageService) GetUserUsage(userID string, period UsagePeriod) (*UsageReport, error) {
+	report, err := s.report(period, func() *gorm.DB { return s.userQuery(userID, period) })
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userQuery(userID, period).
+		Select("usage_rows.pdi_id AS pdi_id, pdis.name AS name, " + usageTotalsSelect).
+		Group("usage_rows.pdi_id, pdis.name").
+		Order("cost_usd DESC").
+		Scan(&report.ByPDI).Error; err != nil {
+		return nil, fmt.Errorf("erro ao agregar consumo por PDI: %v", err)
+	}
+
+	return report, nil
+}
+
+// GetPDIUsage agrega o consumo de um PDI do usuário.
+func (s *UsageService) GetPDIUsage(userID, pdiID string, period UsagePeriod) (*UsageReport, error) {
+	return s.report(period, func() *gorm.DB {
+		return s.userQuery(userID, period).Where("usage_rows.pdi_id = ?", pdiID)
+	})
+}
+
+func (s *UsageService) report(period UsagePeriod, query func() *gorm.DB) (*UsageReport, error) {
+	report := &UsageReport{ByModel: []ModelUsage{}}
+	if !period.From.IsZero() {
+		report.From = &period.From
+	}
+	if !period.To.IsZero() {
+		report.To = &period.To
+	}
+
+	if err := query().Select(usageTotalsSelect).Scan(&report.UsageTotals).Error; err != nil {
+		return nil, fmt.Errorf("erro ao agregar consumo: %v", err)
+	}
+
+	if err := query().
+		Select("COALESCE(usage_rows.model, '') AS model, " + usageTotalsSelect).
+		Group("usage_rows.model").
+		Order("cost_usd DESC").
+		Scan(&report.ByModel).Error; err != nil {
+		return nil, fmt.Errorf("erro ao agregar consumo por modelo: %v", err)
+	}
+
+	return report, nil
+}
+
+// userQuery seleciona o consumo registrado nos PDIs do usuário.
+func (s *UsageService) userQuery(userID string, period UsagePeriod) *gorm.DB {
+	query := s.db.Table("("+usageRowsQuery+") AS usage_rows").
+		Joins("JOIN pdis ON pdis.id = usage_rows.pdi_id").
+		Where("pdis.user_id = ?", userID)
+	if !period.From.IsZero() {
+		query = query.Where("usage_rows.created_at >= ?", period.From)
+	}
+	if !period.To.IsZero() {
+		query = query.Where("usage_rows.created_at < ?", period.To)
+	}
+	return query
+}
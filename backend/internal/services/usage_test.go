@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"meu-pdi-estrategico/backend/internal/models"
+)
+
+func TestEstimateCost(t *testing.T) {
+	usage := Usage{PromptTokens: 1000000, CompletionTokens: 500000, TotalTokens: 1500000}
+
+	if cost := EstimateCost("gpt-4o-mini-2024-07-18", usage); cost != 0.45 {
+		t.Errorf("EstimateCost(gpt-4o-mini) = %v, esperado 0.45", cost)
+	}
+	if cost := EstimateCost("gpt-4o", usage); cost != 7.5 {
+		t.Errorf("EstimateCost(gpt-4o) = %v, esperado 7.5", cost)
+	}
+	if cost := EstimateCost("modelo-desconhecido", usage); cost != 0 {
+		t.Errorf("EstimateCost(desconhecido) = %v, esperado 0", cost)
+	}
+}
+
+func TestUsageService(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	chatService := NewChatService(db)
+
+	second, err := NewPDIService(db).CreatePDI(user.ID.String(), CreatePDIRequest{Name: "Segundo PDI"})
+	if err != nil {
+		t.Fatalf("Erro ao criar PDI: %v", err)
+	}
+
+	other, err := NewUserService(db).CreateUser("outro@exemplo.com", "Senha@123", "outro")
+	if err != nil {
+		t.Fatalf("Erro ao criar usuário: %v", err)
+	}
+	otherPDI, err := NewPDIService(db).CreatePDI(other.ID.String(), CreatePDIRequest{Name: "PDI alheio"})
+	if err != nil {
+		t.Fatalf("Erro ao criar PDI: %v", err)
+	}
+
+	replies := []*models.Message{
+		{PDIID: pdi.ID, Role: "assistant", Content: "a", Model: "gpt-4o-mini", PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150, CostUSD: 0.1},
+		{PDIID: pdi.ID, Role: "assistant", Content: "b", Model: "gpt-4o", PromptTokens: 200, CompletionTokens: 100, TotalTokens: 300, CostUSD: 0.2},
+		{PDIID: second.ID, Role: "assistant", Content: "c", Model: "gpt-4o-mini", PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, CostUSD: 0.01},
+		{PDIID: otherPDI.ID, Role: "assistant", Content: "d", Model: "gpt-4o", PromptTokens: 999, CompletionTokens: 999, TotalTokens: 1998, CostUSD: 9},
+		{PDIID: pdi.ID, Role: "user", Content: "e"},
+	}
+	for _, reply := range replies {
+		if _, err := chatService.CreateMessage(reply); err != nil {
+			t.Fatalf("Erro ao criar mensagem: %v", err)
+		}
+	}
+
+	// Respostas substituídas continuam contando no consumo
+	if err := db.Delete(replies[1]).Error; err != nil {
+		t.Fatalf("Erro ao excluir mensagem: %v", err)
+	}
+
+	service := NewUsageService(db)
+
+	report, err := service.GetUserUsage(user.ID.String(), UsagePeriod{})
+	if err != nil {
+		t.Fatalf("GetUserUsage() error = %v", err)
+	}
+	if report.Messages != 3 || report.TotalTokens != 465 || report.PromptTokens != 310 || report.CompletionTokens != 155 {
+		t.Errorf("GetUserUsage() totals = %+v", report.UsageTotals)
+	}
+	if len(report.ByPDI) != 2 || report.ByPDI[0].PDIID != pdi.ID || report.ByPDI[0].TotalTokens != 450 {
+		t.Errorf("GetUserUsage() by_pdi = %+v", report.ByPDI)
+	}
+	if len(report.ByModel) != 2 {
+		t.Errorf("GetUserUsage() by_model = %+v", report.ByModel)
+	}
+
+	report, err = service.GetPDIUsage(user.ID.String(), second.ID, UsagePeriod{})
+	if err != nil {
+		t.Fatalf("GetPDIUsage() error = %v", err)
+	}
+	if report.Messages != 1 || report.TotalTokens != 15 || len(report.ByPDI) != 0 {
+		t.Errorf("GetPDIUsage() = %+v", report)
+	}
+
+	report, err = service.GetUserUsage(user.ID.String(), UsagePeriod{From: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("GetUserUsage() error = %v", err)
+	}
+	if report.Messages != 0 || report.CostUSD != 0 {
+		t.Errorf("GetUserUsage() no futuro = %+v", report.UsageTotals)
+	}
+}
+
+func TestOpenAIService_ProcessMessage_Usage(t *testing.T) {
+	db, user, pdi := setupChatTestDB(t)
+	service := NewOpenAIService(db, NewFakeProvider(FakeReply{Content: "Três palavras aqui"}), nil)
+
+	message, err := NewChatService(db).CreateMessage(&models.Message{PDIID: pdi.ID, Content: "Olá coach", Role: "user"})
+	if err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+
+	reply, err := service.ProcessMessage(context.Background(), message, user.ID.String())
+	if err != nil {
+		t.Fatalf("ProcessMessage() error = %v", err)
+	}
+
+	if reply.Model != ProviderFake || reply.PromptTokens != 2 || reply.CompletionTokens != 3 || reply.TotalTokens != 5 {
+		t.Errorf("ProcessMessage() usage = model %v, %d/%d/%d", reply.Model, reply.PromptTokens, reply.CompletionTokens, reply.TotalTokens)
+	}
+}
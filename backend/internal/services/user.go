@@ -120,6 +120,7 @@ func (s *UserService) CreateUser(email, password, nickname string) (*models.User
 		Password:  hashedPassword,
 		Nickname:  nickname,
 		Activated: true,
+		Plan:      models.DefaultPlan,
 	}
 
 	if err := s.db.Create(user).Error; err != nil {
@@ -166,3 +167,16 @@ func (s *UserService) GetUserById(userID uuid.UUID) (*models.User, error) {
 
 	return &user, nil
 } 
+
+// IsAdmin indica se o usuário ativo tem o papel de administrador.
+func (s *UserService) IsAdmin(userID string) (bool, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return false, ErrUserNotFound
+	}
+	user, err := s.GetUserById(id)
+	if err != nil {
+		return false, err
+	}
+	return user.Role == models.RoleAdmin, nil
+}
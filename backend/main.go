@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"meu-pdi-estrategico/backend/internal/handlers"
@@ -80,15 +83,34 @@ func setupDatabase() (*gorm.DB, error) {
 	return db, nil
 }
 
+func provisionAssistant() {
+	result, err := services.ProvisionAssistantFromEnv()
+	if err != nil {
+		log.Fatalf("Erro ao provisionar assistente: %v", err)
+	}
+	fmt.Printf("Assistente %s (%s)\n", result.AssistantID, result.Action)
+	if os.Getenv("OPENAI_ASSISTANT_ID") == "" {
+		fmt.Printf("Defina OPENAI_ASSISTANT_ID=%s\n", result.AssistantID)
+	}
+}
+
 func main() {
 	if err := loadEnv(); err != nil {
 		log.Fatalf("Erro ao carregar variáveis de ambiente: %v", err)
 	}
 
+	// go run . provision-assistant cria ou atualiza o assistente da OpenAI e sai
+	if len(os.Args) > 1 && os.Args[1] == "provision-assistant" {
+		provisionAssistant()
+		return
+	}
+
+	attachmentConfig := services.AttachmentConfigFromEnv()
 	app := fiber.New(fiber.Config{
 		AppName:      "Meu PDI Estratégico",
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
+		BodyLimit:    attachmentConfig.BodyLimit(),
 	})
 
 	app.Use(cors.New(cors.Config{
@@ -98,6 +120,9 @@ func main() {
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
+	// Só o envio de mensagens comporta anexos; as demais rotas ficam no
+	// limite padrão do Fiber
+	app.Use(middleware.BodyLimit(fiber.DefaultBodyLimit, routes.IsUploadRequest))
 
 	db, err := setupDatabase()
 	if err != nil {
@@ -107,21 +132,81 @@ func main() {
 	userService := services.NewUserService(db)
 	pdiService := services.NewPDIService(db)
 	chatService := services.NewChatService(db)
-	openaiService := services.NewOpenAIService(db)
+	usageService := services.NewUsageService(db)
+	quotaService := services.NewQuotaService(db, services.QuotaDefaultsFromEnv())
+	personaService := services.NewPersonaService(db, services.PersonaDefaultFromEnv())
+	feedbackService := services.NewFeedbackService(db)
+	provider, err := services.NewProviderFromEnv()
+	if err != nil {
+		log.Fatalf("Erro ao configurar provedor de LLM: %v", err)
+	}
+	log.Printf("Provedor de LLM: %s", provider.Name())
+	contextManager, err := services.NewContextManagerFromEnv(db, provider)
+	if err != nil {
+		log.Fatalf("Erro ao configurar contexto do chat: %v", err)
+	}
+	if contextManager != nil {
+		log.Printf("Contexto do chat gerenciado pela aplicação")
+	}
+	filters, err := services.FilterPipelineFromEnv()
+	if err != nil {
+		log.Fatalf("Erro ao configurar filtros de conteúdo: %v", err)
+	}
+	openaiService := services.NewOpenAIService(db, provider, contextManager)
+	openaiService.SetFilters(filters)
+	feedbackService.SetFilters(filters)
+
+	chatConfig := services.ChatWorkerConfigFromEnv()
+	if failed, err := chatService.FailPendingMessages("processamento interrompido pelo reinício do servidor", chatConfig.StaleAfter); err != nil {
+		log.Printf("Erro ao recuperar mensagens pendentes: %v", err)
+	} else if failed > 0 {
+		log.Printf("%d mensagens pendentes marcadas como falha", failed)
+	}
+
+	chatWorker := services.NewChatWorkerPool(openaiService, chatService, chatConfig)
+	chatWorker.Start()
 
 	// Configurar middleware de autenticação
 	middleware.SetJWTSecret(os.Getenv("JWT_SECRET"))
 
 	routes.SetupAuthRoutes(app, handlers.NewLoginHandler(userService))
 	routes.SetupUserRoutes(app, userService)
-	routes.SetupPDIRoutes(app, handlers.NewPDIHandler(pdiService))
-	routes.SetupChatRoutes(app, handlers.NewChatHandler(chatService, openaiService, pdiService))
+	goalService := services.NewGoalService(db)
+	workflowService := services.NewPDIWorkflowService(db, goalService)
+	workflowService.OnTransition(func(event services.PDITransitionEvent) {
+		log.Printf("[PDI] %s: %s -> %s", event.PDI.ID, event.Transition.FromStatus, event.Transition.ToStatus)
+	})
+
+	routes.SetupPDIRoutes(app, handlers.NewPDIHandler(pdiService, workflowService))
+	routes.SetupChatRoutes(app, handlers.NewChatHandler(chatService, pdiService, openaiService, chatWorker, quotaService, services.NewAttachmentExtractor(attachmentConfig)))
+	routes.SetupUsageRoutes(app, handlers.NewUsageHandler(usageService, quotaService, pdiService))
+	routes.SetupPersonaRoutes(app, handlers.NewPersonaHandler(personaService), userService)
+	routes.SetupFeedbackRoutes(app, handlers.NewFeedbackHandler(feedbackService, chatService, pdiService), userService)
+	routes.SetupSearchRoutes(app, handlers.NewSearchHandler(services.NewSearchService(db)))
+	routes.SetupGoalRoutes(app, handlers.NewGoalHandler(goalService, pdiService))
+	routes.SetupRevisionRoutes(app, handlers.NewRevisionHandler(services.NewRevisionService(db, goalService), pdiService))
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "3000"
 	}
 
+	// No SIGTERM, o servidor para de aceitar conexões e os workers terminam
+	// as mensagens em andamento antes de sair
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		log.Printf("Encerrando o servidor...")
+		if err := app.Shutdown(); err != nil {
+			log.Printf("Erro ao encerrar o servidor: %v", err)
+		}
+	}()
+
 	log.Printf("Servidor iniciado na porta %s", port)
-	log.Fatal(app.Listen(fmt.Sprintf(":%s", port)))
+	if err := app.Listen(fmt.Sprintf(":%s", port)); err != nil {
+		log.Fatal(err)
+	}
+	chatWorker.Shutdown()
+	log.Printf("Servidor encerrado")
 }
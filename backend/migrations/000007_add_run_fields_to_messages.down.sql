@@ -0,0 +1,3 @@
+ALTER TABLE messages DROP COLUMN attempts;
+ALTER TABLE messages DROP COLUMN provider_message_id;
+ALTER TABLE messages DROP COLUMN run_id;
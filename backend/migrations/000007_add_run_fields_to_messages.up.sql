@@ -0,0 +1,3 @@
+ALTER TABLE messages ADD COLUMN run_id TEXT;
+ALTER TABLE messages ADD COLUMN provider_message_id TEXT;
+ALTER TABLE messages ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_messages_pdi_id_created_at;
+
+ALTER TABLE messages DROP COLUMN cost_usd;
+ALTER TABLE messages DROP COLUMN total_tokens;
+ALTER TABLE messages DROP COLUMN completion_tokens;
+ALTER TABLE messages DROP COLUMN prompt_tokens;
+ALTER TABLE messages DROP COLUMN model;
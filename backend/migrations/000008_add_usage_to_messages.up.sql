@@ -0,0 +1,7 @@
+ALTER TABLE messages ADD COLUMN model TEXT;
+ALTER TABLE messages ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN total_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN cost_usd NUMERIC(12, 6) NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_messages_pdi_id_created_at ON messages(pdi_id, created_at);
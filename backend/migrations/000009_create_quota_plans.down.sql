@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN plan;
+
+DROP TRIGGER IF EXISTS update_quota_plans_updated_at ON quota_plans;
+DROP TABLE IF EXISTS quota_plans;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS quota_plans (
+    name VARCHAR(50) PRIMARY KEY,
+    messages_per_day INTEGER NOT NULL DEFAULT 0,
+    tokens_per_month BIGINT NOT NULL DEFAULT 0,
+    max_monthly_spend_usd NUMERIC(12, 6) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_quota_plans_updated_at
+    BEFORE UPDATE ON quota_plans
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE users ADD COLUMN plan VARCHAR(50) NOT NULL DEFAULT 'free';
@@ -0,0 +1,2 @@
+ALTER TABLE pdis DROP COLUMN context_summarized_until;
+ALTER TABLE pdis DROP COLUMN context_summary;
@@ -0,0 +1,2 @@
+ALTER TABLE pdis ADD COLUMN context_summary TEXT;
+ALTER TABLE pdis ADD COLUMN context_summarized_until TIMESTAMP NULL;
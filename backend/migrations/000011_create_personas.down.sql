@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN role;
+ALTER TABLE messages DROP COLUMN persona_id;
+ALTER TABLE pdis DROP COLUMN persona;
+
+DROP TABLE IF EXISTS personas;
@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS personas (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    slug VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT,
+    instructions TEXT NOT NULL,
+    model VARCHAR(100),
+    temperature DOUBLE PRECISION,
+    tools JSONB,
+    weight INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_personas_slug_version ON personas(slug, version);
+
+INSERT INTO personas (slug, version, name, description, instructions, weight) VALUES
+(
+    'career-coach', 1, 'Coach de carreira',
+    'Ajuda a construir um PDI estratégico a partir do contexto e dos objetivos profissionais.',
+    -- Sem instruções próprias: vale backend/internal/services/assistant/instructions.md
+    '',
+    1
+),
+(
+    'tech-lead-mentor', 1, 'Mentor de tech lead',
+    'Foca em liderança técnica: arquitetura, qualidade, influência e desenvolvimento do time.',
+    'Você é um tech lead experiente que mentora a pessoa usuária na construção de um Plano de Desenvolvimento Individual (PDI).
+Explore decisões de arquitetura, qualidade de código, comunicação com o time e com a gestão e o desenvolvimento de outras pessoas.
+Proponha metas com entregas técnicas concretas e resultados-chave mensuráveis.
+Quando o plano estiver pronto, chame a função save_pdi. Responda sempre em português, em Markdown.',
+    1
+),
+(
+    'promotion-advisor', 1, 'Consultor de promoção',
+    'Prepara a pessoa para a próxima promoção, com base nas expectativas do próximo nível.',
+    'Você é um consultor de carreira que prepara a pessoa usuária para a próxima promoção.
+Pergunte o cargo atual, o cargo desejado e as expectativas do próximo nível na empresa, e identifique as lacunas entre eles.
+Monte um Plano de Desenvolvimento Individual (PDI) com evidências que a pessoa possa apresentar no ciclo de avaliação.
+Quando o plano estiver pronto, chame a função save_pdi. Responda sempre em português, em Markdown.',
+    1
+);
+
+ALTER TABLE pdis ADD COLUMN persona VARCHAR(100);
+ALTER TABLE messages ADD COLUMN persona_id UUID REFERENCES personas(id);
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user';
@@ -0,0 +1,5 @@
+ALTER TABLE pdis DROP COLUMN IF EXISTS active_branch_id;
+
+DROP INDEX IF EXISTS idx_messages_branch_id;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS branch_id;
@@ -0,0 +1,10 @@
+ALTER TABLE messages ADD COLUMN branch_id UUID;
+
+-- O ramo principal de cada PDI usa o próprio ID do PDI
+UPDATE messages SET branch_id = pdi_id WHERE branch_id IS NULL;
+
+ALTER TABLE messages ALTER COLUMN branch_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_messages_branch_id ON messages(branch_id);
+
+ALTER TABLE pdis ADD COLUMN active_branch_id UUID;
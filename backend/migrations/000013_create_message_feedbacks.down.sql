@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS update_message_feedbacks_updated_at ON message_feedbacks;
+DROP TABLE IF EXISTS message_feedbacks;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS message_feedbacks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    message_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    rating VARCHAR(10) NOT NULL CHECK (rating IN ('up', 'down')),
+    comment TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_message_feedbacks_message_user ON message_feedbacks(message_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_message_feedbacks_created_at ON message_feedbacks(created_at);
+
+CREATE TRIGGER update_message_feedbacks_updated_at
+    BEFORE UPDATE ON message_feedbacks
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
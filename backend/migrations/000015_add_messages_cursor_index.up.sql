@@ -0,0 +1,2 @@
+-- Paginação da conversa por (created_at, id) dentro do ramo
+CREATE INDEX IF NOT EXISTS idx_messages_branch_cursor ON messages(pdi_id, branch_id, created_at, id);
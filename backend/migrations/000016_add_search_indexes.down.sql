@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_messages_content_search;
+DROP INDEX IF EXISTS idx_pdis_content_search;
+DROP INDEX IF EXISTS idx_pdis_name_search;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS content_search;
+ALTER TABLE pdis DROP COLUMN IF EXISTS content_search;
+ALTER TABLE pdis DROP COLUMN IF EXISTS name_search;
+
+DROP FUNCTION IF EXISTS pdi_content_text(JSONB);
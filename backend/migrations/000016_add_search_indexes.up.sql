@@ -0,0 +1,26 @@
+-- Texto dos campos do conteúdo do PDI (metas, ações, perguntas...), usado na
+-- busca. Só os valores de texto entram; chaves e números ficam de fora.
+CREATE OR REPLACE FUNCTION pdi_content_text(content JSONB) RETURNS TEXT AS $$
+    SELECT COALESCE(string_agg(value #>> '{}', ' '), '')
+    FROM jsonb_path_query(COALESCE(content, '{}'::jsonb), 'strict $.** ? (@.type() == "string")') AS value
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Cada texto é indexado em português e em inglês
+ALTER TABLE pdis ADD COLUMN name_search TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('portuguese', COALESCE(name, '')), 'A') ||
+    setweight(to_tsvector('english', COALESCE(name, '')), 'A')
+) STORED;
+
+ALTER TABLE pdis ADD COLUMN content_search TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('portuguese', pdi_content_text(content)), 'B') ||
+    setweight(to_tsvector('english', pdi_content_text(content)), 'B')
+) STORED;
+
+ALTER TABLE messages ADD COLUMN content_search TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('portuguese', COALESCE(content, '')), 'C') ||
+    setweight(to_tsvector('english', COALESCE(content, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_pdis_name_search ON pdis USING GIN (name_search);
+CREATE INDEX IF NOT EXISTS idx_pdis_content_search ON pdis USING GIN (content_search);
+CREATE INDEX IF NOT EXISTS idx_messages_content_search ON messages USING GIN (content_search);
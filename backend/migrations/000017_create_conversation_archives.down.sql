@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_messages_archive_id;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS archive_id;
+
+DROP TABLE IF EXISTS conversation_archives;
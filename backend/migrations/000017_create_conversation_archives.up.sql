@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS conversation_archives (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pdi_id UUID NOT NULL,
+    thread_id TEXT,
+    active_branch_id UUID,
+    context_summary TEXT,
+    context_summarized_until TIMESTAMP,
+    content JSONB,
+    content_reset BOOLEAN NOT NULL DEFAULT false,
+    message_count INTEGER NOT NULL DEFAULT 0,
+    first_message TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pdi_id) REFERENCES pdis(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_archives_pdi_id ON conversation_archives(pdi_id);
+
+-- Mensagens de uma conversa reiniciada ficam excluídas e ligadas ao arquivo
+ALTER TABLE messages ADD COLUMN archive_id UUID REFERENCES conversation_archives(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_messages_archive_id ON messages(archive_id);
@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS update_action_items_updated_at ON action_items;
+DROP TRIGGER IF EXISTS update_key_results_updated_at ON key_results;
+DROP TRIGGER IF EXISTS update_goals_updated_at ON goals;
+
+DROP TABLE IF EXISTS skills;
+DROP TABLE IF EXISTS action_items;
+DROP TABLE IF EXISTS key_results;
+DROP TABLE IF EXISTS goals;
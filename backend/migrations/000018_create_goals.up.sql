@@ -0,0 +1,106 @@
+CREATE TABLE IF NOT EXISTS goals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pdi_id UUID NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    description TEXT NOT NULL,
+    alignment TEXT,
+    progress NUMERIC(5,2) NOT NULL DEFAULT 0 CHECK (progress BETWEEN 0 AND 100),
+    progress_note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pdi_id) REFERENCES pdis(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_goals_pdi_id ON goals(pdi_id, position);
+
+CREATE TABLE IF NOT EXISTS key_results (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    goal_id UUID NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    description TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (goal_id) REFERENCES goals(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_key_results_goal_id ON key_results(goal_id);
+
+CREATE TABLE IF NOT EXISTS action_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    goal_id UUID NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    description TEXT NOT NULL,
+    done BOOLEAN NOT NULL DEFAULT false,
+    done_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (goal_id) REFERENCES goals(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_action_items_goal_id ON action_items(goal_id);
+
+CREATE TABLE IF NOT EXISTS skills (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    goal_id UUID NOT NULL,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('hard', 'soft')),
+    position INTEGER NOT NULL DEFAULT 0,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (goal_id) REFERENCES goals(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_skills_goal_id ON skills(goal_id);
+CREATE INDEX IF NOT EXISTS idx_skills_name ON skills(kind, lower(name));
+
+CREATE TRIGGER update_goals_updated_at
+    BEFORE UPDATE ON goals
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_key_results_updated_at
+    BEFORE UPDATE ON key_results
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_action_items_updated_at
+    BEFORE UPDATE ON action_items
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Backfill a partir do conteúdo dos PDIs. A posição de cada item é o seu
+-- índice no array correspondente de pdis.content.
+INSERT INTO goals (pdi_id, position, description, alignment, progress, progress_note)
+SELECT p.id,
+       g.ord - 1,
+       COALESCE(g.value->>'description', ''),
+       COALESCE(g.value->>'alignment', ''),
+       CASE WHEN jsonb_typeof(g.value->'progress') = 'number'
+            THEN LEAST(GREATEST((g.value->>'progress')::numeric, 0), 100)
+            ELSE 0 END,
+       COALESCE(g.value->>'progress_note', '')
+FROM pdis p,
+     jsonb_array_elements(p.content->'goals') WITH ORDINALITY AS g(value, ord)
+WHERE jsonb_typeof(p.content->'goals') = 'array'
+  AND jsonb_typeof(g.value) = 'object';
+
+INSERT INTO key_results (goal_id, position, description)
+SELECT goals.id, item.ord - 1, item.value #>> '{}'
+FROM goals
+JOIN pdis p ON p.id = goals.pdi_id,
+     jsonb_array_elements(p.content->'goals'->goals.position->'key_results') WITH ORDINALITY AS item(value, ord)
+WHERE jsonb_typeof(p.content->'goals'->goals.position->'key_results') = 'array';
+
+INSERT INTO action_items (goal_id, position, description)
+SELECT goals.id, item.ord - 1, item.value #>> '{}'
+FROM goals
+JOIN pdis p ON p.id = goals.pdi_id,
+     jsonb_array_elements(p.content->'goals'->goals.position->'action_plan') WITH ORDINALITY AS item(value, ord)
+WHERE jsonb_typeof(p.content->'goals'->goals.position->'action_plan') = 'array';
+
+INSERT INTO skills (goal_id, kind, position, name)
+SELECT goals.id, kinds.kind, item.ord - 1, item.value #>> '{}'
+FROM goals
+JOIN pdis p ON p.id = goals.pdi_id
+CROSS JOIN (VALUES ('hard', 'hard_skills'), ('soft', 'soft_skills')) AS kinds(kind, field),
+     jsonb_array_elements(p.content->'goals'->goals.position->'skills'->kinds.field) WITH ORDINALITY AS item(value, ord)
+WHERE jsonb_typeof(p.content->'goals'->goals.position->'skills'->kinds.field) = 'array';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pdi_revisions;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS pdi_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pdi_id UUID NOT NULL,
+    number INTEGER NOT NULL,
+    content JSONB NOT NULL,
+    author VARCHAR(20) NOT NULL CHECK (author IN ('user', 'assistant', 'system')),
+    message_id UUID,
+    reason VARCHAR(50),
+    restored_from INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pdi_id) REFERENCES pdis(id) ON DELETE CASCADE,
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX idx_pdi_revisions_pdi_number ON pdi_revisions(pdi_id, number);
+
+-- O conteúdo atual de cada PDI vira a primeira revisão
+INSERT INTO pdi_revisions (pdi_id, number, content, author, reason, created_at)
+SELECT id, 1, content, 'system', 'baseline', updated_at
+FROM pdis
+WHERE content IS NOT NULL AND content <> '{}'::jsonb;
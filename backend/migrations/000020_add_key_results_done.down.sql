@@ -0,0 +1,3 @@
+ALTER TABLE key_results
+    DROP COLUMN IF EXISTS done_at,
+    DROP COLUMN IF EXISTS done;
@@ -0,0 +1,3 @@
+ALTER TABLE key_results
+    ADD COLUMN IF NOT EXISTS done BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS done_at TIMESTAMP;
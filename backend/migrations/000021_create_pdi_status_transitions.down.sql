@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS pdi_status_transitions;
+
+ALTER TABLE pdis DROP CONSTRAINT IF EXISTS pdis_status_check;
@@ -0,0 +1,20 @@
+-- O status era livre na criação; valores fora do fluxo voltam para DRAFT
+UPDATE pdis SET status = 'DRAFT'
+WHERE status NOT IN ('DRAFT', 'PENDING', 'IN_PROGRESS', 'DONE');
+
+ALTER TABLE pdis
+    ADD CONSTRAINT pdis_status_check CHECK (status IN ('DRAFT', 'PENDING', 'IN_PROGRESS', 'DONE'));
+
+CREATE TABLE IF NOT EXISTS pdi_status_transitions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pdi_id UUID NOT NULL,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    user_id UUID NOT NULL,
+    note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pdi_id) REFERENCES pdis(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_pdi_status_transitions_pdi_id ON pdi_status_transitions(pdi_id, created_at);
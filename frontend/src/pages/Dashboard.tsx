@@ -137,8 +137,7 @@ const Dashboard: React.FC = () => {
       const pdiName = nextNumber === 1 ? 'Novo PDI' : `Novo PDI #${nextNumber}`;
       
       const response = await api.post('/api/pdis', {
-        name: pdiName,
-        status: 'DRAFT'
+        name: pdiName
       });
       navigate(`/pdi/${response.data.id}/chat`);
     } catch (error) {
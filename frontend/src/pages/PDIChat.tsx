@@ -4,6 +4,7 @@ import { useNavigate, useParams } from 'react-router-dom';
 import { useTheme } from '../hooks/useTheme';
 import { FiHome, FiSend, FiUser, FiMessageSquare, FiEye, FiGitBranch } from 'react-icons/fi';
 import api from '../utils/axios';
+import { chatService } from '../services/chat.service';
 import MarkdownRenderer from '../components/MarkdownRenderer';
 
 type Theme = 'light' | 'dark';
@@ -185,6 +186,21 @@ const MessagesContainer = styled.div<ThemedProps>`
   }
 `;
 
+const LoadOlderButton = styled.button<ThemedProps>`
+  align-self: center;
+  padding: 0.5rem 1rem;
+  border: 1px solid ${({ theme }) => theme === 'dark' ? 'rgba(255, 255, 255, 0.2)' : 'rgba(0, 0, 0, 0.1)'};
+  border-radius: 0.375rem;
+  background: transparent;
+  color: inherit;
+  cursor: pointer;
+
+  &:disabled {
+    opacity: 0.5;
+    cursor: default;
+  }
+`;
+
 const MessageWrapper = styled.div<{ role: 'user' | 'assistant' } & ThemedProps>`
   display: flex;
   padding: 1.5rem;
@@ -466,6 +482,9 @@ const PDIChat: React.FC = () => {
   const [isLoading, setIsLoading] = useState(true);
   const [isSending, setIsSending] = useState(false);
   const [isAssistantResponding, setIsAssistantResponding] = useState(false);
+  const [hasOlderMessages, setHasOlderMessages] = useState(false);
+  const [isLoadingOlder, setIsLoadingOlder] = useState(false);
+  const keepScrollRef = useRef(false);
   const messagesEndRef = useRef<HTMLDivElement>(null);
   const textareaRef = useRef<HTMLTextAreaElement>(null);
 
@@ -489,6 +508,7 @@ const PDIChat: React.FC = () => {
       try {
         const response = await api.get(`/api/pdis/${id}/chat`);
         setMessages(response.data.messages);
+        setHasOlderMessages(response.data.has_before);
       } catch (error) {
         console.error('Erro ao carregar mensagens:', error);
       }
@@ -500,9 +520,29 @@ const PDIChat: React.FC = () => {
   }, [id]);
 
   useEffect(() => {
+    // Ao carregar mensagens anteriores, a posição da conversa é mantida
+    if (keepScrollRef.current) {
+      keepScrollRef.current = false;
+      return;
+    }
     messagesEndRef.current?.scrollIntoView({ behavior: 'smooth' });
   }, [messages]);
 
+  const loadOlderMessages = async () => {
+    if (messages.length === 0) return;
+    setIsLoadingOlder(true);
+    try {
+      const response = await api.get(`/api/pdis/${id}/chat`, { params: { before: messages[0].id } });
+      keepScrollRef.current = true;
+      setMessages((prev) => [...response.data.messages, ...prev]);
+      setHasOlderMessages(response.data.has_before);
+    } catch (error) {
+      console.error('Erro ao carregar mensagens anteriores:', error);
+    } finally {
+      setIsLoadingOlder(false);
+    }
+  };
+
   useEffect(() => {
     if (textareaRef.current) {
       textareaRef.current.style.height = 'auto';
@@ -552,15 +592,37 @@ const PDIChat: React.FC = () => {
     setIsSending(true);
     setIsAssistantResponding(true);
 
+    const streamingId = 'stream-' + Date.now();
     try {
-      const response = await api.post(`/api/pdis/${id}/chat`, messageToSend);
-      // Remove a mensagem temporária e adiciona as mensagens reais
-      setMessages(prev => prev.filter(msg => msg.id !== userMessage.id));
-      setMessages(prev => [...prev, ...response.data]);
+      await chatService.streamMessage(id, messageToSend.content, {
+        onMessage: (message) => {
+          if (message.role === 'user') {
+            // Substitui a mensagem temporária pela mensagem persistida
+            setMessages(prev => prev.map(msg => msg.id === userMessage.id ? message : msg));
+            return;
+          }
+          setMessages(prev => [...prev.filter(msg => msg.id !== streamingId), message]);
+        },
+        onToken: (token) => {
+          setIsAssistantResponding(false);
+          setMessages(prev => {
+            if (!prev.some(msg => msg.id === streamingId)) {
+              return [...prev, {
+                id: streamingId,
+                content: token,
+                role: 'assistant',
+                status: 'pending',
+                created_at: new Date().toISOString()
+              }];
+            }
+            return prev.map(msg => msg.id === streamingId ? { ...msg, content: msg.content + token } : msg);
+          });
+        },
+      });
     } catch (error) {
       console.error('Erro ao enviar mensagem:', error);
-      // Remove a mensagem temporária em caso de erro
-      setMessages(prev => prev.filter(msg => msg.id !== userMessage.id));
+      // Remove as mensagens temporárias em caso de erro
+      setMessages(prev => prev.filter(msg => msg.id !== userMessage.id && msg.id !== streamingId));
     } finally {
       setIsSending(false);
       setIsAssistantResponding(false);
@@ -608,6 +670,11 @@ const PDIChat: React.FC = () => {
 
       <ChatContainer>
         <MessagesContainer theme={theme}>
+          {hasOlderMessages && (
+            <LoadOlderButton theme={theme} onClick={loadOlderMessages} disabled={isLoadingOlder}>
+              {isLoadingOlder ? 'Carregando...' : 'Carregar mensagens anteriores'}
+            </LoadOlderButton>
+          )}
           {messages.map((message) => (
             <MessageWrapper key={message.id} role={message.role} theme={theme}>
               <Avatar role={message.role} theme={theme}>
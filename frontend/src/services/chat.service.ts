@@ -0,0 +1,88 @@
+import { config } from '../config/env';
+import { authService } from './auth.service';
+
+export interface ChatMessage {
+  id: string;
+  content: string;
+  role: 'user' | 'assistant';
+  status: string;
+  created_at: string;
+}
+
+export interface ToolCall {
+  id: string;
+  name: string;
+  arguments: string;
+}
+
+export interface StreamHandlers {
+  onMessage?: (message: ChatMessage) => void;
+  onToken?: (token: string) => void;
+  onToolCall?: (tool: ToolCall) => void;
+}
+
+class ChatService {
+  private baseUrl = `${config.apiUrl}/api/pdis`;
+
+  // Envia a mensagem e consome a resposta transmitida via Server-Sent Events.
+  async streamMessage(pdiId: string, content: string, handlers: StreamHandlers): Promise<void> {
+    const response = await fetch(`${this.baseUrl}/${pdiId}/chat/stream`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        'Accept': 'text/event-stream',
+        'Authorization': `Bearer ${authService.getToken()}`,
+      },
+      body: JSON.stringify({ content, role: 'user' }),
+    });
+
+    if (!response.ok || !response.body) {
+      const error = await response.json().catch(() => ({}));
+      throw new Error(error.error || 'Erro ao enviar mensagem');
+    }
+
+    const reader = response.body.getReader();
+    const decoder = new TextDecoder();
+    let buffer = '';
+
+    for (;;) {
+      const { done, value } = await reader.read();
+      if (done) break;
+
+      buffer += decoder.decode(value, { stream: true });
+      const blocks = buffer.split('\n\n');
+      buffer = blocks.pop() ?? '';
+
+      for (const block of blocks) {
+        this.dispatch(block, handlers);
+      }
+    }
+  }
+
+  private dispatch(block: string, handlers: StreamHandlers) {
+    let event = 'message';
+    let data = '';
+    for (const line of block.split('\n')) {
+      if (line.startsWith('event: ')) event = line.slice(7);
+      if (line.startsWith('data: ')) data += line.slice(6);
+    }
+    if (!data) return;
+
+    const payload = JSON.parse(data);
+    switch (event) {
+      case 'message':
+        handlers.onMessage?.(payload);
+        break;
+      case 'token':
+        handlers.onToken?.(payload.content);
+        break;
+      case 'tool_call':
+        handlers.onToolCall?.(payload.tool);
+        break;
+      case 'error':
+        throw new Error(payload.error);
+    }
+  }
+}
+
+export const chatService = new ChatService();